@@ -10,6 +10,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // where the node starts in the source
 }
 
 type Statement interface {
@@ -22,6 +23,12 @@ type Expression interface {
 	expressionNode()
 }
 
+// Pattern is what the arms of a match expression test values against
+type Pattern interface {
+	Node
+	patternNode()
+}
+
 type Program struct {
 	Statements []Statement
 }
@@ -33,6 +40,13 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
@@ -48,25 +62,54 @@ func (p *Program) String() string {
 type Identifier struct {
 	Token token.Token // token.IDENT
 	Value string
+	Type  *TypeAnnotation // optional, only where the identifier is bound
 }
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
-func (i *Identifier) String() string       { return i.Value }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos() }
+func (i *Identifier) String() string {
+	if i.Type != nil {
+		return i.Value + ": " + i.Type.String()
+	}
+	return i.Value
+}
+
+// TypeAnnotation names the declared type of a binding or of what a function
+// returns, like int in let x: int = 5;. Annotations are not evaluated.
+type TypeAnnotation struct {
+	Token token.Token // token.IDENT, or token.FUNCTION for fn
+	Name  string
+}
+
+func (ta *TypeAnnotation) TokenLiteral() string { return ta.Token.Literal }
+func (ta *TypeAnnotation) Pos() token.Position  { return ta.Token.Pos() }
+func (ta *TypeAnnotation) String() string       { return ta.Name }
 
 type LetStatement struct {
 	Token token.Token // token.LET
 	Name  *Identifier
+	Names []*Identifier // every bound name when unpacking a tuple, Name included
 	Value Expression
+	Doc   string // text of the /// comments right before the statement
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos() }
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if len(ls.Names) > 1 {
+		names := []string{}
+		for _, n := range ls.Names {
+			names = append(names, n.String())
+		}
+		out.WriteString(strings.Join(names, ", "))
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -85,6 +128,7 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos() }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -97,6 +141,106 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// YieldStatement hands a value to whoever is iterating over the generator the
+// enclosing function returned, pausing the function until the next value is
+// asked for
+type YieldStatement struct {
+	Token token.Token // token.YIELD
+	Value Expression
+}
+
+func (ys *YieldStatement) statementNode()       {}
+func (ys *YieldStatement) TokenLiteral() string { return ys.Token.Literal }
+func (ys *YieldStatement) Pos() token.Position  { return ys.Token.Pos() }
+func (ys *YieldStatement) String() string {
+	return ys.TokenLiteral() + " " + ys.Value.String() + ";"
+}
+
+// RaiseStatement fails with Value, which becomes the message of the error when
+// it's a string
+type RaiseStatement struct {
+	Token token.Token // token.RAISE
+	Value Expression
+}
+
+func (rs *RaiseStatement) statementNode()       {}
+func (rs *RaiseStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *RaiseStatement) Pos() token.Position  { return rs.Token.Pos() }
+func (rs *RaiseStatement) String() string {
+	return rs.TokenLiteral() + " " + rs.Value.String() + ";"
+}
+
+// ForStatement runs Body once for every value of Iterable, binding the value to
+// Name, or unpacking it into Names when there are several like in
+// for (k, v in hash)
+type ForStatement struct {
+	Token    token.Token // token.FOR
+	Name     *Identifier
+	Names    []*Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position  { return fs.Token.Pos() }
+func (fs *ForStatement) String() string {
+	names := []string{}
+	for _, name := range fs.Names {
+		names = append(names, name.String())
+	}
+
+	return fs.TokenLiteral() + " (" + strings.Join(names, ", ") + " in " + fs.Iterable.String() + ") " + fs.Body.String()
+}
+
+// DoWhileStatement runs Body once, and then again for as long as Condition
+// holds. Every run gets an environment of its own, which Condition is evaluated
+// in after it, so it sees the bindings the body made.
+type DoWhileStatement struct {
+	Token     token.Token // token.DO
+	Body      *BlockStatement
+	Condition Expression
+}
+
+func (ds *DoWhileStatement) statementNode()       {}
+func (ds *DoWhileStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DoWhileStatement) Pos() token.Position  { return ds.Token.Pos() }
+func (ds *DoWhileStatement) String() string {
+	return ds.TokenLiteral() + " " + ds.Body.String() + " while (" + ds.Condition.String() + ");"
+}
+
+// GlobalStatement rebinds Name at the top level of the script to Value, even
+// from within a function. Name must already be bound there.
+type GlobalStatement struct {
+	Token token.Token // token.GLOBAL
+	Name  *Identifier
+	Value Expression
+}
+
+func (gs *GlobalStatement) statementNode()       {}
+func (gs *GlobalStatement) TokenLiteral() string { return gs.Token.Literal }
+func (gs *GlobalStatement) Pos() token.Position  { return gs.Token.Pos() }
+func (gs *GlobalStatement) String() string {
+	return gs.TokenLiteral() + " " + gs.Name.String() + " = " + gs.Value.String() + ";"
+}
+
+// AssignStatement replaces the element Target refers to with Value, where
+// Target is an index expression like arr[i] or hash[key]. With ||= the element
+// is only replaced when it's falsy, and with ??= when it's null or missing.
+type AssignStatement struct {
+	Token    token.Token // token.ASSIGN, token.OR_ASSIGN or token.NULLISH_ASSIGN
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (as *AssignStatement) statementNode()       {}
+func (as *AssignStatement) TokenLiteral() string { return as.Token.Literal }
+func (as *AssignStatement) Pos() token.Position  { return as.Target.Pos() }
+func (as *AssignStatement) String() string {
+	return as.Target.String() + " " + as.Operator + " " + as.Value.String() + ";"
+}
+
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
@@ -104,6 +248,7 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return es.Token.Pos() }
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -118,8 +263,19 @@ type IntLiteral struct {
 
 func (il *IntLiteral) expressionNode()      {}
 func (il *IntLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntLiteral) Pos() token.Position  { return il.Token.Pos() }
 func (il *IntLiteral) String() string       { return il.TokenLiteral() }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos() }
+func (fl *FloatLiteral) String() string       { return fl.TokenLiteral() }
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -128,6 +284,7 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos() }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -148,6 +305,7 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position  { return ie.Left.Pos() }
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -167,6 +325,7 @@ type BooleanLiteral struct {
 
 func (b *BooleanLiteral) expressionNode()      {}
 func (b *BooleanLiteral) TokenLiteral() string { return b.Token.Literal }
+func (b *BooleanLiteral) Pos() token.Position  { return b.Token.Pos() }
 func (b *BooleanLiteral) String() string       { return b.TokenLiteral() }
 
 type IfExpression struct {
@@ -178,6 +337,7 @@ type IfExpression struct {
 
 func (ife *IfExpression) expressionNode()      {}
 func (ife *IfExpression) TokenLiteral() string { return ife.Token.Literal }
+func (ife *IfExpression) Pos() token.Position  { return ife.Token.Pos() }
 func (ife *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -202,6 +362,7 @@ type BlockStatement struct {
 func (bs *BlockStatement) expressionNode()      {}
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos() }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -213,13 +374,17 @@ func (bs *BlockStatement) String() string {
 }
 
 type FunctionLiteral struct {
-	Token  token.Token
-	Params []*Identifier
-	Body   *BlockStatement
+	Token      token.Token
+	Params     []*Identifier
+	Variadic   bool            // last param collects the remaining arguments
+	ReturnType *TypeAnnotation // optional
+	Body       *BlockStatement
+	Generator  bool // body yields, so calling it returns a generator
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos() }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
@@ -227,24 +392,54 @@ func (fl *FunctionLiteral) String() string {
 	for _, p := range fl.Params {
 		params = append(params, p.String())
 	}
+	if fl.Variadic {
+		params[len(params)-1] += "..."
+	}
 
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// MacroLiteral is like a function literal, except that its body runs before the
+// program does, taking the code of its arguments and returning code to replace
+// the call with
+type MacroLiteral struct {
+	Token  token.Token // token.MACRO
+	Params []*Identifier
+	Body   *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) Pos() token.Position  { return ml.Token.Pos() }
+func (ml *MacroLiteral) String() string {
+	params := []string{}
+	for _, p := range ml.Params {
+		params = append(params, p.String())
+	}
+	return ml.TokenLiteral() + "(" + strings.Join(params, ", ") + ") " + ml.Body.String()
+}
+
 type CallExpression struct {
 	Token    token.Token
 	Function Expression
 	Args     []Expression
+	// Source is the text between the parentheses as written, which is only
+	// kept for calls to dbg
+	Source string
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Function.Pos() }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -270,8 +465,23 @@ type StringLiteral struct {
 
 func (s *StringLiteral) expressionNode()      {}
 func (s *StringLiteral) TokenLiteral() string { return s.Token.Literal }
+func (s *StringLiteral) Pos() token.Position  { return s.Token.Pos() }
 func (s *StringLiteral) String() string       { return s.TokenLiteral() }
 
+// InterfaceLiteral lists the methods a value must provide to implement it, like
+// interface { area, perimeter }
+type InterfaceLiteral struct {
+	Token   token.Token // token.INTERFACE
+	Methods []string
+}
+
+func (il *InterfaceLiteral) expressionNode()      {}
+func (il *InterfaceLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *InterfaceLiteral) Pos() token.Position  { return il.Token.Pos() }
+func (il *InterfaceLiteral) String() string {
+	return il.TokenLiteral() + " { " + strings.Join(il.Methods, ", ") + " }"
+}
+
 type ArrayLiteral struct {
 	Token token.Token
 	Elems []Expression
@@ -279,6 +489,7 @@ type ArrayLiteral struct {
 
 func (a *ArrayLiteral) expressionNode()      {}
 func (a *ArrayLiteral) TokenLiteral() string { return a.Token.Literal }
+func (a *ArrayLiteral) Pos() token.Position  { return a.Token.Pos() }
 func (a *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -296,6 +507,29 @@ func (a *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// HashLiteral holds its pairs as parallel slices, in the order they are written.
+// A spread like ...other is kept as a key without a value.
+type HashLiteral struct {
+	Token  token.Token // token.LBRACE
+	Keys   []Expression
+	Values []Expression
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos() }
+func (hl *HashLiteral) String() string {
+	pairs := []string{}
+	for i, key := range hl.Keys {
+		if hl.Values[i] == nil {
+			pairs = append(pairs, key.String())
+			continue
+		}
+		pairs = append(pairs, key.String()+": "+hl.Values[i].String())
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
 type IndexExpression struct {
 	Token token.Token
 	Left  Expression
@@ -304,6 +538,7 @@ type IndexExpression struct {
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Left.Pos() }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -315,3 +550,216 @@ func (ie *IndexExpression) String() string {
 
 	return out.String()
 }
+
+type SliceExpression struct {
+	Token token.Token
+	Left  Expression
+	Low   Expression
+	High  Expression
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) Pos() token.Position  { return se.Left.Pos() }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+type TupleExpression struct {
+	Token token.Token
+	Elems []Expression
+}
+
+func (te *TupleExpression) expressionNode()      {}
+func (te *TupleExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TupleExpression) Pos() token.Position  { return te.Token.Pos() }
+func (te *TupleExpression) String() string {
+	var out bytes.Buffer
+
+	for i, e := range te.Elems {
+		out.WriteString(e.String())
+		if i+1 != len(te.Elems) {
+			out.WriteString(", ")
+		}
+	}
+
+	return out.String()
+}
+
+type SpreadExpression struct {
+	Token token.Token // token.ELLIPSIS
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) Pos() token.Position  { return se.Token.Pos() }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
+// MatchExpression evaluates the first arm whose pattern matches Subject and
+// whose guard, if any, holds
+type MatchExpression struct {
+	Token   token.Token // token.MATCH
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) Pos() token.Position  { return me.Token.Pos() }
+func (me *MatchExpression) String() string {
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	return "match " + me.Subject.String() + " { " + strings.Join(arms, ", ") + " }"
+}
+
+// MatchArm binds the names in Pattern for its guard and body to use. The body
+// is a block or a single expression.
+type MatchArm struct {
+	Token   token.Token // first token of the pattern
+	Pattern Pattern
+	Guard   Expression // optional
+	Body    Expression
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+func (ma *MatchArm) Pos() token.Position  { return ma.Token.Pos() }
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if " + ma.Guard.String())
+	}
+	out.WriteString(" => " + ma.Body.String())
+
+	return out.String()
+}
+
+// WildcardPattern, written _, matches anything
+type WildcardPattern struct {
+	Token token.Token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) Pos() token.Position  { return wp.Token.Pos() }
+func (wp *WildcardPattern) String() string       { return "_" }
+
+// BindingPattern matches anything, binding it to Name
+type BindingPattern struct {
+	Name *Identifier
+}
+
+func (bp *BindingPattern) patternNode()         {}
+func (bp *BindingPattern) TokenLiteral() string { return bp.Name.TokenLiteral() }
+func (bp *BindingPattern) Pos() token.Position  { return bp.Name.Pos() }
+func (bp *BindingPattern) String() string       { return bp.Name.String() }
+
+// LiteralPattern matches values equal to a literal like 1, -2.5, "a" or true
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+func (lp *LiteralPattern) Pos() token.Position  { return lp.Value.Pos() }
+func (lp *LiteralPattern) String() string       { return lp.Value.String() }
+
+// ArrayPattern matches arrays whose elements match Elems. Without Rest the
+// lengths must be equal, while with it Rest matches an array of the elements
+// left over, like in [first, ...rest].
+type ArrayPattern struct {
+	Token token.Token // token.LBRACKET
+	Elems []Pattern
+	Rest  Pattern // optional
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) Pos() token.Position  { return ap.Token.Pos() }
+func (ap *ArrayPattern) String() string {
+	elems := []string{}
+	for _, e := range ap.Elems {
+		elems = append(elems, e.String())
+	}
+	if ap.Rest != nil {
+		elems = append(elems, "..."+ap.Rest.String())
+	}
+
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+// HashPattern matches hashes holding every key in Keys, with values matching
+// Values. Other keys are ignored. A name on its own like in {name, age} is
+// short for {"name": name, "age": age}.
+type HashPattern struct {
+	Token  token.Token // token.LBRACE
+	Keys   []Expression
+	Values []Pattern
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) Pos() token.Position  { return hp.Token.Pos() }
+func (hp *HashPattern) String() string {
+	pairs := []string{}
+	for i, key := range hp.Keys {
+		pairs = append(pairs, key.String()+": "+hp.Values[i].String())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// TuplePattern matches tuples of the same length whose elements match Elems
+type TuplePattern struct {
+	Token token.Token // token.LPAREN
+	Elems []Pattern
+}
+
+func (tp *TuplePattern) patternNode()         {}
+func (tp *TuplePattern) TokenLiteral() string { return tp.Token.Literal }
+func (tp *TuplePattern) Pos() token.Position  { return tp.Token.Pos() }
+func (tp *TuplePattern) String() string {
+	elems := []string{}
+	for _, e := range tp.Elems {
+		elems = append(elems, e.String())
+	}
+
+	return "(" + strings.Join(elems, ", ") + ")"
+}
+
+// TypePattern, written is Int, matches values of the type its name is bound
+// to, which are bound to Binding when it's written like is Str as s
+type TypePattern struct {
+	Token   token.Token // the is identifier
+	Type    *Identifier
+	Binding *BindingPattern // optional
+}
+
+func (tp *TypePattern) patternNode()         {}
+func (tp *TypePattern) TokenLiteral() string { return tp.Token.Literal }
+func (tp *TypePattern) Pos() token.Position  { return tp.Token.Pos() }
+func (tp *TypePattern) String() string {
+	if tp.Binding != nil {
+		return "is " + tp.Type.String() + " as " + tp.Binding.String()
+	}
+	return "is " + tp.Type.String()
+}
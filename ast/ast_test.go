@@ -1,6 +1,8 @@
 package ast
 
 import (
+	"bytes"
+	"reflect"
 	"testing"
 
 	"github.com/nayyara-airlangga/basedlang/token"
@@ -26,3 +28,156 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestWalk(t *testing.T) {
+	// fn(x) { x + 1 }(2)
+	program := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{
+				Expression: &CallExpression{
+					Function: &FunctionLiteral{
+						Params: []*Identifier{{Value: "x"}},
+						Body: &BlockStatement{
+							Statements: []Statement{
+								&ExpressionStatement{
+									Expression: &InfixExpression{
+										Left:     &Identifier{Value: "x"},
+										Operator: "+",
+										Right:    &IntLiteral{Value: 1},
+									},
+								},
+							},
+						},
+					},
+					Args: []Expression{&IntLiteral{Value: 2}},
+				},
+			},
+		},
+	}
+
+	visited := []string{}
+	Walk(program, func(n Node) bool {
+		visited = append(visited, reflect.TypeOf(n).Elem().Name())
+		return true
+	})
+
+	expected := []string{
+		"Program", "ExpressionStatement", "CallExpression", "FunctionLiteral", "Identifier",
+		"BlockStatement", "ExpressionStatement", "InfixExpression", "Identifier", "IntLiteral",
+		"IntLiteral",
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("wrong traversal order.\nexpected=%v\ngot=%v", expected, visited)
+	}
+
+	visited = []string{}
+	Walk(program, func(n Node) bool {
+		visited = append(visited, reflect.TypeOf(n).Elem().Name())
+		_, isFunc := n.(*FunctionLiteral)
+		return !isFunc
+	})
+
+	expected = []string{"Program", "ExpressionStatement", "CallExpression", "FunctionLiteral", "IntLiteral"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("children were not skipped.\nexpected=%v\ngot=%v", expected, visited)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+				Name:  &Identifier{Token: token.Token{Type: token.IDENT, Literal: "add"}, Value: "add"},
+				Names: []*Identifier{{Token: token.Token{Type: token.IDENT, Literal: "add"}, Value: "add"}},
+				Value: &FunctionLiteral{
+					Token: token.Token{Type: token.FUNCTION, Literal: "fn"},
+					Params: []*Identifier{
+						{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"},
+					},
+					Body: &BlockStatement{
+						Token: token.Token{Type: token.LBRACE, Literal: "{"},
+						Statements: []Statement{
+							&ExpressionStatement{
+								Token: token.Token{Type: token.IDENT, Literal: "x"},
+								Expression: &InfixExpression{
+									Token:    token.Token{Type: token.PLUS, Literal: "+"},
+									Left:     &Identifier{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"},
+									Operator: "+",
+									Right:    &IntLiteral{Token: token.Token{Type: token.INT, Literal: "1"}, Value: 1},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, program); err != nil {
+		t.Fatalf("Encode returned error: %s", err)
+	}
+	if !IsEncoded(buf.Bytes()) {
+		t.Fatalf("encoded program does not start with %q", EncodingMagic)
+	}
+
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode returned error: %s", err)
+	}
+	if !reflect.DeepEqual(decoded, program) {
+		t.Errorf("decoded program wrong.\nwant=%s\ngot=%s", program, decoded)
+	}
+
+	stale := []byte(EncodingMagic + "\x00")
+	if _, err := Decode(bytes.NewReader(stale)); err == nil {
+		t.Errorf("expected an error decoding an unsupported version")
+	}
+	if _, err := Decode(bytes.NewReader([]byte("let x = 1;"))); err == nil {
+		t.Errorf("expected an error decoding source code")
+	}
+}
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntLiteral{Token: token.Token{Type: token.INT, Literal: "1"}, Value: 1} }
+	block := func() *BlockStatement {
+		return &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}}
+	}
+	turnOneIntoTwo := func(node Node) Node {
+		if lit, isInt := node.(*IntLiteral); isInt && lit.Value == 1 {
+			return &IntLiteral{Token: token.Token{Type: token.INT, Literal: "2"}, Value: 2}
+		}
+		return node
+	}
+
+	tests := []struct {
+		node     Node
+		expected string
+	}{
+		{one(), "2"},
+		{&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}}, "2"},
+		{&InfixExpression{Left: one(), Operator: "+", Right: one()}, "(2 + 2)"},
+		{&PrefixExpression{Operator: "-", Right: one()}, "(-2)"},
+		{&IndexExpression{Left: one(), Index: one()}, "(2[2])"},
+		{&IfExpression{Condition: one(), Body: block(), Else: block()}, "if 2 2 else 2"},
+		{&ReturnStatement{Token: token.Token{Literal: "return"}, ReturnValue: one()}, "return 2;"},
+		{&LetStatement{Token: token.Token{Literal: "let"}, Name: &Identifier{Value: "x"}, Value: one()}, "let x = 2;"},
+		{&FunctionLiteral{Token: token.Token{Literal: "fn"}, Params: []*Identifier{}, Body: block()}, "fn() 2"},
+		{&CallExpression{Function: &Identifier{Value: "f"}, Args: []Expression{one(), one()}}, "f(2, 2)"},
+		{&ArrayLiteral{Elems: []Expression{one(), one()}}, "[2, 2]"},
+		{&HashLiteral{Keys: []Expression{one()}, Values: []Expression{one()}}, "{2: 2}"},
+	}
+
+	for _, tc := range tests {
+		original := tc.node.String()
+
+		modified := Modify(tc.node, turnOneIntoTwo)
+		if modified.String() != tc.expected {
+			t.Errorf("wrong modification. expected=%q, got=%q", tc.expected, modified.String())
+		}
+		if tc.node.String() != original {
+			t.Errorf("original was changed. expected=%q, got=%q", original, tc.node.String())
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package ast
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+// EncodingMagic starts every file written by Encode, followed by a byte holding
+// EncodingVersion
+const EncodingMagic = "BASEDC"
+
+// EncodingVersion is bumped whenever the encoded form of the AST changes, so
+// stale files are rejected instead of being decoded into garbage
+const EncodingVersion = 2
+
+func init() {
+	// Nodes are stored behind Statement and Expression interfaces, which gob can
+	// only encode for registered types
+	for _, node := range []Node{
+		&Identifier{},
+		&LetStatement{},
+		&ReturnStatement{},
+		&YieldStatement{},
+		&RaiseStatement{},
+		&ForStatement{},
+		&DoWhileStatement{},
+		&AssignStatement{},
+		&GlobalStatement{},
+		&ExpressionStatement{},
+		&IntLiteral{},
+		&FloatLiteral{},
+		&PrefixExpression{},
+		&InfixExpression{},
+		&BooleanLiteral{},
+		&IfExpression{},
+		&BlockStatement{},
+		&FunctionLiteral{},
+		&MacroLiteral{},
+		&CallExpression{},
+		&StringLiteral{},
+		&InterfaceLiteral{},
+		&ArrayLiteral{},
+		&HashLiteral{},
+		&IndexExpression{},
+		&SliceExpression{},
+		&TupleExpression{},
+		&SpreadExpression{},
+		&MatchExpression{},
+		&WildcardPattern{},
+		&BindingPattern{},
+		&LiteralPattern{},
+		&ArrayPattern{},
+		&HashPattern{},
+		&TuplePattern{},
+		&TypePattern{},
+	} {
+		gob.Register(node)
+	}
+}
+
+// Encode writes program to w in a versioned binary form that Decode can load
+// without lexing or parsing the source again
+func Encode(w io.Writer, program *Program) error {
+	if _, err := io.WriteString(w, EncodingMagic); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte{EncodingVersion}); err != nil {
+		return err
+	}
+	return gob.NewEncoder(w).Encode(program)
+}
+
+// Decode reads a program written by Encode
+func Decode(r io.Reader) (*Program, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(EncodingMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil || !IsEncoded(header) {
+		return nil, fmt.Errorf("not a compiled basedlang program")
+	}
+	if version := header[len(EncodingMagic)]; version != EncodingVersion {
+		return nil, fmt.Errorf("unsupported compiled program version %d, want %d", version, EncodingVersion)
+	}
+
+	program := &Program{}
+	if err := gob.NewDecoder(br).Decode(program); err != nil {
+		return nil, fmt.Errorf("corrupt compiled program: %w", err)
+	}
+
+	return program, nil
+}
+
+// IsEncoded reports whether data starts like a program written by Encode
+func IsEncoded(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(EncodingMagic))
+}
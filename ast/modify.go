@@ -0,0 +1,170 @@
+package ast
+
+// Modify rebuilds the tree rooted at node bottom up, replacing every statement
+// and expression with what modifier returns for it once its children are
+// rebuilt. Nodes are copied rather than changed, so the original tree can be
+// modified again, like the body of a macro that is called many times.
+// Parameters, names bound by let and for, and patterns are left as they are.
+func Modify(node Node, modifier func(Node) Node) Node {
+	switch n := node.(type) {
+	case nil:
+		return nil
+	case *Program:
+		c := *n
+		c.Statements = modifyStatements(n.Statements, modifier)
+		return modifier(&c)
+	case *LetStatement:
+		c := *n
+		c.Value = modifyExpression(n.Value, modifier)
+		return modifier(&c)
+	case *ReturnStatement:
+		c := *n
+		c.ReturnValue = modifyExpression(n.ReturnValue, modifier)
+		return modifier(&c)
+	case *YieldStatement:
+		c := *n
+		c.Value = modifyExpression(n.Value, modifier)
+		return modifier(&c)
+	case *RaiseStatement:
+		c := *n
+		c.Value = modifyExpression(n.Value, modifier)
+		return modifier(&c)
+	case *ForStatement:
+		c := *n
+		c.Iterable = modifyExpression(n.Iterable, modifier)
+		c.Body = modifyBlock(n.Body, modifier)
+		return modifier(&c)
+	case *DoWhileStatement:
+		c := *n
+		c.Body = modifyBlock(n.Body, modifier)
+		c.Condition = modifyExpression(n.Condition, modifier)
+		return modifier(&c)
+	case *GlobalStatement:
+		c := *n
+		c.Value = modifyExpression(n.Value, modifier)
+		return modifier(&c)
+	case *AssignStatement:
+		c := *n
+		c.Target = modifyExpression(n.Target, modifier)
+		c.Value = modifyExpression(n.Value, modifier)
+		return modifier(&c)
+	case *ExpressionStatement:
+		c := *n
+		c.Expression = modifyExpression(n.Expression, modifier)
+		return modifier(&c)
+	case *BlockStatement:
+		c := *n
+		c.Statements = modifyStatements(n.Statements, modifier)
+		return modifier(&c)
+	case *PrefixExpression:
+		c := *n
+		c.Right = modifyExpression(n.Right, modifier)
+		return modifier(&c)
+	case *InfixExpression:
+		c := *n
+		c.Left = modifyExpression(n.Left, modifier)
+		c.Right = modifyExpression(n.Right, modifier)
+		return modifier(&c)
+	case *IfExpression:
+		c := *n
+		c.Condition = modifyExpression(n.Condition, modifier)
+		c.Body = modifyBlock(n.Body, modifier)
+		c.Else = modifyExpression(n.Else, modifier)
+		return modifier(&c)
+	case *FunctionLiteral:
+		c := *n
+		c.Body = modifyBlock(n.Body, modifier)
+		return modifier(&c)
+	case *MacroLiteral:
+		c := *n
+		c.Body = modifyBlock(n.Body, modifier)
+		return modifier(&c)
+	case *CallExpression:
+		c := *n
+		c.Function = modifyExpression(n.Function, modifier)
+		c.Args = modifyExpressions(n.Args, modifier)
+		return modifier(&c)
+	case *ArrayLiteral:
+		c := *n
+		c.Elems = modifyExpressions(n.Elems, modifier)
+		return modifier(&c)
+	case *HashLiteral:
+		c := *n
+		c.Keys = modifyExpressions(n.Keys, modifier)
+		c.Values = modifyExpressions(n.Values, modifier)
+		return modifier(&c)
+	case *TupleExpression:
+		c := *n
+		c.Elems = modifyExpressions(n.Elems, modifier)
+		return modifier(&c)
+	case *IndexExpression:
+		c := *n
+		c.Left = modifyExpression(n.Left, modifier)
+		c.Index = modifyExpression(n.Index, modifier)
+		return modifier(&c)
+	case *SliceExpression:
+		c := *n
+		c.Left = modifyExpression(n.Left, modifier)
+		c.Low = modifyExpression(n.Low, modifier)
+		c.High = modifyExpression(n.High, modifier)
+		return modifier(&c)
+	case *SpreadExpression:
+		c := *n
+		c.Value = modifyExpression(n.Value, modifier)
+		return modifier(&c)
+	case *MatchExpression:
+		c := *n
+		c.Subject = modifyExpression(n.Subject, modifier)
+		c.Arms = make([]*MatchArm, len(n.Arms))
+		for i, arm := range n.Arms {
+			armCopy := *arm
+			armCopy.Guard = modifyExpression(arm.Guard, modifier)
+			armCopy.Body = modifyExpression(arm.Body, modifier)
+			c.Arms[i] = &armCopy
+		}
+		return modifier(&c)
+	default:
+		return modifier(node)
+	}
+}
+
+// modifyExpression modifies expr, keeping it as it was if the modifier
+// replaces it with something that isn't an expression
+func modifyExpression(expr Expression, modifier func(Node) Node) Expression {
+	if expr == nil {
+		return nil
+	}
+	if modified, isExpr := Modify(expr, modifier).(Expression); isExpr {
+		return modified
+	}
+	return expr
+}
+
+func modifyExpressions(exprs []Expression, modifier func(Node) Node) []Expression {
+	modified := make([]Expression, len(exprs))
+	for i, e := range exprs {
+		modified[i] = modifyExpression(e, modifier)
+	}
+	return modified
+}
+
+func modifyStatements(stmts []Statement, modifier func(Node) Node) []Statement {
+	modified := make([]Statement, len(stmts))
+	for i, s := range stmts {
+		modified[i] = s
+		if m, isStmt := Modify(s, modifier).(Statement); isStmt {
+			modified[i] = m
+		}
+	}
+	return modified
+}
+
+func modifyBlock(block *BlockStatement, modifier func(Node) Node) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	if modified, isBlock := Modify(block, modifier).(*BlockStatement); isBlock {
+		return modified
+	}
+	return block
+}
@@ -0,0 +1,67 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Fprint writes the tree rooted at node to w, one node per line and indented by
+// depth, for debugging the parser
+func Fprint(w io.Writer, node Node) error {
+	return fprint(w, node, 0)
+}
+
+func fprint(w io.Writer, node Node, depth int) error {
+	line := strings.Repeat("  ", depth) + reflect.TypeOf(node).Elem().Name()
+	if detail := nodeDetail(node); detail != "" {
+		line += " " + detail
+	}
+
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+
+	for _, child := range Children(node) {
+		if err := fprint(w, child, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nodeDetail describes what a node holds besides its children
+func nodeDetail(node Node) string {
+	switch n := node.(type) {
+	case *Identifier:
+		return n.Value
+	case *IntLiteral:
+		return n.TokenLiteral()
+	case *FloatLiteral:
+		return n.TokenLiteral()
+	case *BooleanLiteral:
+		return n.TokenLiteral()
+	case *TypeAnnotation:
+		return n.Name
+	case *StringLiteral:
+		return fmt.Sprintf("%q", n.Value)
+	case *InterfaceLiteral:
+		return strings.Join(n.Methods, ", ")
+	case *PrefixExpression:
+		return n.Operator
+	case *InfixExpression:
+		return n.Operator
+	case *FunctionLiteral:
+		details := []string{}
+		if n.Variadic {
+			details = append(details, "variadic")
+		}
+		if n.Generator {
+			details = append(details, "generator")
+		}
+		return strings.Join(details, " ")
+	}
+	return ""
+}
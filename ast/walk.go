@@ -0,0 +1,219 @@
+package ast
+
+import "github.com/nayyara-airlangga/basedlang/token"
+
+// Walk traverses the tree rooted at node in depth-first order, calling fn for
+// every node it visits. Children of a node are skipped when fn returns false.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+
+	for _, child := range Children(node) {
+		Walk(child, fn)
+	}
+}
+
+// Children lists the direct children of node in source order, leaving out
+// optional parts that are missing like an else branch or a slice bound
+func Children(node Node) []Node {
+	children := []Node{}
+
+	add := func(nodes ...Node) {
+		for _, n := range nodes {
+			if n != nil {
+				children = append(children, n)
+			}
+		}
+	}
+	addExpr := func(exprs ...Expression) {
+		for _, e := range exprs {
+			if e != nil {
+				children = append(children, e)
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statements {
+			add(s)
+		}
+	case *Identifier:
+		if n.Type != nil {
+			add(n.Type)
+		}
+	case *LetStatement:
+		for _, name := range n.Names {
+			add(name)
+		}
+		addExpr(n.Value)
+	case *ReturnStatement:
+		addExpr(n.ReturnValue)
+	case *YieldStatement:
+		addExpr(n.Value)
+	case *RaiseStatement:
+		addExpr(n.Value)
+	case *ForStatement:
+		for _, name := range n.Names {
+			add(name)
+		}
+		addExpr(n.Iterable)
+		if n.Body != nil {
+			add(n.Body)
+		}
+	case *DoWhileStatement:
+		if n.Body != nil {
+			add(n.Body)
+		}
+		addExpr(n.Condition)
+	case *AssignStatement:
+		addExpr(n.Target, n.Value)
+	case *GlobalStatement:
+		add(n.Name)
+		addExpr(n.Value)
+	case *ExpressionStatement:
+		addExpr(n.Expression)
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			add(s)
+		}
+	case *PrefixExpression:
+		addExpr(n.Right)
+	case *InfixExpression:
+		addExpr(n.Left, n.Right)
+	case *IfExpression:
+		addExpr(n.Condition)
+		if n.Body != nil {
+			add(n.Body)
+		}
+		addExpr(n.Else)
+	case *FunctionLiteral:
+		for _, p := range n.Params {
+			add(p)
+		}
+		if n.ReturnType != nil {
+			add(n.ReturnType)
+		}
+		if n.Body != nil {
+			add(n.Body)
+		}
+	case *MacroLiteral:
+		for _, p := range n.Params {
+			add(p)
+		}
+		if n.Body != nil {
+			add(n.Body)
+		}
+	case *CallExpression:
+		addExpr(n.Function)
+		addExpr(n.Args...)
+	case *ArrayLiteral:
+		addExpr(n.Elems...)
+	case *HashLiteral:
+		for i, key := range n.Keys {
+			addExpr(key, n.Values[i])
+		}
+	case *TupleExpression:
+		addExpr(n.Elems...)
+	case *IndexExpression:
+		addExpr(n.Left, n.Index)
+	case *SliceExpression:
+		addExpr(n.Left, n.Low, n.High)
+	case *SpreadExpression:
+		addExpr(n.Value)
+	case *MatchExpression:
+		addExpr(n.Subject)
+		for _, arm := range n.Arms {
+			add(arm)
+		}
+	case *MatchArm:
+		add(n.Pattern)
+		addExpr(n.Guard, n.Body)
+	case *BindingPattern:
+		add(n.Name)
+	case *LiteralPattern:
+		addExpr(n.Value)
+	case *ArrayPattern:
+		for _, e := range n.Elems {
+			add(e)
+		}
+		if n.Rest != nil {
+			add(n.Rest)
+		}
+	case *HashPattern:
+		for i, key := range n.Keys {
+			add(key, n.Values[i])
+		}
+	case *TuplePattern:
+		for _, e := range n.Elems {
+			add(e)
+		}
+	case *TypePattern:
+		add(n.Type)
+		if n.Binding != nil {
+			add(n.Binding)
+		}
+	}
+
+	return children
+}
+
+// PatternBindings lists the names pattern binds when it matches
+func PatternBindings(pattern Pattern) []*Identifier {
+	names := []*Identifier{}
+	Walk(pattern, func(n Node) bool {
+		if bp, isBinding := n.(*BindingPattern); isBinding {
+			names = append(names, bp.Name)
+		}
+		return true
+	})
+	return names
+}
+
+// End approximates where node ends in the source, just past its last token.
+// Closing delimiters aren't kept in the tree, so they are assumed to follow the
+// last element right away, as they do in formatted code.
+func End(node Node) token.Position {
+	width := len(node.TokenLiteral())
+	if _, isStr := node.(*StringLiteral); isStr {
+		width += 2 // the quotes aren't part of the literal
+	}
+	end := node.Pos()
+	end.Column += max(width, 1)
+
+	// Pipelines are calls too, but without parentheses
+	var open token.Token
+	switch n := node.(type) {
+	case *CallExpression:
+		open = n.Token
+	case *IndexExpression:
+		open = n.Token
+	case *SliceExpression:
+		open = n.Token
+	case *ArrayLiteral:
+		open = n.Token
+	case *HashLiteral:
+		open = n.Token
+	}
+	delimited := open.Type == token.LPAREN || open.Type == token.LBRACKET || open.Type == token.LBRACE
+	if delimited {
+		end = later(end, open.End())
+	}
+
+	for _, child := range Children(node) {
+		end = later(end, End(child))
+	}
+
+	if delimited {
+		end.Column++ // the closing delimiter
+	}
+	return end
+}
+
+func later(a, b token.Position) token.Position {
+	if b.Line > a.Line || b.Line == a.Line && b.Column > a.Column {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/evaluator"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+// buildCmd parses a script once and writes it to a .basedc file, which run
+// loads without lexing or parsing the source again
+func buildCmd(args []string) int {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	output := fs.String("o", "", "write the compiled program to `file` instead of <script>.basedc")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "build expects a single script to compile")
+		return 2
+	}
+
+	filename := fs.Arg(0)
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.Parse()
+	if len(p.Errs()) != 0 {
+		printParserErrors(filename, string(src), p.Errs())
+		return 1
+	}
+	// Macros are expanded once here, so compiled programs no longer need them
+	program, expandErr := evaluator.ExpandMacros(program, object.NewEnvironment())
+	if expandErr != nil {
+		printRuntimeError(filename, string(src), expandErr)
+		return 1
+	}
+
+	path := *output
+	if path == "" {
+		path = strings.TrimSuffix(filename, ".based") + ".basedc"
+	}
+
+	if err := writeCompiled(path, program); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func writeCompiled(path string, program *ast.Program) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := ast.Encode(f, program); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nayyara-airlangga/basedlang/evaluator"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/parser"
+	"github.com/nayyara-airlangga/basedlang/resolver"
+	"github.com/nayyara-airlangga/basedlang/types"
+)
+
+// checkCmd parses and resolves scripts without running them, reporting every
+// problem found. With --types, scripts are type checked as well. It exits with
+// 1 if any script has problems, warnings alone don't count.
+func checkCmd(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	typed := fs.Bool("types", false, "also report operations on values of the wrong type")
+	plugins := make(map[string]object.BuiltinFn)
+	pluginFlag(fs, plugins)
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "check expects at least one script to check")
+		return 2
+	}
+
+	// Builtins of plugins are bound like any other
+	predeclared := evaluator.BuiltinNames()
+	for name := range plugins {
+		predeclared = append(predeclared, name)
+	}
+
+	status := 0
+	for _, filename := range fs.Args() {
+		diagnostics, warnings, err := checkFile(filename, predeclared, *typed)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		for _, msg := range diagnostics {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, msg)
+		}
+		for _, msg := range warnings {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, msg)
+		}
+		if len(diagnostics) != 0 {
+			status = 1
+		}
+	}
+
+	return status
+}
+
+func checkFile(filename string, predeclared []string, typed bool) (diagnostics, warnings []string, err error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.Parse()
+	// Resolving a partially parsed program would only add noise
+	if len(p.Errs()) != 0 {
+		diagnostics := make([]string, len(p.Errs()))
+		for i, d := range p.Errs() {
+			diagnostics[i] = d.String()
+		}
+		return diagnostics, nil, nil
+	}
+	program, expandErr := evaluator.ExpandMacros(program, object.NewEnvironment())
+	if expandErr != nil {
+		return []string{fmt.Sprintf("%d:%d: %s", expandErr.Pos.Line, expandErr.Pos.Column, expandErr.Message)}, nil, nil
+	}
+
+	r := resolver.New(predeclared)
+	r.Resolve(program)
+	diagnostics = r.Errs()
+
+	if typed {
+		c := types.New()
+		c.Check(program)
+		diagnostics = append(diagnostics, c.Errs()...)
+	}
+
+	return diagnostics, r.Warnings(), nil
+}
@@ -0,0 +1,140 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+)
+
+// Profile counts how many times each line holding a statement was executed
+type Profile struct {
+	mu     sync.Mutex
+	counts map[int]int
+}
+
+func NewProfile() *Profile {
+	return &Profile{counts: make(map[int]int)}
+}
+
+// isCovered reports whether n is a statement coverage is measured on. Blocks
+// only group other statements, so they aren't counted on their own.
+func isCovered(n ast.Node) bool {
+	switch n.(type) {
+	case *ast.LetStatement, *ast.ReturnStatement, *ast.ExpressionStatement, *ast.YieldStatement, *ast.RaiseStatement, *ast.ForStatement, *ast.DoWhileStatement, *ast.AssignStatement, *ast.GlobalStatement:
+		return true
+	default:
+		return false
+	}
+}
+
+// Record marks n as executed if it is a covered statement
+func (p *Profile) Record(n ast.Node) {
+	if !isCovered(n) {
+		return
+	}
+
+	p.mu.Lock()
+	p.counts[n.Pos().Line]++
+	p.mu.Unlock()
+}
+
+// Count reports how many times statements on line were executed
+func (p *Profile) Count(line int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.counts[line]
+}
+
+// Lines reports the sorted lines of program holding covered statements
+func Lines(program *ast.Program) []int {
+	seen := make(map[int]bool)
+
+	ast.Walk(program, func(n ast.Node) bool {
+		if isCovered(n) {
+			seen[n.Pos().Line] = true
+		}
+		return true
+	})
+
+	lines := make([]int, 0, len(seen))
+	for line := range seen {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+
+	return lines
+}
+
+// Percent reports the share of statement lines of program that were executed
+func (p *Profile) Percent(program *ast.Program) float64 {
+	lines := Lines(program)
+	if len(lines) == 0 {
+		return 100
+	}
+
+	hit := 0
+	for _, line := range lines {
+		if p.Count(line) > 0 {
+			hit++
+		}
+	}
+
+	return float64(hit) * 100 / float64(len(lines))
+}
+
+// WriteReport writes source annotated with the execution count of every
+// statement line, marking the ones never executed with #####
+func (p *Profile) WriteReport(w io.Writer, source string, program *ast.Program) error {
+	covered := make(map[int]bool)
+	for _, line := range Lines(program) {
+		covered[line] = true
+	}
+
+	bw := bufio.NewWriter(w)
+
+	for i, text := range strings.Split(strings.TrimRight(source, "\n"), "\n") {
+		line := i + 1
+		text = strings.TrimRight(text, "\r")
+
+		switch count := p.Count(line); {
+		case !covered[line]:
+			fmt.Fprintf(bw, "%6s | %s\n", "-", text)
+		case count == 0:
+			fmt.Fprintf(bw, "%6s | %s\n", "#####", text)
+		default:
+			fmt.Fprintf(bw, "%6d | %s\n", count, text)
+		}
+	}
+
+	fmt.Fprintf(bw, "coverage: %.1f%% of statements\n", p.Percent(program))
+
+	return bw.Flush()
+}
+
+// WriteLCOV writes the profile in the lcov tracefile format for filename
+func (p *Profile) WriteLCOV(w io.Writer, filename string, program *ast.Program) error {
+	bw := bufio.NewWriter(w)
+
+	lines := Lines(program)
+	hit := 0
+
+	fmt.Fprintln(bw, "TN:")
+	fmt.Fprintf(bw, "SF:%s\n", filename)
+	for _, line := range lines {
+		count := p.Count(line)
+		if count > 0 {
+			hit++
+		}
+		fmt.Fprintf(bw, "DA:%d,%d\n", line, count)
+	}
+	fmt.Fprintf(bw, "LF:%d\n", len(lines))
+	fmt.Fprintf(bw, "LH:%d\n", hit)
+	fmt.Fprintln(bw, "end_of_record")
+
+	return bw.Flush()
+}
@@ -0,0 +1,90 @@
+package coverage
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+const source = `let abs = fn(x) {
+  if (x < 0) {
+    return -x;
+  }
+  x
+};
+abs(5);
+abs(6);
+`
+
+func TestReport(t *testing.T) {
+	program := parser.New(lexer.New(source)).Parse()
+
+	profile := NewProfile()
+	// Simulate evaluating the top level statements and two calls without the
+	// negative branch
+	ast.Walk(program, func(n ast.Node) bool {
+		switch n.Pos().Line {
+		case 1, 7, 8:
+			profile.Record(n)
+		case 2, 5:
+			profile.Record(n)
+			profile.Record(n)
+		}
+		return true
+	})
+
+	var out bytes.Buffer
+	if err := profile.WriteReport(&out, source, program); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := `     1 | let abs = fn(x) {
+     2 |   if (x < 0) {
+ ##### |     return -x;
+     - |   }
+     2 |   x
+     - | };
+     1 | abs(5);
+     1 | abs(6);
+coverage: 83.3% of statements
+`
+	if out.String() != expected {
+		t.Errorf("wrong report.\nexpected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestLCOV(t *testing.T) {
+	program := parser.New(lexer.New(source)).Parse()
+
+	profile := NewProfile()
+	ast.Walk(program, func(n ast.Node) bool {
+		if n.Pos().Line == 7 {
+			profile.Record(n)
+		}
+		return true
+	})
+
+	var out bytes.Buffer
+	if err := profile.WriteLCOV(&out, "abs.based", program); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := `TN:
+SF:abs.based
+DA:1,0
+DA:2,0
+DA:3,0
+DA:5,0
+DA:7,1
+DA:8,0
+LF:6
+LH:1
+end_of_record
+`
+	if out.String() != expected {
+		t.Errorf("wrong lcov output.\nexpected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
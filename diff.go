@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/diff"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+// diffCmd reports the declarations added, removed or changed from one script to
+// another. Like diff(1), it exits with 1 when the scripts differ and 2 when
+// they can't be compared.
+func diffCmd(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "diff expects two scripts to compare")
+		return 2
+	}
+
+	programs := make([]*ast.Program, 2)
+	for i, filename := range args {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+
+		p := parser.New(lexer.New(string(src)))
+		programs[i] = p.Parse()
+		if len(p.Errs()) != 0 {
+			printParserErrors(filename, string(src), p.Errs())
+			return 2
+		}
+	}
+
+	changes := diff.Declarations(programs[0], programs[1])
+	for _, c := range changes {
+		switch c.Kind {
+		case diff.Added:
+			pos := c.New.Pos()
+			fmt.Printf("%s:%d:%d: added %s\n\t+ %s\n", args[1], pos.Line, pos.Column, c.Name, c.New)
+		case diff.Removed:
+			pos := c.Old.Pos()
+			fmt.Printf("%s:%d:%d: removed %s\n\t- %s\n", args[0], pos.Line, pos.Column, c.Name, c.Old)
+		case diff.Changed:
+			pos := c.New.Pos()
+			fmt.Printf("%s:%d:%d: changed %s\n\t- %s\n\t+ %s\n", args[1], pos.Line, pos.Column, c.Name, c.Old, c.New)
+		}
+	}
+
+	if len(changes) != 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+// Package diff compares programs structurally, reporting the top-level
+// declarations one program adds, removes or changes relative to another.
+// Programs are compared by their syntax trees, so formatting, doc comments and
+// redundant parentheses make no difference.
+package diff
+
+import "github.com/nayyara-airlangga/basedlang/ast"
+
+// Kind tells how a declaration changed
+type Kind int
+
+const (
+	Added Kind = iota
+	Removed
+	Changed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	default:
+		return "changed"
+	}
+}
+
+// Change is a declaration that differs between two programs. Old is nil for
+// added declarations and New is nil for removed ones. When a name is bound more
+// than once, they are its first bindings.
+type Change struct {
+	Kind Kind
+	Name string
+	Old  *ast.LetStatement
+	New  *ast.LetStatement
+}
+
+// Declarations compares the top-level let statements of a and b by the names
+// they bind. A name counts as changed when the statements binding it differ in
+// either program. Removed and changed declarations come first, in the order of
+// a, followed by added ones in the order of b.
+func Declarations(a, b *ast.Program) []Change {
+	oldDecls, oldNames := declarations(a)
+	newDecls, newNames := declarations(b)
+
+	changes := []Change{}
+	for _, name := range oldNames {
+		old := oldDecls[name]
+		updated, exists := newDecls[name]
+		switch {
+		case !exists:
+			changes = append(changes, Change{Kind: Removed, Name: name, Old: old[0]})
+		case !sameStatements(old, updated):
+			changes = append(changes, Change{Kind: Changed, Name: name, Old: old[0], New: updated[0]})
+		}
+	}
+	for _, name := range newNames {
+		if _, exists := oldDecls[name]; !exists {
+			changes = append(changes, Change{Kind: Added, Name: name, New: newDecls[name][0]})
+		}
+	}
+
+	return changes
+}
+
+// declarations gathers the let statements binding each name at the top level of
+// program, along with the names in the order they are first bound
+func declarations(program *ast.Program) (map[string][]*ast.LetStatement, []string) {
+	decls := make(map[string][]*ast.LetStatement)
+	names := []string{}
+
+	for _, s := range program.Statements {
+		ls, isLet := s.(*ast.LetStatement)
+		if !isLet {
+			continue
+		}
+		for _, name := range ls.Names {
+			if _, seen := decls[name.Value]; !seen {
+				names = append(names, name.Value)
+			}
+			decls[name.Value] = append(decls[name.Value], ls)
+		}
+	}
+
+	return decls, names
+}
+
+func sameStatements(a, b []*ast.LetStatement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].String() != b[i].String() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.Parse()
+	if len(p.Errs()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errs())
+	}
+	return program
+}
+
+func TestDeclarations(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected []string
+	}{
+		{"let a = 1;", "let a = 1;", []string{}},
+		// Formatting, doc comments and parentheses don't count as changes
+		{
+			"let inc = fn(x) { x + 1 };",
+			"/// Adds one\nlet inc = fn(x) {\n  (x) + 1\n};",
+			[]string{},
+		},
+		{"let a = 1;", "let a = 2;", []string{"changed a"}},
+		{"let a = 1; let b = 2;", "let a = 1;", []string{"removed b"}},
+		{"let a = 1;", "let a = 1; let b = 2;", []string{"added b"}},
+		{
+			"let a = 1; let b = 2; let c = 3;",
+			"let d = 4; let c = 3; let a = 0;",
+			[]string{"changed a", "removed b", "added d"},
+		},
+		{"let x, y = f();", "let x, y = g();", []string{"changed x", "changed y"}},
+		{"let x, y = f();", "let x = 1; let y = 2;", []string{"changed x", "changed y"}},
+		// A name bound again counts as changed if any of its bindings differ
+		{"let a = 1; let a = a + 1;", "let a = 1; let a = a + 2;", []string{"changed a"}},
+		{"let a = 1; let a = a + 1;", "let a = 1;", []string{"changed a"}},
+		// Statements other than let don't declare anything
+		{"let a = 1; print(a);", "let a = 1; print(a + 1);", []string{}},
+	}
+
+	for _, tt := range tests {
+		changes := Declarations(parse(t, tt.a), parse(t, tt.b))
+
+		got := make([]string, len(changes))
+		for i, c := range changes {
+			got[i] = c.Kind.String() + " " + c.Name
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Declarations(%q, %q) wrong. expected=%v, got=%v", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestDeclarationsStatements(t *testing.T) {
+	a := parse(t, "let a = 1;\nlet b = 2;")
+	b := parse(t, "let b = 3;\nlet c = 4;")
+
+	expected := []Change{
+		{Kind: Removed, Name: "a", Old: a.Statements[0].(*ast.LetStatement)},
+		{Kind: Changed, Name: "b", Old: a.Statements[1].(*ast.LetStatement), New: b.Statements[0].(*ast.LetStatement)},
+		{Kind: Added, Name: "c", New: b.Statements[1].(*ast.LetStatement)},
+	}
+
+	if changes := Declarations(a, b); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Declarations wrong.\nexpected=%+v\ngot=%+v", expected, changes)
+	}
+}
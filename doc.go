@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/doc"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+// docCmd renders the documentation of the bindings a script defines
+func docCmd(args []string) int {
+	fs := flag.NewFlagSet("doc", flag.ContinueOnError)
+	format := fs.String("format", "md", "output format, either md or html")
+	output := fs.String("o", "", "write the documentation to `file` instead of stdout")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "doc expects a single script to document")
+		return 2
+	}
+
+	var write func(io.Writer, string, []doc.Entry) error
+	switch *format {
+	case "md":
+		write = doc.WriteMarkdown
+	case "html":
+		write = doc.WriteHTML
+	default:
+		fmt.Fprintf(os.Stderr, "unknown doc format %q, expected md or html\n", *format)
+		return 2
+	}
+
+	filename := fs.Arg(0)
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.Parse()
+	if len(p.Errs()) != 0 {
+		printParserErrors(filename, string(src), p.Errs())
+		return 1
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	title := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	if err := write(out, title, doc.Extract(program)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,116 @@
+// Package doc extracts the documentation of the bindings a script defines and
+// renders it as Markdown or HTML.
+package doc
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+)
+
+// Entry documents a single top-level binding
+type Entry struct {
+	Name string
+	// Declaration summarizes the binding, like let add = fn(a: int, b: int) -> int
+	Declaration string
+	Doc         string
+}
+
+// Extract gathers the top-level bindings of program that are either documented
+// with /// comments or bound to functions, in source order
+func Extract(program *ast.Program) []Entry {
+	entries := []Entry{}
+
+	for _, s := range program.Statements {
+		ls, isLet := s.(*ast.LetStatement)
+		if !isLet || len(ls.Names) > 1 {
+			continue
+		}
+
+		fl, isFn := ls.Value.(*ast.FunctionLiteral)
+		if ls.Doc == "" && !isFn {
+			continue
+		}
+
+		decl := "let " + ls.Name.String() + " = "
+		if isFn {
+			decl += signature(fl)
+		} else if ls.Value != nil {
+			decl += ls.Value.String()
+		}
+
+		entries = append(entries, Entry{Name: ls.Name.Value, Declaration: decl, Doc: ls.Doc})
+	}
+
+	return entries
+}
+
+// signature is a function literal without its body
+func signature(fl *ast.FunctionLiteral) string {
+	params := []string{}
+	for _, p := range fl.Params {
+		params = append(params, p.String())
+	}
+	if fl.Variadic {
+		params[len(params)-1] += "..."
+	}
+
+	sig := "fn(" + strings.Join(params, ", ") + ")"
+	if fl.ReturnType != nil {
+		sig += " -> " + fl.ReturnType.String()
+	}
+	return sig
+}
+
+// WriteMarkdown writes entries to w as a Markdown document called title
+func WriteMarkdown(w io.Writer, title string, entries []Entry) error {
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "# %s\n", title)
+	for _, e := range entries {
+		fmt.Fprintf(&out, "\n## %s\n\n```\n%s\n```\n", e.Name, e.Declaration)
+		if e.Doc != "" {
+			fmt.Fprintf(&out, "\n%s\n", e.Doc)
+		}
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// WriteHTML writes entries to w as a standalone HTML page called title
+func WriteHTML(w io.Writer, title string, entries []Entry) error {
+	var out strings.Builder
+
+	title = html.EscapeString(title)
+	fmt.Fprintf(&out, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n", title)
+	fmt.Fprintf(&out, "<h1>%s</h1>\n", title)
+
+	for _, e := range entries {
+		name := html.EscapeString(e.Name)
+		fmt.Fprintf(&out, "<h2 id=\"%s\">%s</h2>\n", name, name)
+		fmt.Fprintf(&out, "<pre><code>%s</code></pre>\n", html.EscapeString(e.Declaration))
+		for _, paragraph := range paragraphs(e.Doc) {
+			fmt.Fprintf(&out, "<p>%s</p>\n", html.EscapeString(paragraph))
+		}
+	}
+
+	out.WriteString("</body>\n</html>\n")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// paragraphs splits doc on blank lines
+func paragraphs(doc string) []string {
+	result := []string{}
+	for _, p := range strings.Split(doc, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package doc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+const input = `/// Adds two numbers.
+///
+/// Both must be <ints>.
+let add = fn(a: int, b: int) -> int { a + b };
+let sum = fn(rest...) { rest };
+/// The answer.
+let answer = 42;
+let hidden = 1;
+let x, y = f();
+`
+
+func TestExtract(t *testing.T) {
+	p := parser.New(lexer.New(input))
+	program := p.Parse()
+	if len(p.Errs()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errs())
+	}
+
+	expected := []Entry{
+		{Name: "add", Declaration: "let add = fn(a: int, b: int) -> int", Doc: "Adds two numbers.\n\nBoth must be <ints>."},
+		{Name: "sum", Declaration: "let sum = fn(rest...)"},
+		{Name: "answer", Declaration: "let answer = 42", Doc: "The answer."},
+	}
+
+	if entries := Extract(program); !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Extract wrong.\nexpected=%+v\ngot=%+v", expected, entries)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	entries := []Entry{
+		{Name: "add", Declaration: "let add = fn(a, b)", Doc: "Adds two numbers."},
+		{Name: "answer", Declaration: "let answer = 42"},
+	}
+
+	expected := "# math\n" +
+		"\n## add\n\n```\nlet add = fn(a, b)\n```\n\nAdds two numbers.\n" +
+		"\n## answer\n\n```\nlet answer = 42\n```\n"
+
+	var out bytes.Buffer
+	if err := WriteMarkdown(&out, "math", entries); err != nil {
+		t.Fatalf("WriteMarkdown returned error: %s", err)
+	}
+	if out.String() != expected {
+		t.Errorf("WriteMarkdown wrong.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	entries := []Entry{
+		{Name: "add", Declaration: "let add = fn(a: int) -> int", Doc: "Adds <one>.\n\nSecond paragraph."},
+	}
+
+	var out bytes.Buffer
+	if err := WriteHTML(&out, "math", entries); err != nil {
+		t.Fatalf("WriteHTML returned error: %s", err)
+	}
+
+	for _, want := range []string{
+		"<title>math</title>",
+		`<h2 id="add">add</h2>`,
+		"<pre><code>let add = fn(a: int) -&gt; int</code></pre>",
+		"<p>Adds &lt;one&gt;.</p>\n<p>Second paragraph.</p>",
+	} {
+		if !bytes.Contains(out.Bytes(), []byte(want)) {
+			t.Errorf("WriteHTML output is missing %q. got=%q", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/evaluator"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+// evalCmd evaluates code given on the command line and prints its result, like
+// python -c
+func evalCmd(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "-e expects code to evaluate")
+		return 2
+	}
+
+	src := strings.Join(args, " ")
+	p := parser.New(lexer.New(src))
+	program := p.Parse()
+	if len(p.Errs()) != 0 {
+		printParserErrors("-e", src, p.Errs())
+		return 1
+	}
+	program, expandErr := evaluator.ExpandMacros(program, object.NewEnvironment())
+	if expandErr != nil {
+		printRuntimeError("-e", src, expandErr)
+		return 1
+	}
+
+	in := evaluator.New()
+	defer in.Close()
+
+	evaluated := in.Eval(program, object.NewEnvironment())
+	if evaluated == nil || evaluated.Type() == object.NULL {
+		return 0
+	}
+	switch evaluated := evaluated.(type) {
+	case *object.Error:
+		printRuntimeError("-e", src, evaluated)
+		return 1
+	case *object.Exit:
+		return int(evaluated.Code)
+	}
+
+	fmt.Println(evaluated.Inspect())
+
+	return 0
+}
@@ -1,47 +1,292 @@
 package evaluator
 
-import "github.com/nayyara-airlangga/basedlang/object"
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
 
 const (
 	ErrInvalidLen                  = "invalid argument: %s (%s) not supported for len"
 	ErrNotEnoughArgsAppend         = "invalid argument: not enough arguments for append, expected>=1, got=0"
 	ErrFirstArgShouldBeArrayAppend = "invalid argument: first argument for append must be an array. got=%s (%s)"
+	ErrNotEnoughArgsSpawn          = "invalid argument: not enough arguments for spawn, expected>=1, got=0"
+	ErrFirstArgShouldBeFnSpawn     = "invalid argument: first argument for spawn must be a function. got=%s (%s)"
+	ErrArgShouldBeTask             = "invalid argument: %s expects a task. got=%s (%s)"
+	ErrArgShouldBeArrayOfTasks     = "invalid argument: join expects an array of tasks. got=%s (%s)"
+	ErrInvalidChanSize             = "invalid argument: chan size must be a non-negative integer. got=%s (%s)"
+	ErrArgShouldBeChannel          = "invalid argument: %s expects a channel. got=%s (%s)"
+	ErrArgShouldBeArrayOfChannels  = "invalid argument: select expects an array of channels. got=%s (%s)"
+	ErrArgShouldBeString           = "invalid argument: %s expects a string %s. got=%s (%s)"
+	ErrArgShouldBeInteger          = "invalid argument: %s expects an integer %s. got=%s (%s)"
+	ErrArgShouldBeArray            = "invalid argument: %s expects an array. got=%s (%s)"
+	ErrArgShouldBeHash             = "invalid argument: %s expects a hash of %s. got=%s (%s)"
+	ErrArgShouldBeFn               = "invalid argument: %s expects a function %s. got=%s (%s)"
+	ErrArgShouldBeInterface        = "invalid argument: implements expects an interface. got=%s (%s)"
+	ErrSendOnClosedChannel         = "send on closed channel"
+	ErrCloseOfClosedChannel        = "close of closed channel"
+	ErrIO                          = "io error: %s"
 )
 
-var builtins map[string]*object.Builtin = map[string]*object.Builtin{
-	"len": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError(ErrWrongNumberOfArgs, len(args), 1)
-			}
+// Builtins that evaluate basedlang functions are registered on init, since
+// referring to applyFunction in the builtins literal is an initialization cycle
+func init() {
+	builtins["spawn"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) < 1 {
+			return newError(object.ArgumentError, ErrNotEnoughArgsSpawn)
+		}
 
-			switch arg := args[0].(type) {
-			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
-			case *object.Array:
-				return &object.Integer{Value: int64(len(arg.Elems))}
-			default:
-				return newError(ErrInvalidLen, arg.Inspect(), arg.Type())
-			}
-		},
+		switch fn := args[0].(type) {
+		case *object.Function, *object.Builtin:
+			fnArgs := args[1:]
+			return object.NewTask(func() object.Object {
+				return in.applyFunction(fn, fnArgs)
+			})
+		default:
+			return newError(object.TypeError, ErrFirstArgShouldBeFnSpawn, fn.Inspect(), fn.Type())
+		}
+	}
+}
+
+type builtinFn func(in *Interpreter, args ...object.Object) object.Object
+
+var builtins map[string]builtinFn = map[string]builtinFn{
+	"len": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		switch arg := args[0].(type) {
+		case *object.String:
+			return newInteger(int64(len(arg.Value)))
+		case *object.Array:
+			return newInteger(int64(arg.Len()))
+		case *object.Hash:
+			return newInteger(int64(arg.Len()))
+		case *object.StringBuilder:
+			return newInteger(int64(arg.Len()))
+		default:
+			return newError(object.TypeError, ErrInvalidLen, arg.Inspect(), arg.Type())
+		}
+	},
+	"append": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) < 1 {
+			return newError(object.ArgumentError, ErrNotEnoughArgsAppend)
+		}
+
+		arr, isArr := args[0].(*object.Array)
+		if !isArr {
+			return newError(object.TypeError, ErrFirstArgShouldBeArrayAppend, args[0].Inspect(), args[0].Type())
+		}
+		if len(args) == 1 {
+			return arr
+		}
+
+		// Copied so arrays appended to the same one never share elements, which
+		// index assignment would make visible
+		newArr := &object.Array{Elems: append(arr.Elements(), args[1:]...)}
+
+		return in.track(newArr)
 	},
-	"append": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) < 1 {
-				return newError(ErrNotEnoughArgsAppend)
+	// implements reports whether a value provides every method of an interface
+	"implements": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 2)
+		}
+
+		iface, isIface := args[1].(*object.Interface)
+		if !isIface {
+			return newError(object.TypeError, ErrArgShouldBeInterface, args[1].Inspect(), args[1].Type())
+		}
+
+		return nativeBoolToObjBool(iface.ImplementedBy(args[0]))
+	},
+	// typeof returns the type of a value, to be compared with types like Int
+	"typeof": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		return object.TypeOf(args[0])
+	},
+	"print": func(in *Interpreter, args ...object.Object) object.Object {
+		strs := make([]string, len(args))
+		for i, arg := range args {
+			strs[i] = arg.Inspect()
+		}
+
+		fmt.Fprintln(in.stdout, strings.Join(strs, " "))
+
+		return NULL
+	},
+	"input": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) > 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+		if len(args) == 1 {
+			prompt, isStr := args[0].(*object.String)
+			if !isStr {
+				return newError(object.TypeError, ErrArgShouldBeString, "input", "prompt", args[0].Inspect(), args[0].Type())
 			}
+			fmt.Fprint(in.stdout, prompt.Value)
+		}
+
+		line, err := in.readLine()
+		if err == io.EOF {
+			return NULL
+		}
+		if err != nil {
+			return wrapError(object.IOError, ErrIO, err)
+		}
+
+		return in.track(&object.String{Value: line})
+	},
+	"chan": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) > 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+		if len(args) == 0 {
+			return object.NewChannel(0)
+		}
+
+		size, isInt := args[0].(*object.Integer)
+		if !isInt || size.Value < 0 {
+			return newError(object.ValueError, ErrInvalidChanSize, args[0].Inspect(), args[0].Type())
+		}
 
-			arr, isArr := args[0].(*object.Array)
-			if !isArr {
-				return newError(ErrFirstArgShouldBeArrayAppend, args[0].Inspect(), args[0].Type())
+		return object.NewChannel(int(size.Value))
+	},
+	"send": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 2)
+		}
+
+		ch, isChan := args[0].(*object.Channel)
+		if !isChan {
+			return newError(object.TypeError, ErrArgShouldBeChannel, "send", args[0].Inspect(), args[0].Type())
+		}
+		if !ch.Send(args[1]) {
+			return newError(object.ValueError, ErrSendOnClosedChannel)
+		}
+
+		return NULL
+	},
+	"recv": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		ch, isChan := args[0].(*object.Channel)
+		if !isChan {
+			return newError(object.TypeError, ErrArgShouldBeChannel, "recv", args[0].Inspect(), args[0].Type())
+		}
+
+		val, ok := ch.Recv()
+		if !ok {
+			return NULL
+		}
+
+		return val
+	},
+	"close": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		ch, isChan := args[0].(*object.Channel)
+		if !isChan {
+			return newError(object.TypeError, ErrArgShouldBeChannel, "close", args[0].Inspect(), args[0].Type())
+		}
+		if !ch.Close() {
+			return newError(object.ValueError, ErrCloseOfClosedChannel)
+		}
+
+		return NULL
+	},
+	// select receives from whichever channel is ready first and returns the
+	// channel's index along with the value, to be unpacked with let i, v = ...
+	"select": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		arr, isArr := args[0].(*object.Array)
+		if !isArr {
+			return newError(object.TypeError, ErrArgShouldBeArrayOfChannels, args[0].Inspect(), args[0].Type())
+		}
+
+		cases := make([]reflect.SelectCase, arr.Len())
+		for i, e := range arr.Elements() {
+			ch, isChan := e.(*object.Channel)
+			if !isChan {
+				return newError(object.TypeError, ErrArgShouldBeArrayOfChannels, e.Inspect(), e.Type())
 			}
-			if len(args) == 1 {
-				return arr
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.Chan())}
+		}
+
+		chosen, val, ok := reflect.Select(cases)
+
+		var received object.Object = NULL
+		if ok {
+			received = val.Interface().(object.Object)
+		}
+
+		return &object.Tuple{Elems: []object.Object{newInteger(int64(chosen)), received}}
+	},
+	"await": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		task, isTask := args[0].(*object.Task)
+		if !isTask {
+			return newError(object.TypeError, ErrArgShouldBeTask, "await", args[0].Inspect(), args[0].Type())
+		}
+
+		return task.Await()
+	},
+	"join": func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		arr, isArr := args[0].(*object.Array)
+		if !isArr {
+			return newError(object.TypeError, ErrArgShouldBeArrayOfTasks, args[0].Inspect(), args[0].Type())
+		}
+
+		results := make([]object.Object, arr.Len())
+		for i, e := range arr.Elements() {
+			task, isTask := e.(*object.Task)
+			if !isTask {
+				return newError(object.TypeError, ErrArgShouldBeArrayOfTasks, e.Inspect(), e.Type())
 			}
+			results[i] = task.Await()
+		}
 
-			newArr := &object.Array{Elems: append(arr.Elems, args[1:]...)}
+		// Surface the first failure, like evaluating an array literal does
+		for _, res := range results {
+			if isError(res) {
+				return res
+			}
+		}
 
-			return newArr
-		},
+		return in.track(&object.Array{Elems: results})
 	},
 }
+
+// BuiltinNames lists the names of every builtin function and type, in no
+// particular order
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins)+len(object.Types))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range object.Types {
+		names = append(names, name)
+	}
+	return names
+}
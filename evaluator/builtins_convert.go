@@ -0,0 +1,100 @@
+package evaluator
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrCannotParse   = "invalid argument: %s can't parse %q as %s"
+	ErrCannotConvert = "invalid argument: %s can't convert %s (%s)"
+)
+
+// Conversions accept the types their result can be made from without losing
+// meaning, failing with a ValueError for strings that don't parse
+
+func init() {
+	builtins["int"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		switch arg := args[0].(type) {
+		case *object.Integer, *object.Float:
+			// Floats are truncated towards zero
+			return roundToInteger("int", args, math.Trunc)
+		case *object.Boolean:
+			if arg.Value {
+				return newInteger(1)
+			}
+			return newInteger(0)
+		case *object.String:
+			i, err := strconv.ParseInt(arg.Value, 10, 64)
+			if err != nil {
+				return newError(object.ValueError, ErrCannotParse, "int", arg.Value, "an integer")
+			}
+			return newInteger(i)
+		default:
+			return newError(object.TypeError, ErrCannotConvert, "int", arg.Inspect(), arg.Type())
+		}
+	}
+	builtins["float"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		switch arg := args[0].(type) {
+		case *object.Float:
+			return arg
+		case *object.Integer:
+			return &object.Float{Value: float64(arg.Value)}
+		case *object.Boolean:
+			if arg.Value {
+				return &object.Float{Value: 1}
+			}
+			return &object.Float{Value: 0}
+		case *object.String:
+			f, err := strconv.ParseFloat(arg.Value, 64)
+			// Out of range strings parse to an infinity along with the error
+			if err != nil && !math.IsInf(f, 0) {
+				return newError(object.ValueError, ErrCannotParse, "float", arg.Value, "a float")
+			}
+			return &object.Float{Value: f}
+		default:
+			return newError(object.TypeError, ErrCannotConvert, "float", arg.Inspect(), arg.Type())
+		}
+	}
+	// str returns what print would show for a value
+	builtins["str"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		if str, isStr := args[0].(*object.String); isStr {
+			return str
+		}
+		return in.track(&object.String{Value: args[0].Inspect()})
+	}
+	// bool parses the strings "true" and "false", and otherwise returns whether
+	// a value is truthy, which every value but false and null is
+	builtins["bool"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		str, isStr := args[0].(*object.String)
+		if !isStr {
+			return nativeBoolToObjBool(isTruthy(args[0]))
+		}
+		switch str.Value {
+		case "true":
+			return TRUE
+		case "false":
+			return FALSE
+		default:
+			return newError(object.ValueError, ErrCannotParse, "bool", str.Value, "a boolean")
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package evaluator
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrExec = "exec error: %s"
+)
+
+func init() {
+	builtins["exec"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) < 1 {
+			return newError(object.ArgumentError, ErrNotEnoughArgs, len(args), 1)
+		}
+
+		strs := make([]string, len(args))
+		for i, arg := range args {
+			str, isStr := arg.(*object.String)
+			if !isStr {
+				return newError(object.TypeError, ErrArgShouldBeString, "exec", "command", arg.Inspect(), arg.Type())
+			}
+			strs[i] = str.Value
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(strs[0], strs[1:]...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		// A command that ran and failed is reported through its exit code, only
+		// commands that couldn't run at all are errors
+		code := 0
+		if err := cmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return wrapError(object.IOError, ErrExec, err)
+			}
+			code = exitErr.ExitCode()
+		}
+
+		stdoutObj := in.track(&object.String{Value: stdout.String()})
+		if isError(stdoutObj) {
+			return stdoutObj
+		}
+		stderrObj := in.track(&object.String{Value: stderr.String()})
+		if isError(stderrObj) {
+			return stderrObj
+		}
+
+		result := object.NewHash()
+		result.Set(object.InternString("stdout"), stdoutObj)
+		result.Set(object.InternString("stderr"), stderrObj)
+		result.Set(object.InternString("code"), newInteger(int64(code)))
+
+		return in.track(result)
+	}
+}
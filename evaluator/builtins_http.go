@@ -0,0 +1,205 @@
+package evaluator
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrHTTP            = "http error: %s"
+	ErrInvalidResponse = "invalid response: handler must return a hash. got=%s (%s)"
+)
+
+func init() {
+	builtins["http_get"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+		return in.httpRequest("http_get", object.InternString(http.MethodGet), args[0], nil, nil)
+	}
+	builtins["http_request"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) < 2 {
+			return newError(object.ArgumentError, ErrNotEnoughArgs, len(args), 2)
+		}
+		if len(args) > 4 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 4)
+		}
+
+		var headers, body object.Object
+		if len(args) > 2 {
+			headers = args[2]
+		}
+		if len(args) > 3 {
+			body = args[3]
+		}
+
+		return in.httpRequest("http_request", args[0], args[1], headers, body)
+	}
+	builtins["serve"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 2)
+		}
+
+		addr, isStr := args[0].(*object.String)
+		if !isStr {
+			return newError(object.TypeError, ErrArgShouldBeString, "serve", "address", args[0].Inspect(), args[0].Type())
+		}
+		switch args[1].(type) {
+		case *object.Function, *object.Builtin:
+		default:
+			return newError(object.TypeError, ErrArgShouldBeFn, "serve", "handler", args[1].Inspect(), args[1].Type())
+		}
+
+		// Serving only stops when the listener fails
+		if err := http.ListenAndServe(addr.Value, in.httpHandler(args[1])); err != nil {
+			return wrapError(object.IOError, ErrHTTP, err)
+		}
+		return NULL
+	}
+}
+
+// httpHandler serves requests by calling handler with a hash of the request's
+// method, path, query, headers and body. The handler returns a hash of the
+// response's status, headers and body, all of which are optional.
+func (in *Interpreter) httpHandler(handler object.Object) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		query := object.NewHash()
+		for _, name := range sortedKeys(r.URL.Query()) {
+			query.Set(&object.String{Value: name}, &object.String{Value: r.URL.Query().Get(name)})
+		}
+
+		request := object.NewHash()
+		request.Set(object.InternString("method"), &object.String{Value: r.Method})
+		request.Set(object.InternString("path"), &object.String{Value: r.URL.Path})
+		request.Set(object.InternString("query"), query)
+		request.Set(object.InternString("headers"), headerHash(r.Header))
+		request.Set(object.InternString("body"), &object.String{Value: string(body)})
+
+		result := in.applyFunction(handler, []object.Object{request})
+		if isError(result) {
+			http.Error(w, result.Inspect(), http.StatusInternalServerError)
+			return
+		}
+
+		response, isHash := result.(*object.Hash)
+		if !isHash {
+			msg := newError(object.TypeError, ErrInvalidResponse, result.Inspect(), result.Type()).Inspect()
+			http.Error(w, msg, http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, response)
+	})
+}
+
+func writeResponse(w http.ResponseWriter, response *object.Hash) {
+	if headers, ok := response.Get(object.InternString("headers")); ok {
+		if hash, isHash := headers.(*object.Hash); isHash {
+			hash.Each(func(key, value object.Object) {
+				w.Header().Add(key.Inspect(), value.Inspect())
+			})
+		}
+	}
+
+	status := http.StatusOK
+	if code, ok := response.Get(object.InternString("status")); ok {
+		if code, isInt := code.(*object.Integer); isInt {
+			status = int(code.Value)
+		}
+	}
+	w.WriteHeader(status)
+
+	if body, ok := response.Get(object.InternString("body")); ok && body != NULL {
+		io.WriteString(w, body.Inspect())
+	}
+}
+
+// httpRequest sends a request on behalf of the builtin called name and returns
+// the response as a hash of its status, headers and body. Headers and body are
+// optional.
+func (in *Interpreter) httpRequest(name string, method, url, headers, body object.Object) object.Object {
+	methodStr, isStr := method.(*object.String)
+	if !isStr {
+		return newError(object.TypeError, ErrArgShouldBeString, name, "method", method.Inspect(), method.Type())
+	}
+	urlStr, isStr := url.(*object.String)
+	if !isStr {
+		return newError(object.TypeError, ErrArgShouldBeString, name, "url", url.Inspect(), url.Type())
+	}
+
+	var reqBody io.Reader
+	if body != nil && body != NULL {
+		bodyStr, isStr := body.(*object.String)
+		if !isStr {
+			return newError(object.TypeError, ErrArgShouldBeString, name, "body", body.Inspect(), body.Type())
+		}
+		reqBody = strings.NewReader(bodyStr.Value)
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(methodStr.Value), urlStr.Value, reqBody)
+	if err != nil {
+		return wrapError(object.IOError, ErrHTTP, err)
+	}
+
+	if headers != nil && headers != NULL {
+		hash, isHash := headers.(*object.Hash)
+		if !isHash {
+			return newError(object.TypeError, ErrArgShouldBeHash, name, "headers", headers.Inspect(), headers.Type())
+		}
+		hash.Each(func(key, value object.Object) {
+			req.Header.Add(key.Inspect(), value.Inspect())
+		})
+	}
+
+	resp, err := in.httpClient.Do(req)
+	if err != nil {
+		return wrapError(object.IOError, ErrHTTP, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return wrapError(object.IOError, ErrHTTP, err)
+	}
+
+	bodyObj := in.track(&object.String{Value: string(respBody)})
+	if isError(bodyObj) {
+		return bodyObj
+	}
+
+	response := object.NewHash()
+	response.Set(object.InternString("status"), newInteger(int64(resp.StatusCode)))
+	response.Set(object.InternString("headers"), headerHash(resp.Header))
+	response.Set(object.InternString("body"), bodyObj)
+
+	return in.track(response)
+}
+
+// headerHash converts HTTP headers to a hash sorted by name, joining the values
+// of repeated headers with commas
+func headerHash(header http.Header) *object.Hash {
+	hash := object.NewHash()
+	for _, name := range sortedKeys(header) {
+		hash.Set(&object.String{Value: name}, &object.String{Value: strings.Join(header[name], ", ")})
+	}
+	return hash
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,217 @@
+package evaluator
+
+import (
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrArgShouldBeIterable = "invalid argument: %s expects an iterable %s. got=%s (%s)"
+	ErrInvalidCount        = "invalid argument: %s expects a non-negative count. got=%s (%s)"
+	ErrZeroStep            = "invalid argument: range step must not be zero"
+)
+
+// Sequence builtins return iterators computing their values on demand, so
+// they can be chained over sequences too long to ever hold in memory. Errors
+// raised while computing a value are produced in its place.
+
+func init() {
+	// range(stop), range(start, stop) or range(start, stop, step) counts from
+	// start, or 0, up to but excluding stop
+	builtins["range"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) < 1 {
+			return newError(object.ArgumentError, ErrNotEnoughArgs, len(args), 1)
+		}
+		if len(args) > 3 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 3)
+		}
+
+		bounds := []int64{0, 0, 1}
+		names := []string{"start", "stop", "step"}
+		if len(args) == 1 {
+			names = names[1:2]
+		}
+		for i, arg := range args {
+			n, isInt := arg.(*object.Integer)
+			if !isInt {
+				return newError(object.TypeError, ErrArgShouldBeInteger, "range", names[i], arg.Inspect(), arg.Type())
+			}
+			if len(args) == 1 {
+				bounds[1] = n.Value
+			} else {
+				bounds[i] = n.Value
+			}
+		}
+
+		i, stop, step := bounds[0], bounds[1], bounds[2]
+		if step == 0 {
+			return newError(object.ValueError, ErrZeroStep)
+		}
+
+		return object.NewLazy(func() (object.Object, bool) {
+			if step > 0 && i >= stop || step < 0 && i <= stop {
+				return nil, false
+			}
+			val := newInteger(i)
+			i += step
+			return val, true
+		})
+	}
+	builtins["take"] = func(in *Interpreter, args ...object.Object) object.Object {
+		n, next, err := in.countAndIterable("take", args)
+		if err != nil {
+			return err
+		}
+
+		return object.NewLazy(func() (object.Object, bool) {
+			if n <= 0 {
+				return nil, false
+			}
+			n--
+			return next()
+		})
+	}
+	builtins["drop"] = func(in *Interpreter, args ...object.Object) object.Object {
+		n, next, err := in.countAndIterable("drop", args)
+		if err != nil {
+			return err
+		}
+
+		return object.NewLazy(func() (object.Object, bool) {
+			for ; n > 0; n-- {
+				if val, ok := next(); !ok || isError(val) {
+					return val, ok
+				}
+			}
+			return next()
+		})
+	}
+	// zip produces tuples of the values of every iterable in turn, until the
+	// shortest one runs out
+	builtins["zip"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) < 1 {
+			return newError(object.ArgumentError, ErrNotEnoughArgs, len(args), 1)
+		}
+
+		nexts := make([]func() (object.Object, bool), len(args))
+		for i, arg := range args {
+			if nexts[i] = in.iterate(arg); nexts[i] == nil {
+				return newError(object.TypeError, ErrArgShouldBeIterable, "zip", "argument", arg.Inspect(), arg.Type())
+			}
+		}
+
+		return object.NewLazy(func() (object.Object, bool) {
+			elems := make([]object.Object, len(nexts))
+			for i, next := range nexts {
+				val, ok := next()
+				if !ok {
+					return nil, false
+				}
+				if isError(val) {
+					return val, true
+				}
+				elems[i] = val
+			}
+			return in.track(&object.Tuple{Elems: elems}), true
+		})
+	}
+	// iterate(f, x) produces x, f(x), f(f(x)) and so on forever
+	builtins["iterate"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 2)
+		}
+		fn, err := callableArg("iterate", args[0])
+		if err != nil {
+			return err
+		}
+
+		var val object.Object
+		return object.NewLazy(func() (object.Object, bool) {
+			switch {
+			case val == nil:
+				val = args[1]
+			case isError(val):
+				return nil, false
+			default:
+				val = in.applyFunction(fn, []object.Object{val})
+			}
+			return val, true
+		})
+	}
+	// map(f, xs) produces f(x) for every x of xs
+	builtins["map"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 2)
+		}
+		fn, err := callableArg("map", args[0])
+		if err != nil {
+			return err
+		}
+		next := in.iterate(args[1])
+		if next == nil {
+			return newError(object.TypeError, ErrArgShouldBeIterable, "map", "to map over", args[1].Inspect(), args[1].Type())
+		}
+
+		return object.NewLazy(func() (object.Object, bool) {
+			val, ok := next()
+			if !ok || isError(val) {
+				return val, ok
+			}
+			return in.applyFunction(fn, []object.Object{val}), true
+		})
+	}
+	// collect gathers every value of an iterable into an array
+	builtins["collect"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+		next := in.iterate(args[0])
+		if next == nil {
+			return newError(object.TypeError, ErrArgShouldBeIterable, "collect", "argument", args[0].Inspect(), args[0].Type())
+		}
+
+		elems := []object.Object{}
+		for {
+			// Iterators may never end, so collecting is metered like evaluating
+			if err := in.consumeFuel(); err != nil {
+				return err
+			}
+
+			val, ok := next()
+			if !ok {
+				return in.track(&object.Array{Elems: elems})
+			}
+			if isError(val) {
+				return val
+			}
+			elems = append(elems, val)
+		}
+	}
+}
+
+// countAndIterable checks the arguments of take and drop, which are a count and
+// what to take from or drop from
+func (in *Interpreter) countAndIterable(name string, args []object.Object) (int64, func() (object.Object, bool), *object.Error) {
+	if len(args) != 2 {
+		return 0, nil, newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 2)
+	}
+
+	n, isInt := args[0].(*object.Integer)
+	if !isInt || n.Value < 0 {
+		return 0, nil, newError(object.ValueError, ErrInvalidCount, name, args[0].Inspect(), args[0].Type())
+	}
+	next := in.iterate(args[1])
+	if next == nil {
+		return 0, nil, newError(object.TypeError, ErrArgShouldBeIterable, name, "to "+name+" from", args[1].Inspect(), args[1].Type())
+	}
+
+	return n.Value, next, nil
+}
+
+func callableArg(name string, arg object.Object) (object.Object, *object.Error) {
+	switch arg.(type) {
+	case *object.Function, *object.Builtin:
+		return arg, nil
+	default:
+		return nil, newError(object.TypeError, ErrArgShouldBeFn, name, "to call", arg.Inspect(), arg.Type())
+	}
+}
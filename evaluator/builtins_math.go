@@ -0,0 +1,160 @@
+package evaluator
+
+import (
+	"math"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrArgShouldBeNumber = "invalid argument: %s expects a number %s. got=%s (%s)"
+	ErrNotAnInteger      = "invalid argument: %s can't convert %s to an integer"
+	ErrPowOverflow       = "integer overflow: pow(%d, %d)"
+)
+
+// Math builtins keep integers as integers whenever the result is exact, and
+// promote to floats as soon as a float is involved, like arithmetic does
+
+func init() {
+	builtins["abs"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		switch arg := args[0].(type) {
+		case *object.Integer:
+			if arg.Value < 0 {
+				return newInteger(-arg.Value)
+			}
+			return arg
+		case *object.Float:
+			return &object.Float{Value: math.Abs(arg.Value)}
+		default:
+			return newError(object.TypeError, ErrArgShouldBeNumber, "abs", "argument", arg.Inspect(), arg.Type())
+		}
+	}
+	builtins["min"] = func(in *Interpreter, args ...object.Object) object.Object {
+		return extremum("min", args, func(a, b float64) bool { return a < b })
+	}
+	builtins["max"] = func(in *Interpreter, args ...object.Object) object.Object {
+		return extremum("max", args, func(a, b float64) bool { return a > b })
+	}
+	builtins["sqrt"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+		if !isNumber(args[0]) {
+			return newError(object.TypeError, ErrArgShouldBeNumber, "sqrt", "argument", args[0].Inspect(), args[0].Type())
+		}
+		return &object.Float{Value: math.Sqrt(toFloat(args[0]))}
+	}
+	builtins["pow"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 2)
+		}
+		if !isNumber(args[0]) {
+			return newError(object.TypeError, ErrArgShouldBeNumber, "pow", "base", args[0].Inspect(), args[0].Type())
+		}
+		if !isNumber(args[1]) {
+			return newError(object.TypeError, ErrArgShouldBeNumber, "pow", "exponent", args[1].Inspect(), args[1].Type())
+		}
+
+		// Negative integer exponents give fractions, which only floats can hold
+		base, baseIsInt := args[0].(*object.Integer)
+		exp, expIsInt := args[1].(*object.Integer)
+		if baseIsInt && expIsInt && exp.Value >= 0 {
+			res, ok := intPow(base.Value, exp.Value)
+			if !ok && in.strict {
+				return newError(object.OverflowError, ErrPowOverflow, base.Value, exp.Value)
+			}
+			return newInteger(res)
+		}
+		return &object.Float{Value: math.Pow(toFloat(args[0]), toFloat(args[1]))}
+	}
+	builtins["floor"] = func(in *Interpreter, args ...object.Object) object.Object {
+		return roundToInteger("floor", args, math.Floor)
+	}
+	builtins["ceil"] = func(in *Interpreter, args ...object.Object) object.Object {
+		return roundToInteger("ceil", args, math.Ceil)
+	}
+	builtins["round"] = func(in *Interpreter, args ...object.Object) object.Object {
+		return roundToInteger("round", args, math.Round)
+	}
+}
+
+// extremum returns the argument that beats all others according to better. The
+// result is a float if any of the arguments is.
+func extremum(name string, args []object.Object, better func(a, b float64) bool) object.Object {
+	if len(args) < 1 {
+		return newError(object.ArgumentError, ErrNotEnoughArgs, len(args), 1)
+	}
+
+	// A single array argument stands for its elements
+	if arr, isArr := args[0].(*object.Array); isArr && len(args) == 1 {
+		if arr.Len() == 0 {
+			return newError(object.ArgumentError, ErrNotEnoughArgs, 0, 1)
+		}
+		args = arr.Elements()
+	}
+
+	best, promote := args[0], false
+	for _, arg := range args {
+		if !isNumber(arg) {
+			return newError(object.TypeError, ErrArgShouldBeNumber, name, "argument", arg.Inspect(), arg.Type())
+		}
+		if arg.Type() == object.FLOAT {
+			promote = true
+		}
+		if better(toFloat(arg), toFloat(best)) {
+			best = arg
+		}
+	}
+
+	if promote {
+		return &object.Float{Value: toFloat(best)}
+	}
+	return best
+}
+
+// roundToInteger rounds a float to an integer with round, passing integers
+// through untouched
+func roundToInteger(name string, args []object.Object, round func(float64) float64) object.Object {
+	if len(args) != 1 {
+		return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		return arg
+	case *object.Float:
+		rounded := round(arg.Value)
+		// Also rejects NaN, which fails every comparison
+		if !(rounded >= math.MinInt64 && rounded < math.MaxInt64) {
+			return newError(object.ValueError, ErrNotAnInteger, name, arg.Inspect())
+		}
+		return newInteger(int64(rounded))
+	default:
+		return newError(object.TypeError, ErrArgShouldBeNumber, name, "argument", arg.Inspect(), arg.Type())
+	}
+}
+
+// intPow raises base to a non-negative exp by squaring, wrapping around on
+// overflow like the rest of integer arithmetic. It reports whether the result
+// is exact.
+func intPow(base, exp int64) (int64, bool) {
+	result, exact := int64(1), true
+	for exp > 0 {
+		var ok bool
+		if exp&1 == 1 {
+			result, ok = arith("*", result, base)
+			exact = exact && ok
+		}
+		exp >>= 1
+		// Squaring past the last bit of exp doesn't affect the result
+		if exp > 0 {
+			base, ok = arith("*", base, base)
+			exact = exact && ok
+		}
+	}
+	return result, exact
+}
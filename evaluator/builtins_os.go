@@ -0,0 +1,86 @@
+package evaluator
+
+import (
+	"os"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrOS = "os error: %s"
+)
+
+func init() {
+	builtins["args"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 0)
+		}
+
+		elems := make([]object.Object, len(in.args))
+		for i, arg := range in.args {
+			elems[i] = &object.String{Value: arg}
+		}
+		return in.track(&object.Array{Elems: elems})
+	}
+	builtins["env"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		name, isStr := args[0].(*object.String)
+		if !isStr {
+			return newError(object.TypeError, ErrArgShouldBeString, "env", "name", args[0].Inspect(), args[0].Type())
+		}
+
+		value, isSet := os.LookupEnv(name.Value)
+		if !isSet {
+			return NULL
+		}
+		return in.track(&object.String{Value: value})
+	}
+	builtins["set_env"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 2)
+		}
+
+		name, isStr := args[0].(*object.String)
+		if !isStr {
+			return newError(object.TypeError, ErrArgShouldBeString, "set_env", "name", args[0].Inspect(), args[0].Type())
+		}
+		value, isStr := args[1].(*object.String)
+		if !isStr {
+			return newError(object.TypeError, ErrArgShouldBeString, "set_env", "value", args[1].Inspect(), args[1].Type())
+		}
+
+		if err := os.Setenv(name.Value, value.Value); err != nil {
+			return wrapError(object.IOError, ErrOS, err)
+		}
+		return NULL
+	}
+	builtins["exit"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) > 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		code := int64(0)
+		if len(args) == 1 {
+			arg, isInt := args[0].(*object.Integer)
+			if !isInt {
+				return newError(object.TypeError, ErrArgShouldBeInteger, "exit", "code", args[0].Inspect(), args[0].Type())
+			}
+			code = arg.Value
+		}
+		return &object.Exit{Code: code}
+	}
+	builtins["cwd"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 0)
+		}
+
+		dir, err := os.Getwd()
+		if err != nil {
+			return wrapError(object.IOError, ErrOS, err)
+		}
+		return in.track(&object.String{Value: dir})
+	}
+}
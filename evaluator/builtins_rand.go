@@ -0,0 +1,65 @@
+package evaluator
+
+import (
+	"math/rand"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrInvalidRandBound = "invalid argument: rand_int expects a positive integer bound. got=%s (%s)"
+)
+
+// Random builtins draw from the interpreter's generator, so WithSeed and
+// deterministic mode make them repeatable
+func init() {
+	builtins["rand_int"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		n, isInt := args[0].(*object.Integer)
+		if !isInt || n.Value <= 0 {
+			return newError(object.ValueError, ErrInvalidRandBound, args[0].Inspect(), args[0].Type())
+		}
+
+		var value int64
+		in.withRand(func(r *rand.Rand) {
+			value = r.Int63n(n.Value)
+		})
+
+		return newInteger(value)
+	}
+	builtins["rand_float"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 0)
+		}
+
+		var value float64
+		in.withRand(func(r *rand.Rand) {
+			value = r.Float64()
+		})
+
+		return &object.Float{Value: value}
+	}
+	builtins["shuffle"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		arr, isArr := args[0].(*object.Array)
+		if !isArr {
+			return newError(object.TypeError, ErrArgShouldBeArray, "shuffle", args[0].Inspect(), args[0].Type())
+		}
+
+		// Shuffle a copy, leaving the argument as it was
+		elems := arr.Elements()
+		in.withRand(func(r *rand.Rand) {
+			r.Shuffle(len(elems), func(i, j int) {
+				elems[i], elems[j] = elems[j], elems[i]
+			})
+		})
+
+		return in.track(&object.Array{Elems: elems})
+	}
+}
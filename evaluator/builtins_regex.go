@@ -0,0 +1,130 @@
+package evaluator
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrInvalidRegex = "invalid argument: %s"
+)
+
+// maxCachedRegexps bounds the compiled patterns kept around, since scripts may
+// build patterns dynamically
+const maxCachedRegexps = 256
+
+var regexCache = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{compiled: make(map[string]*regexp.Regexp)}
+
+func init() {
+	builtins["regex_match"] = func(in *Interpreter, args ...object.Object) object.Object {
+		re, strs, err := regexArgs("regex_match", args, "string")
+		if err != nil {
+			return err
+		}
+		return nativeBoolToObjBool(re.MatchString(strs[0]))
+	}
+	builtins["regex_find_all"] = func(in *Interpreter, args ...object.Object) object.Object {
+		re, strs, err := regexArgs("regex_find_all", args, "string")
+		if err != nil {
+			return err
+		}
+
+		matches := []object.Object{}
+		for _, loc := range re.FindAllStringSubmatchIndex(strs[0], -1) {
+			matches = append(matches, matchHash(re, strs[0], loc))
+		}
+
+		return in.track(&object.Array{Elems: matches})
+	}
+	builtins["regex_replace"] = func(in *Interpreter, args ...object.Object) object.Object {
+		re, strs, err := regexArgs("regex_replace", args, "string", "replacement")
+		if err != nil {
+			return err
+		}
+		return in.track(&object.String{Value: re.ReplaceAllString(strs[0], strs[1])})
+	}
+}
+
+// regexArgs checks the arguments of the regex builtin called name, which are a
+// pattern followed by strings named by names. It returns the compiled pattern
+// and the values of the strings.
+func regexArgs(name string, args []object.Object, names ...string) (*regexp.Regexp, []string, *object.Error) {
+	if len(args) != len(names)+1 {
+		return nil, nil, newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), len(names)+1)
+	}
+
+	pattern, isStr := args[0].(*object.String)
+	if !isStr {
+		return nil, nil, newError(object.TypeError, ErrArgShouldBeString, name, "pattern", args[0].Inspect(), args[0].Type())
+	}
+
+	strs := make([]string, len(names))
+	for i, argName := range names {
+		str, isStr := args[i+1].(*object.String)
+		if !isStr {
+			return nil, nil, newError(object.TypeError, ErrArgShouldBeString, name, argName, args[i+1].Inspect(), args[i+1].Type())
+		}
+		strs[i] = str.Value
+	}
+
+	re, err := compileRegex(pattern.Value)
+	if err != nil {
+		return nil, nil, wrapError(object.ValueError, ErrInvalidRegex, err)
+	}
+
+	return re, strs, nil
+}
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	regexCache.Lock()
+	defer regexCache.Unlock()
+
+	if re, ok := regexCache.compiled[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(regexCache.compiled) >= maxCachedRegexps {
+		regexCache.compiled = make(map[string]*regexp.Regexp)
+	}
+	regexCache.compiled[pattern] = re
+
+	return re, nil
+}
+
+// matchHash describes a match found at loc in s, as returned by
+// FindStringSubmatchIndex. Groups that did not participate in the match are
+// null.
+func matchHash(re *regexp.Regexp, s string, loc []int) *object.Hash {
+	groups := []object.Object{}
+	named := object.NewHash()
+
+	for i := 1; i < len(loc)/2; i++ {
+		var group object.Object = NULL
+		if loc[2*i] >= 0 {
+			group = &object.String{Value: s[loc[2*i]:loc[2*i+1]]}
+		}
+		groups = append(groups, group)
+
+		if name := re.SubexpNames()[i]; name != "" {
+			named.Set(&object.String{Value: name}, group)
+		}
+	}
+
+	match := object.NewHash()
+	match.Set(object.InternString("match"), &object.String{Value: s[loc[0]:loc[1]]})
+	match.Set(object.InternString("index"), newInteger(int64(loc[0])))
+	match.Set(object.InternString("groups"), &object.Array{Elems: groups})
+	match.Set(object.InternString("named"), named)
+
+	return match
+}
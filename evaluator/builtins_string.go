@@ -0,0 +1,53 @@
+package evaluator
+
+import (
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const ErrArgShouldBeBuilder = "invalid argument: %s expects a string builder. got=%s (%s)"
+
+// String builders are for building strings out of many parts, like in a loop,
+// where concatenating with + would copy the string built so far every time.
+// Parts are written like print writes them, so strings are written as is and
+// other values are inspected.
+
+func init() {
+	// builder(parts...) creates a string builder starting with parts
+	builtins["builder"] = func(in *Interpreter, args ...object.Object) object.Object {
+		sb := &object.StringBuilder{}
+		if err := in.writeParts(sb, args); err != nil {
+			return err
+		}
+		return in.track(sb)
+	}
+	// write(sb, parts...) appends parts to a string builder and returns it, so
+	// that the string can be read with str once it's built
+	builtins["write"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) < 1 {
+			return newError(object.ArgumentError, ErrNotEnoughArgs, len(args), 1)
+		}
+
+		sb, isBuilder := args[0].(*object.StringBuilder)
+		if !isBuilder {
+			return newError(object.TypeError, ErrArgShouldBeBuilder, "write", args[0].Inspect(), args[0].Type())
+		}
+		if err := in.writeParts(sb, args[1:]); err != nil {
+			return err
+		}
+		return sb
+	}
+}
+
+func (in *Interpreter) writeParts(sb *object.StringBuilder, parts []object.Object) object.Object {
+	for _, part := range parts {
+		s := part.Inspect()
+		if str, isStr := part.(*object.String); isStr {
+			s = str.Value
+		}
+		if err := in.allocate(int64(len(s))); err != nil {
+			return err
+		}
+		sb.WriteString(s)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package evaluator
+
+import (
+	"time"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrInvalidTime = "invalid argument: %s"
+)
+
+// Times are integers counting milliseconds since the Unix epoch and durations
+// are integers counting milliseconds, so they can be added and compared like
+// any other integer. Times are formatted and parsed in UTC with Go layouts,
+// defaulting to RFC 3339.
+
+func init() {
+	builtins["now"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 0)
+		}
+		return newInteger(in.now().UnixMilli())
+	}
+	builtins["sleep"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		ms, isInt := args[0].(*object.Integer)
+		if !isInt {
+			return newError(object.TypeError, ErrArgShouldBeInteger, "sleep", "duration", args[0].Inspect(), args[0].Type())
+		}
+		time.Sleep(time.Duration(ms.Value) * time.Millisecond)
+
+		return NULL
+	}
+	builtins["format_time"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) < 1 || len(args) > 2 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 2)
+		}
+
+		ms, isInt := args[0].(*object.Integer)
+		if !isInt {
+			return newError(object.TypeError, ErrArgShouldBeInteger, "format_time", "time", args[0].Inspect(), args[0].Type())
+		}
+		layout, err := timeLayout("format_time", args[1:])
+		if err != nil {
+			return err
+		}
+
+		return in.track(&object.String{Value: time.UnixMilli(ms.Value).UTC().Format(layout)})
+	}
+	builtins["parse_time"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) < 1 || len(args) > 2 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 2)
+		}
+
+		str, isStr := args[0].(*object.String)
+		if !isStr {
+			return newError(object.TypeError, ErrArgShouldBeString, "parse_time", "time", args[0].Inspect(), args[0].Type())
+		}
+		layout, err := timeLayout("parse_time", args[1:])
+		if err != nil {
+			return err
+		}
+
+		t, parseErr := time.Parse(layout, str.Value)
+		if parseErr != nil {
+			return wrapError(object.ValueError, ErrInvalidTime, parseErr)
+		}
+
+		return newInteger(t.UnixMilli())
+	}
+}
+
+// timeLayout returns the layout passed as the optional argument of the builtin
+// called name
+func timeLayout(name string, args []object.Object) (string, *object.Error) {
+	if len(args) == 0 {
+		return time.RFC3339, nil
+	}
+
+	layout, isStr := args[0].(*object.String)
+	if !isStr {
+		return "", newError(object.TypeError, ErrArgShouldBeString, name, "layout", args[0].Inspect(), args[0].Type())
+	}
+	return layout.Value, nil
+}
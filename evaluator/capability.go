@@ -0,0 +1,87 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrCapabilityDisabled = "permission denied: %s needs the %s capability"
+)
+
+// Capability is a set of things builtins can do to the host beyond computing
+// values, which embedders may deny to untrusted scripts
+type Capability uint
+
+const (
+	// CapFS allows reading the file system
+	CapFS Capability = 1 << iota
+	// CapNet allows making and serving network requests
+	CapNet
+	// CapProcess allows reading and changing the process environment and running
+	// other programs
+	CapProcess
+	// CapTime allows reading the clock and sleeping
+	CapTime
+
+	CapNone Capability = 0
+	CapAll             = CapFS | CapNet | CapProcess | CapTime
+)
+
+var capabilityNames = []struct {
+	cap  Capability
+	name string
+}{
+	{CapFS, "fs"},
+	{CapNet, "net"},
+	{CapProcess, "process"},
+	{CapTime, "time"},
+}
+
+func (c Capability) String() string {
+	var names []string
+	for _, cn := range capabilityNames {
+		if c&cn.cap != 0 {
+			names = append(names, cn.name)
+			c &^= cn.cap
+		}
+	}
+	if c != 0 {
+		names = append(names, fmt.Sprintf("Capability(%d)", uint(c)))
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// builtinCapabilities lists the builtins that reach outside the interpreter and
+// what they need to do so. Every other builtin only computes values or uses the
+// streams and arguments the host hands to the interpreter, so it is always
+// available.
+var builtinCapabilities = map[string]Capability{
+	"cwd":          CapFS,
+	"http_get":     CapNet,
+	"http_request": CapNet,
+	"serve":        CapNet,
+	"env":          CapProcess,
+	"set_env":      CapProcess,
+	"exec":         CapProcess,
+	"now":          CapTime,
+	"sleep":        CapTime,
+}
+
+// allowed reports whether builtins may use c
+func (in *Interpreter) allowed(c Capability) bool {
+	return in.caps&c == c
+}
+
+// disabledBuiltin stands in for a builtin that is missing the capabilities it
+// needs, failing with a permission error whenever it is called
+func disabledBuiltin(name string, missing Capability) builtinFn {
+	return func(in *Interpreter, args ...object.Object) object.Object {
+		return newError(object.PermissionError, ErrCapabilityDisabled, name, missing)
+	}
+}
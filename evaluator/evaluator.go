@@ -2,112 +2,248 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/nayyara-airlangga/basedlang/ast"
 	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/token"
+	"github.com/nayyara-airlangga/basedlang/types"
 )
 
 const (
 	ErrUnsupportedOperatorInfix  = "unsupported operator: %s %s %s"
 	ErrUnsupportedOperatorPrefix = "unsupported operator: %s%s"
 	ErrUnsupportedOperatorIndex  = "unsupported operator: index not supported on %s (%s)"
+	ErrUnsupportedOperatorSlice  = "unsupported operator: slice not supported on %s (%s)"
+	ErrUnsupportedAssignment     = "unsupported operator: index assignment not supported on %s (%s)"
 	ErrInvalidIndex              = "invalid argument: index %s (%s) is not an integer"
+	ErrIndexOutOfRange           = "invalid argument: index %d out of range for length %d"
+	ErrUnusableHashKey           = "invalid argument: %s (%s) is unusable as a hash key"
+	ErrInvalidSpread             = "invalid argument: cannot spread %s (%s), expected an array"
+	ErrInvalidHashSpread         = "invalid argument: cannot spread %s (%s), expected a hash"
 	ErrTypeMismatch              = "type mismatch: %s %s %s"
 	ErrIdentifierNotFound        = "identifier not found: %s"
+	ErrGlobalNotFound            = "global not found: %s"
 	ErrNotAFunction              = "not a function: %s"
 	ErrWrongNumberOfArgs         = "wrong number of arguments. got=%d, want=%d"
+	ErrNotEnoughArgs             = "wrong number of arguments. got=%d, want>=%d"
+	ErrWrongNumberOfValues       = "wrong number of values to unpack. got=%d, want=%d"
+	ErrWrongArgType              = "type mismatch: argument %s must be %s. got=%s (%s)"
+	ErrWrongReturnType           = "type mismatch: result must be %s. got=%s (%s)"
+	ErrUnknownType               = "unknown type: %s"
+	ErrDivisionByZero            = "division by zero"
+	ErrIntegerOverflow           = "integer overflow: %d %s %d"
+	ErrIntegerOverflowNegate     = "integer overflow: -(%d)"
 )
 
-func newError(format string, args ...any) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, args...)}
+func newError(kind object.ErrorKind, format string, args ...any) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
 }
 
+// wrapError creates an error caused by a Go error, whose message fills the
+// single verb of format
+func wrapError(kind object.ErrorKind, format string, cause error) *object.Error {
+	err := newError(kind, format, cause)
+	err.Cause = cause
+	return err
+}
+
+// isError reports whether obj aborts evaluation, which exits do as well as
+// errors
 func isError(obj object.Object) bool {
-	return obj != nil && obj.Type() == object.ERROR
+	return obj != nil && (obj.Type() == object.ERROR || obj.Type() == object.EXIT)
 }
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NullValue
+	TRUE  = object.TrueValue
+	FALSE = object.FalseValue
 )
 
-func Eval(n ast.Node, env *object.Environment) object.Object {
+// Small integers are preallocated and shared the same way booleans are, so hot
+// arithmetic and counters don't allocate. Integer objects must therefore never be
+// mutated in place.
+const (
+	minCachedInt = -128
+	maxCachedInt = 1024
+)
+
+var cachedInts = func() (ints [maxCachedInt - minCachedInt + 1]object.Integer) {
+	for i := range ints {
+		ints[i].Value = int64(i + minCachedInt)
+	}
+	return
+}()
+
+func newInteger(val int64) *object.Integer {
+	if val >= minCachedInt && val <= maxCachedInt {
+		return &cachedInts[val-minCachedInt]
+	}
+	return &object.Integer{Value: val}
+}
+
+func (in *Interpreter) Eval(n ast.Node, env *object.Environment) object.Object {
+	res := in.eval(n, env)
+
+	// Nodes return the errors of the nodes within them as is, so errors are
+	// located at the innermost node that failed
+	if err, isErr := res.(*object.Error); isErr && err.Pos == (token.Position{}) {
+		err.Pos, err.End = n.Pos(), ast.End(n)
+	}
+	if in.trace != nil {
+		in.traceNode(n, res)
+	}
+	return res
+}
+
+func (in *Interpreter) eval(n ast.Node, env *object.Environment) object.Object {
+	if err := in.consumeFuel(); err != nil {
+		return err
+	}
+	if in.coverage != nil {
+		in.coverage.Record(n)
+	}
+	if in.profile != nil {
+		in.sample(n, env)
+	}
+
 	switch n := n.(type) {
 	// Statements
 	case *ast.Program:
-		return evalProgram(n.Statements, env)
+		env.MarkGlobal()
+		return in.evalProgram(n.Statements, env)
 	case *ast.LetStatement:
-		val := Eval(n.Value, env)
+		val := in.Eval(n.Value, env)
 		if isError(val) {
 			return val
 		}
+		if len(n.Names) > 1 {
+			return evalTupleUnpacking(n.Names, val, env)
+		}
+		// Function literals are named after the let binding them, while they
+		// are still only visible here
+		if _, isLiteral := n.Value.(*ast.FunctionLiteral); isLiteral {
+			val.(*object.Function).Name = n.Name.Value
+		}
 		env.Set(n.Name.Value, val)
+	case *ast.AssignStatement:
+		if err := in.evalAssignStatement(n, env); err != nil {
+			return err
+		}
 	case *ast.ExpressionStatement:
-		return Eval(n.Expression, env)
+		return in.Eval(n.Expression, env)
 	case *ast.BlockStatement:
-		return evalBlockStatements(n.Statements, env)
+		return in.evalBlockStatements(n.Statements, env)
 	case *ast.ReturnStatement:
-		val := Eval(n.ReturnValue, env)
+		val := in.Eval(n.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.YieldStatement:
+		return in.evalYieldStatement(n, env)
+	case *ast.RaiseStatement:
+		return in.evalRaiseStatement(n, env)
+	case *ast.GlobalStatement:
+		if err := in.evalGlobalStatement(n, env); err != nil {
+			return err
+		}
+	case *ast.ForStatement:
+		return in.evalForStatement(n, env)
+	case *ast.DoWhileStatement:
+		return in.evalDoWhileStatement(n, env)
 		// Expressions
 	case *ast.Identifier:
-		return evalIdentifier(n, env)
+		return in.evalIdentifier(n, env)
 	case *ast.IntLiteral:
-		return &object.Integer{Value: n.Value}
+		return newInteger(n.Value)
+	case *ast.FloatLiteral:
+		return &object.Float{Value: n.Value}
 	case *ast.BooleanLiteral:
 		return nativeBoolToObjBool(n.Value)
 	case *ast.StringLiteral:
-		return &object.String{Value: n.Value}
+		return object.InternString(n.Value)
+	case *ast.InterfaceLiteral:
+		return &object.Interface{Methods: n.Methods}
 	case *ast.ArrayLiteral:
-		elems := evalExpressions(n.Elems, env)
+		elems := in.evalExpressions(n.Elems, env)
+		if len(elems) == 1 && isError(elems[0]) {
+			return elems[0]
+		}
+		return in.track(&object.Array{Elems: elems})
+	case *ast.HashLiteral:
+		return in.evalHashLiteral(n, env)
+	case *ast.TupleExpression:
+		elems := in.evalExpressions(n.Elems, env)
 		if len(elems) == 1 && isError(elems[0]) {
 			return elems[0]
 		}
-		return &object.Array{Elems: elems}
+		return in.track(&object.Tuple{Elems: elems})
 	case *ast.IndexExpression:
-		left := Eval(n.Left, env)
+		left := in.Eval(n.Left, env)
 		if isError(left) {
 			return left
 		}
-		idx := Eval(n.Index, env)
+		idx := in.Eval(n.Index, env)
 		if isError(idx) {
 			return idx
 		}
 		return evalIndexExpression(left, idx)
+	case *ast.SliceExpression:
+		return in.evalSliceExpression(n, env)
 	case *ast.PrefixExpression:
-		right := Eval(n.Right, env)
+		right := in.Eval(n.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(n.Operator, right)
+		return in.evalPrefixExpression(n.Operator, right)
 	case *ast.InfixExpression:
-		left := Eval(n.Left, env)
+		left := in.Eval(n.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(n.Right, env)
+		right := in.Eval(n.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(n.Operator, left, right)
+		res := in.evalInfixExpression(n.Operator, left, right)
+		// Concatenation is the only way an infix expression creates a string
+		if res.Type() == object.STRING {
+			return in.track(res)
+		}
+		return res
 	case *ast.IfExpression:
-		return evalIfExpression(n, env)
+		return in.evalIfExpression(n, env)
+	case *ast.MatchExpression:
+		return in.evalMatchExpression(n, env)
 	case *ast.FunctionLiteral:
-		return &object.Function{Params: n.Params, Body: n.Body, Env: env}
+		return &object.Function{Params: n.Params, Variadic: n.Variadic, ReturnType: n.ReturnType, Body: n.Body, Env: env, Generator: n.Generator}
+	case *ast.MacroLiteral:
+		return newError(object.ValueError, ErrMacroOutsideLet)
 	case *ast.CallExpression:
-		f := Eval(n.Function, env)
+		// quote and unquote take the code of their argument rather than its value
+		if isCallTo(n, "quote") {
+			if len(n.Args) != 1 {
+				return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(n.Args), 1)
+			}
+			return in.evalQuote(n.Args[0], env)
+		}
+		if isCallTo(n, "unquote") {
+			return newError(object.ValueError, ErrUnquoteOutsideQuote)
+		}
+		if isCallTo(n, "dbg") {
+			return in.evalDbg(n, env)
+		}
+
+		f := in.Eval(n.Function, env)
 		if isError(f) {
 			return f
 		}
-		args := evalExpressions(n.Args, env)
+		args := in.evalExpressions(n.Args, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(f, args)
+		return in.applyFunctionFrom(f, args, env)
 	default:
 		return NULL
 	}
@@ -115,23 +251,53 @@ func Eval(n ast.Node, env *object.Environment) object.Object {
 	return nil
 }
 
-func applyFunction(f object.Object, args []object.Object) object.Object {
+func (in *Interpreter) applyFunction(f object.Object, args []object.Object) object.Object {
+	return in.applyFunctionFrom(f, args, nil)
+}
+
+// applyFunctionFrom calls f from code evaluated in caller, which is nil when a
+// builtin calls f
+func (in *Interpreter) applyFunctionFrom(f object.Object, args []object.Object, caller *object.Environment) object.Object {
 	switch fn := f.(type) {
 	case *object.Function:
 		fun, isFunc := f.(*object.Function)
 		if !isFunc {
-			return newError(ErrNotAFunction, fun.Type())
+			return newError(object.TypeError, ErrNotAFunction, fun.Type())
 		}
-		if len(fn.Params) != len(args) {
-			return newError(ErrWrongNumberOfArgs, len(args), len(fn.Params))
+		if fn.Variadic && len(args) < len(fn.Params)-1 {
+			return newError(object.ArgumentError, ErrNotEnoughArgs, len(args), len(fn.Params)-1)
+		}
+		if !fn.Variadic && len(fn.Params) != len(args) {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), len(fn.Params))
+		}
+		if err := checkArgTypes(fun, args); err != nil {
+			return err
 		}
 		extEnv := extendFunctionEnv(fun, args)
-		evaluated := Eval(fun.Body, extEnv)
-		return unwrapReturnValue(evaluated)
+		if in.profile != nil {
+			in.enterFrame(extEnv, fun, caller)
+		}
+		if fun.Generator {
+			return in.newGenerator(fun, extEnv)
+		}
+		evaluated := unwrapReturnValue(in.Eval(fun.Body, extEnv))
+		if fun.ReturnType == nil || isError(evaluated) {
+			return evaluated
+		}
+		if evaluated == nil {
+			evaluated = NULL
+		}
+		if ok, err := hasType(fun.ReturnType, evaluated); !ok {
+			if err != nil {
+				return err
+			}
+			return newError(object.TypeError, ErrWrongReturnType, fun.ReturnType.Name, evaluated.Inspect(), evaluated.Type())
+		}
+		return evaluated
 	case *object.Builtin:
 		return fn.Fn(args...)
 	default:
-		return newError(ErrNotAFunction, f.Type())
+		return newError(object.TypeError, ErrNotAFunction, f.Type())
 	}
 
 }
@@ -142,10 +308,55 @@ func extendFunctionEnv(
 ) *object.Environment {
 	env := object.NewLocalEnvironment(fn.Env)
 	for i, arg := range fn.Params {
+		if fn.Variadic && i+1 == len(fn.Params) {
+			rest := make([]object.Object, len(args)-i)
+			copy(rest, args[i:])
+			env.Set(arg.Value, &object.Array{Elems: rest})
+			break
+		}
 		env.Set(arg.Value, args[i])
 	}
 	return env
 }
+
+// checkArgTypes verifies args against the annotations of the parameters they
+// are bound to. A variadic parameter's annotation applies to every argument it
+// collects.
+func checkArgTypes(fn *object.Function, args []object.Object) *object.Error {
+	for i, arg := range args {
+		param := fn.Params[min(i, len(fn.Params)-1)]
+		if param.Type == nil {
+			continue
+		}
+		if ok, err := hasType(param.Type, arg); !ok {
+			if err != nil {
+				return err
+			}
+			return newError(object.TypeError, ErrWrongArgType, param.Value, param.Type.Name, arg.Inspect(), arg.Type())
+		}
+	}
+	return nil
+}
+
+// hasType reports whether obj is of the type ta names, failing with an error
+// when there is no such type
+func hasType(ta *ast.TypeAnnotation, obj object.Object) (bool, *object.Error) {
+	t, ok := types.Lookup(ta.Name)
+	if !ok {
+		return false, newError(object.NameError, ErrUnknownType, ta.Name)
+	}
+	if t == types.Unknown {
+		return true, nil
+	}
+
+	objType := obj.Type()
+	// Builtins can be passed wherever functions are expected
+	if objType == object.BUILTIN {
+		objType = object.FUNCTION
+	}
+	return t == types.Type(objType), nil
+}
+
 func unwrapReturnValue(obj object.Object) object.Object {
 	if rv, isRetVal := obj.(*object.ReturnValue); isRetVal {
 		return rv.Value
@@ -153,71 +364,299 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
-func evalExpressions(exprs []ast.Expression, env *object.Environment) (result []object.Object) {
+func (in *Interpreter) evalExpressions(exprs []ast.Expression, env *object.Environment) (result []object.Object) {
 	for _, e := range exprs {
-		evaluated := Eval(e, env)
+		spread, isSpread := e.(*ast.SpreadExpression)
+		if isSpread {
+			e = spread.Value
+		}
+
+		evaluated := in.Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
-		result = append(result, evaluated)
+
+		if !isSpread {
+			result = append(result, evaluated)
+			continue
+		}
+
+		arr, isArr := evaluated.(*object.Array)
+		if !isArr {
+			return []object.Object{newError(object.TypeError, ErrInvalidSpread, evaluated.Inspect(), evaluated.Type())}
+		}
+		result = append(result, arr.Elements()...)
 	}
 
 	return
 }
 
+func evalTupleUnpacking(
+	names []*ast.Identifier,
+	val object.Object,
+	env *object.Environment,
+) object.Object {
+	tuple, isTuple := val.(*object.Tuple)
+	if !isTuple {
+		return newError(object.ValueError, ErrWrongNumberOfValues, 1, len(names))
+	}
+	if len(tuple.Elems) != len(names) {
+		return newError(object.ValueError, ErrWrongNumberOfValues, len(tuple.Elems), len(names))
+	}
+
+	for i, name := range names {
+		env.Set(name.Value, tuple.Elems[i])
+	}
+
+	return nil
+}
+
+func (in *Interpreter) evalHashLiteral(hl *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for i, keyNode := range hl.Keys {
+		// Pairs of a spread hash are set in its order, so later pairs override
+		// them and they override earlier ones
+		if spread, isSpread := keyNode.(*ast.SpreadExpression); isSpread {
+			other := in.Eval(spread.Value, env)
+			if isError(other) {
+				return other
+			}
+			otherHash, isHash := other.(*object.Hash)
+			if !isHash {
+				return newError(object.TypeError, ErrInvalidHashSpread, other.Inspect(), other.Type())
+			}
+			otherHash.Each(func(key, value object.Object) {
+				hash.Set(key.(object.Hashable), value)
+			})
+			continue
+		}
+
+		key := in.Eval(keyNode, env)
+		if isError(key) {
+			return key
+		}
+		hashable, isHashable := key.(object.Hashable)
+		if !isHashable {
+			return newError(object.TypeError, ErrUnusableHashKey, key.Inspect(), key.Type())
+		}
+
+		value := in.Eval(hl.Values[i], env)
+		if isError(value) {
+			return value
+		}
+
+		hash.Set(hashable, value)
+	}
+
+	return in.track(hash)
+}
+
 func evalIndexExpression(left, idx object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY:
 		if idx.Type() == object.INTEGER {
 			return evalArrayIndexExpression(left, idx)
 		}
-		return newError(ErrInvalidIndex, idx.Inspect(), idx.Type())
+		return newError(object.TypeError, ErrInvalidIndex, idx.Inspect(), idx.Type())
+	case left.Type() == object.HASH:
+		return evalHashIndexExpression(left.(*object.Hash), idx)
 	default:
-		return newError(ErrUnsupportedOperatorIndex, left.Inspect(), left.Type())
+		return newError(object.TypeError, ErrUnsupportedOperatorIndex, left.Inspect(), left.Type())
 	}
 }
 
+func evalHashIndexExpression(hash *object.Hash, key object.Object) object.Object {
+	hashable, isHashable := key.(object.Hashable)
+	if !isHashable {
+		return newError(object.TypeError, ErrUnusableHashKey, key.Inspect(), key.Type())
+	}
+
+	if value, exists := hash.Get(hashable); exists {
+		return value
+	}
+	return NULL
+}
+
 func evalArrayIndexExpression(left, idx object.Object) object.Object {
 	arr := left.(*object.Array)
 	i := idx.(*object.Integer).Value
 
 	if i < 0 {
-		i = int64(len(arr.Elems)) + i
+		i = int64(arr.Len()) + i
 	}
-	if i < 0 || i > int64(len(arr.Elems)-1) {
+	if i < 0 || i > int64(arr.Len()-1) {
 		return NULL
 	}
 
-	return arr.Elems[i]
+	return arr.Get(int(i))
 }
 
-func evalIdentifier(id *ast.Identifier, env *object.Environment) object.Object {
+// evalAssignStatement replaces the element of an array or hash that the target
+// of as refers to. Arrays can only have elements they already have replaced,
+// while hashes get a new entry for a key they don't have yet. The array or hash
+// and the index are evaluated once, and the value only when it's assigned.
+func (in *Interpreter) evalAssignStatement(as *ast.AssignStatement, env *object.Environment) object.Object {
+	target := as.Target.(*ast.IndexExpression)
+
+	left := in.Eval(target.Left, env)
+	if isError(left) {
+		return left
+	}
+	idx := in.Eval(target.Index, env)
+	if isError(idx) {
+		return idx
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		i, isInt := idx.(*object.Integer)
+		if !isInt {
+			return newError(object.TypeError, ErrInvalidIndex, idx.Inspect(), idx.Type())
+		}
+		pos := i.Value
+		if pos < 0 {
+			pos = int64(left.Len()) + pos
+		}
+		if pos < 0 || pos >= int64(left.Len()) {
+			return newError(object.ValueError, ErrIndexOutOfRange, i.Value, left.Len())
+		}
+		if !replaces(as.Operator, left.Get(int(pos))) {
+			return nil
+		}
+		val := in.Eval(as.Value, env)
+		if isError(val) {
+			return val
+		}
+		left.Set(int(pos), val)
+	case *object.Hash:
+		key, isHashable := idx.(object.Hashable)
+		if !isHashable {
+			return newError(object.TypeError, ErrUnusableHashKey, idx.Inspect(), idx.Type())
+		}
+		if current, exists := left.Get(key); exists && !replaces(as.Operator, current) {
+			return nil
+		}
+		val := in.Eval(as.Value, env)
+		if isError(val) {
+			return val
+		}
+		if _, exists := left.Get(key); !exists {
+			if err := in.allocate(2 * elemSize); err != nil {
+				return err
+			}
+		}
+		left.Set(key, val)
+	default:
+		return newError(object.TypeError, ErrUnsupportedAssignment, left.Inspect(), left.Type())
+	}
+
+	return nil
+}
+
+// replaces reports whether assigning with op replaces current, the element
+// being assigned to. ||= only replaces falsy elements and ??= null ones.
+func replaces(op string, current object.Object) bool {
+	switch op {
+	case "||=":
+		return !isTruthy(current)
+	case "??=":
+		return current == NULL
+	default:
+		return true
+	}
+}
+
+func (in *Interpreter) evalSliceExpression(se *ast.SliceExpression, env *object.Environment) object.Object {
+	left := in.Eval(se.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	arr, isArr := left.(*object.Array)
+	if !isArr {
+		return newError(object.TypeError, ErrUnsupportedOperatorSlice, left.Inspect(), left.Type())
+	}
+
+	length := int64(arr.Len())
+
+	low, err := in.evalSliceBound(se.Low, 0, length, env)
+	if err != nil {
+		return err
+	}
+	high, err := in.evalSliceBound(se.High, length, length, env)
+	if err != nil {
+		return err
+	}
+
+	elems := []object.Object{}
+	if low < high {
+		elems = append(elems, arr.Elements()[low:high]...)
+	}
+
+	return in.track(&object.Array{Elems: elems})
+}
+
+// evalSliceBound resolves a slice bound to an offset clamped within [0, length].
+// Missing bounds fall back to def and negative bounds count from the end.
+func (in *Interpreter) evalSliceBound(
+	bound ast.Expression,
+	def, length int64,
+	env *object.Environment,
+) (int64, object.Object) {
+	if bound == nil {
+		return def, nil
+	}
+
+	evaluated := in.Eval(bound, env)
+	if isError(evaluated) {
+		return 0, evaluated
+	}
+
+	intObj, isInt := evaluated.(*object.Integer)
+	if !isInt {
+		return 0, newError(object.TypeError, ErrInvalidIndex, evaluated.Inspect(), evaluated.Type())
+	}
+
+	i := intObj.Value
+	if i < 0 {
+		i = length + i
+	}
+
+	return max(0, min(i, length)), nil
+}
+
+func (in *Interpreter) evalIdentifier(id *ast.Identifier, env *object.Environment) object.Object {
 	val, exists := env.Get(id.Value)
 	if exists {
 		return val
 	}
 
-	builtin, exists := builtins[id.Value]
+	builtin, exists := in.builtins[id.Value]
 	if exists {
 		return builtin
 	}
 
-	return newError(ErrIdentifierNotFound, id.Value)
+	if t, exists := object.Types[id.Value]; exists {
+		return t
+	}
+
+	return newError(object.NameError, ErrIdentifierNotFound, id.Value)
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	cond := Eval(ie.Condition, env)
+func (in *Interpreter) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	cond := in.Eval(ie.Condition, env)
 
 	if isError(cond) {
 		return cond
 	}
 
 	if isTruthy(cond) {
-		return Eval(ie.Body, env)
+		return in.Eval(ie.Body, env)
 	} else if ie.Else != nil {
 		switch el := ie.Else.(type) {
 		case *ast.BlockStatement, *ast.IfExpression:
-			return Eval(el, env)
+			return in.Eval(el, env)
 		default:
 			return NULL
 		}
@@ -237,38 +676,47 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
-func evalInfixExpression(op string, left, right object.Object) object.Object {
+func (in *Interpreter) evalInfixExpression(op string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER && right.Type() == object.INTEGER:
-		return evalIntegerInfixExpression(op, left, right)
+		return in.evalIntegerInfixExpression(op, left, right)
+	case isNumber(left) && isNumber(right):
+		// Mixing integers with floats promotes the integers
+		return evalFloatInfixExpression(op, left, right)
 	case left.Type() == object.STRING && right.Type() == object.STRING:
 		return evalStringInfixExpression(op, left, right)
 	// The following cases are only for boolean expressions
 	case op == "==":
-		return nativeBoolToObjBool(left == right)
+		return nativeBoolToObjBool(object.Equal(left, right))
 	case op == "!=":
-		return nativeBoolToObjBool(left != right)
+		return nativeBoolToObjBool(!object.Equal(left, right))
 	case left.Type() != right.Type():
-		return newError(ErrTypeMismatch, left.Type(), op, right.Type())
+		return newError(object.TypeError, ErrTypeMismatch, left.Type(), op, right.Type())
 	default:
-		return newError(ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
+		return newError(object.TypeError, ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
 	}
 }
 
-func evalIntegerInfixExpression(op string, left, right object.Object) object.Object {
+func (in *Interpreter) evalIntegerInfixExpression(op string, left, right object.Object) object.Object {
 	leftInt := left.(*object.Integer)
 	rightInt := right.(*object.Integer)
 
 	switch op {
 	// Arithmetics
-	case "*":
-		return &object.Integer{Value: leftInt.Value * rightInt.Value}
-	case "/":
-		return &object.Integer{Value: leftInt.Value / rightInt.Value}
-	case "+":
-		return &object.Integer{Value: leftInt.Value + rightInt.Value}
-	case "-":
-		return &object.Integer{Value: leftInt.Value - rightInt.Value}
+	case "*", "/", "//", "+", "-":
+		if (op == "/" || op == "//") && rightInt.Value == 0 {
+			return newError(object.ZeroDivisionError, ErrDivisionByZero)
+		}
+		// Dividing unevenly results in a float rather than truncating, which is
+		// what // is for
+		if op == "/" && leftInt.Value%rightInt.Value != 0 {
+			return &object.Float{Value: float64(leftInt.Value) / float64(rightInt.Value)}
+		}
+		res, ok := arith(op, leftInt.Value, rightInt.Value)
+		if !ok && in.strict {
+			return newError(object.OverflowError, ErrIntegerOverflow, leftInt.Value, op, rightInt.Value)
+		}
+		return newInteger(res)
 	// Relational
 	case "<":
 		return nativeBoolToObjBool(leftInt.Value < rightInt.Value)
@@ -283,29 +731,98 @@ func evalIntegerInfixExpression(op string, left, right object.Object) object.Obj
 	case "!=":
 		return nativeBoolToObjBool(leftInt.Value != rightInt.Value)
 	default:
-		return newError(ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
+		return newError(object.TypeError, ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
 	}
 }
 
-func evalStringInfixExpression(op string, left, right object.Object) object.Object {
-	if op != "+" {
-		return newError(ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
+func evalFloatInfixExpression(op string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch op {
+	// Arithmetics
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "//":
+		return &object.Float{Value: math.Floor(leftVal / rightVal)}
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	// Relational
+	case "<":
+		return nativeBoolToObjBool(leftVal < rightVal)
+	case "<=":
+		return nativeBoolToObjBool(leftVal <= rightVal)
+	case ">":
+		return nativeBoolToObjBool(leftVal > rightVal)
+	case ">=":
+		return nativeBoolToObjBool(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToObjBool(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToObjBool(leftVal != rightVal)
+	default:
+		return newError(object.TypeError, ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
 	}
+}
 
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER || obj.Type() == object.FLOAT
+}
+
+// toFloat converts a number to a float
+func toFloat(obj object.Object) float64 {
+	if i, isInt := obj.(*object.Integer); isInt {
+		return float64(i.Value)
+	}
+	return obj.(*object.Float).Value
+}
+
+// evalStringInfixExpression concatenates strings or compares them, byte by byte
+// in lexicographic order
+func evalStringInfixExpression(op string, left, right object.Object) object.Object {
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
 
-	return &object.String{Value: leftVal + rightVal}
+	switch op {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "<":
+		return nativeBoolToObjBool(leftVal < rightVal)
+	case "<=":
+		return nativeBoolToObjBool(leftVal <= rightVal)
+	case ">":
+		return nativeBoolToObjBool(leftVal > rightVal)
+	case ">=":
+		return nativeBoolToObjBool(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToObjBool(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToObjBool(leftVal != rightVal)
+	default:
+		return newError(object.TypeError, ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
+	}
 }
 
-func evalPrefixExpression(op string, right object.Object) object.Object {
+func (in *Interpreter) evalPrefixExpression(op string, right object.Object) object.Object {
 	switch op {
 	case "!":
+		// Only booleans can be negated in strict mode, rather than treating zero
+		// and other values as falsy
+		if in.strict && right.Type() != object.BOOLEAN {
+			return newError(object.TypeError, ErrUnsupportedOperatorPrefix, op, right.Type())
+		}
 		return evalBangOperatorExpression(right)
 	case "-":
+		if i, isInt := right.(*object.Integer); isInt && i.Value == math.MinInt64 && in.strict {
+			return newError(object.OverflowError, ErrIntegerOverflowNegate, i.Value)
+		}
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return newError(ErrUnsupportedOperatorPrefix, op, right.Type())
+		return newError(object.TypeError, ErrUnsupportedOperatorPrefix, op, right.Type())
 	}
 }
 
@@ -318,25 +835,53 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 			return TRUE
 		}
 		return FALSE
+	case *object.Float:
+		return nativeBoolToObjBool(right.Value == 0)
 	default:
 		return FALSE
 	}
 }
 
+// arith applies an arithmetic operator to integers, wrapping around on overflow.
+// It reports whether the result is exact.
+func arith(op string, a, b int64) (int64, bool) {
+	switch op {
+	case "+":
+		res := a + b
+		return res, (res > a) == (b > 0)
+	case "-":
+		res := a - b
+		return res, (res < a) == (b > 0)
+	case "*":
+		res := a * b
+		return res, a == 0 || res/a == b && !(a == -1 && b == math.MinInt64)
+	}
+
+	res := a / b
+	// Floor division rounds down rather than toward zero
+	if op == "//" && a%b != 0 && (a < 0) != (b < 0) {
+		res--
+	}
+	// Dividing the smallest integer by -1 is the only division that overflows
+	return res, !(a == math.MinInt64 && b == -1)
+}
+
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if intObj, isInt := right.(*object.Integer); isInt {
-		intObj.Value = -intObj.Value
-		return intObj
+	switch right := right.(type) {
+	case *object.Integer:
+		return newInteger(-right.Value)
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
 	}
-	return newError(ErrUnsupportedOperatorPrefix, "-", right.Type())
+	return newError(object.TypeError, ErrUnsupportedOperatorPrefix, "-", right.Type())
 }
 
-func evalProgram(stmts []ast.Statement, env *object.Environment) (res object.Object) {
+func (in *Interpreter) evalProgram(stmts []ast.Statement, env *object.Environment) (res object.Object) {
 	for _, s := range stmts {
-		res = Eval(s, env)
+		res = in.Eval(s, env)
 
-		if err, isErr := res.(*object.Error); isErr {
-			return err
+		if isError(res) {
+			return res
 		}
 		if rv, isRetVal := res.(*object.ReturnValue); isRetVal {
 			return rv.Value
@@ -345,12 +890,12 @@ func evalProgram(stmts []ast.Statement, env *object.Environment) (res object.Obj
 	return res
 }
 
-func evalBlockStatements(stmts []ast.Statement, env *object.Environment) (res object.Object) {
+func (in *Interpreter) evalBlockStatements(stmts []ast.Statement, env *object.Environment) (res object.Object) {
 	for _, s := range stmts {
-		res = Eval(s, env)
+		res = in.Eval(s, env)
 
-		if err, isErr := res.(*object.Error); isErr {
-			return err
+		if isError(res) {
+			return res
 		}
 
 		if rv, isRetVal := res.(*object.ReturnValue); isRetVal {
@@ -360,6 +905,66 @@ func evalBlockStatements(stmts []ast.Statement, env *object.Environment) (res ob
 	return res
 }
 
+// evalDbg evaluates the single argument of a call to dbg, printing where the
+// call is and the code of the argument along with its value before returning
+// the value, like [3:9] x + 1 = 2. The code is printed as it was written, or
+// the way the parser understood it when the source wasn't kept, like for calls
+// made up by macros.
+func (in *Interpreter) evalDbg(call *ast.CallExpression, env *object.Environment) object.Object {
+	if len(call.Args) != 1 {
+		return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(call.Args), 1)
+	}
+
+	val := in.Eval(call.Args[0], env)
+	if isError(val) {
+		return val
+	}
+
+	code := call.Source
+	if code == "" {
+		code = call.Args[0].String()
+	}
+	pos := call.Pos()
+	fmt.Fprintf(in.stderr, "[%d:%d] %s = %s\n", pos.Line, pos.Column, code, val.Inspect())
+
+	return val
+}
+
+// evalRaiseStatement fails with the value of rs, which is kept on the error
+// alongside the message it makes
+func (in *Interpreter) evalRaiseStatement(rs *ast.RaiseStatement, env *object.Environment) object.Object {
+	val := in.Eval(rs.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	msg := val.Inspect()
+	if str, isStr := val.(*object.String); isStr {
+		msg = str.Value
+	}
+
+	err := newError(object.RaisedError, "%s", msg)
+	err.Value = val
+	return err
+}
+
+// evalGlobalStatement rebinds a name in the environment the program enclosing
+// gs was evaluated in
+func (in *Interpreter) evalGlobalStatement(gs *ast.GlobalStatement, env *object.Environment) object.Object {
+	val := in.Eval(gs.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	globals := env.Global()
+	if _, exists := globals.Get(gs.Name.Value); !exists {
+		return newError(object.NameError, ErrGlobalNotFound, gs.Name.Value)
+	}
+	globals.Set(gs.Name.Value, val)
+
+	return nil
+}
+
 func nativeBoolToObjBool(val bool) *object.Boolean {
 	if val {
 		return TRUE
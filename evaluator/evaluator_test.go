@@ -1,11 +1,26 @@
 package evaluator
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"regexp/syntax"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/nayyara-airlangga/basedlang/lexer"
 	"github.com/nayyara-airlangga/basedlang/object"
 	"github.com/nayyara-airlangga/basedlang/parser"
+	"github.com/nayyara-airlangga/basedlang/profiler"
 )
 
 func TestErrorHandling(t *testing.T) {
@@ -67,6 +82,14 @@ func TestErrorHandling(t *testing.T) {
 			`[1, 2, 3][true]`,
 			"invalid argument: index true (BOOLEAN) is not an integer",
 		},
+		{
+			`999[1:]`,
+			"unsupported operator: slice not supported on 999 (INTEGER)",
+		},
+		{
+			`[1, 2, 3][:"2"]`,
+			"invalid argument: index 2 (STRING) is not an integer",
+		},
 	}
 
 	for _, tc := range tests {
@@ -82,21 +105,1855 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
-func TestBuiltInFunctions(t *testing.T) {
+func TestCall(t *testing.T) {
+	in := New()
+	env := object.NewEnvironment()
+	program := parser.New(lexer.New(`
+let total = 0;
+let add = fn(a: int, b) { a + b };
+let early = fn(x) { if (x > 0) { return "positive" } "other" };
+`)).Parse()
+	in.Eval(program, env)
+
+	add, _ := env.Get("add")
+	testIntegerObject(t, in.Call(add, newInteger(2), newInteger(3)), 5)
+
+	tests := []struct {
+		name     string
+		args     []object.Object
+		expected string
+	}{
+		{"add", []object.Object{newInteger(1), newInteger(2)}, "3"},
+		{"early", []object.Object{newInteger(1)}, "positive"},
+		{"len", []object.Object{object.InternString("four")}, "4"},
+		{"add", []object.Object{object.InternString("1"), newInteger(2)}, "ERROR: type mismatch: argument a must be int. got=1 (STRING)"},
+		{"add", nil, "ERROR: wrong number of arguments. got=0, want=2"},
+		{"missing", nil, "ERROR: identifier not found: missing"},
+		{"total", nil, "ERROR: not a function: INTEGER"},
+	}
+
+	for _, tc := range tests {
+		result := in.CallByName(env, tc.name, tc.args...)
+		if result.Inspect() != tc.expected {
+			t.Errorf("wrong result calling %s. expected=%q, got=%q", tc.name, tc.expected, result.Inspect())
+		}
+	}
+}
+
+func TestSharedGlobals(t *testing.T) {
+	in := New()
+	globals := object.NewEnvironment()
+	in.Eval(parser.New(lexer.New("let base = 10; let scale = fn(x) { x * base };")).Parse(), globals)
+
+	script, err := NewScript("let base = n; scale(n) + base")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]object.Object, 50)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			env := object.NewLocalEnvironment(globals)
+			if i%2 == 0 {
+				env = globals.Clone()
+			}
+			env.Set("n", newInteger(int64(i)))
+			results[i] = script.RunIn(in, env)
+		}(i)
+	}
+	wg.Wait()
+
+	// Rebinding base doesn't affect the one scale closes over
+	for i, result := range results {
+		testIntegerObject(t, result, int64(i*10+i))
+	}
+
+	base, _ := globals.Get("base")
+	testIntegerObject(t, base, 10)
+}
+
+func TestGlobalStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let count = 0; let inc = fn() { global count = count + 1; }; inc(); inc(); count", "2"},
+		// Local bindings of the same name are neither read nor rebound
+		{"let x = 1; let f = fn() { let x = 10; global x = x + 1; x }; [f(), x]", "[10, 11]"},
+		{"let x = 1; let f = fn() { fn() { match (2) { n => { global x = n } } }() }; f(); x", "2"},
+		{`let log = []; for (s in ["a", "b"]) { global log = append(log, s); } log`, "[a, b]"},
+		{"let x = 1; global x = 2; x", "2"},
+		{"let x = 1; let f = fn() { x }; global x = 2; f()", "2"},
+		{"let f = fn() { global y = 1 }; f()", "ERROR: global not found: y"},
+		{"let x = 1; let f = fn() { let y = 1; global y = 2 }; f()", "ERROR: global not found: y"},
+		{"global len = 1", "ERROR: global not found: len"},
+		{"let x = 1; global x = 1 + true; x", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+
+	// Hosts sharing globals between scripts give each one a local environment,
+	// which is the one that global statements rebind names in
+	in := New()
+	shared := object.NewEnvironment()
+	in.Eval(parser.New(lexer.New("let n = 1;")).Parse(), shared)
+
+	local := object.NewLocalEnvironment(shared)
+	res := in.Eval(parser.New(lexer.New("let f = fn() { global n = n + 1; }; f(); n")).Parse(), local)
+	testIntegerObject(t, res, 2)
+
+	n, _ := shared.Get("n")
+	testIntegerObject(t, n, 1)
+}
+
+func TestScript(t *testing.T) {
+	script, err := NewScript(`let greet = fn(name) { "hi " + name }; greet(who)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	in := New()
+	for _, who := range []string{"ada", "grace"} {
+		env := object.NewEnvironment()
+		env.Set("who", object.InternString(who))
+
+		result := script.RunIn(in, env)
+		if result.Inspect() != "hi "+who {
+			t.Errorf("wrong result. expected=%q, got=%q", "hi "+who, result.Inspect())
+		}
+	}
+
+	result, env := script.Run(in)
+	if result.Inspect() != "ERROR: identifier not found: who" {
+		t.Errorf("fresh environment leaked bindings. got=%q", result.Inspect())
+	}
+	if _, exists := env.Get("greet"); !exists {
+		t.Errorf("environment of the run doesn't hold the script's bindings")
+	}
+
+	_, err = NewScript("let = 1;")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || len(parseErr.Diagnostics) == 0 {
+		t.Fatalf("expected a parse error. got=%v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "parse error: 1:5: expected next token to be IDENT") {
+		t.Errorf("wrong error message. got=%q", err.Error())
+	}
+}
+
+func TestStructuredErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     object.ErrorKind
+		expected string
+	}{
+		{"1 + true", object.TypeError, "1:1: TypeError: type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 1;\n  foo", object.NameError, "2:3: NameError: identifier not found: foo"},
+		{"len(1, 2)", object.ArgumentError, "1:1: ArgumentError: wrong number of arguments. got=2, want=1"},
+		{"let f = fn(n) {\n  10 / n\n};\nf(0)", object.ZeroDivisionError, "2:3: ZeroDivisionError: division by zero"},
+		{"[1, -true]", object.TypeError, "1:5: TypeError: unsupported operator: -BOOLEAN"},
+		{`regex_match("(", "")`, object.ValueError, "1:1: ValueError: invalid argument: error parsing regexp: missing closing ): `(`"},
+		{"let f = fn(n) {\n  raise \"negative\"\n};\nf(-1)", object.RaisedError, "2:3: RaisedError: negative"},
+		{`raise {"code": 1}`, object.RaisedError, "1:1: RaisedError: {code: 1}"},
+	}
+
+	for _, tc := range tests {
+		err, isErr := testEval(tc.input).(*object.Error)
+		if !isErr {
+			t.Errorf("no error for %q", tc.input)
+			continue
+		}
+		if err.Kind != tc.kind {
+			t.Errorf("wrong kind for %q. expected=%s, got=%s", tc.input, tc.kind, err.Kind)
+		}
+		if err.Error() != tc.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tc.input, tc.expected, err.Error())
+		}
+	}
+
+	err := testEval(`regex_match("(", "")`).(*object.Error)
+	var syntaxErr *syntax.Error
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("error doesn't wrap its cause. got=%#v", err.Cause)
+	}
+}
+
+func TestRaise(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`raise "oops"; 1`, "oops"},
+		{`raise [1, 2]`, "[1, 2]"},
+		{`let f = fn(x) { if (x > 1) { raise x } x }; f(1) + f(2)`, "2"},
+		// Errors raised while evaluating the value are the ones reported
+		{`raise 1 + true`, "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		err, isErr := testEval(tc.input).(*object.Error)
+		if !isErr {
+			t.Errorf("no error for %q", tc.input)
+			continue
+		}
+		if err.Message != tc.expected {
+			t.Errorf("wrong message for %q. expected=%q, got=%q", tc.input, tc.expected, err.Message)
+		}
+	}
+
+	err := testEval(`raise {"code": 1}`).(*object.Error)
+	if hash, isHash := err.Value.(*object.Hash); !isHash || hash.Inspect() != "{code: 1}" {
+		t.Errorf("error doesn't keep the raised value. got=%#v", err.Value)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	input := "let f = fn(x) { x + 1 };\nf(2)"
+	expected := `1:9: fn(x) (x + 1) => fn(x) { (x + 1) }
+1:1: let f = fn(x) (x + 1);
+2:1: f => fn(x) { (x + 1) }
+2:3: 2 => 2
+1:17: x => 2
+1:21: 1 => 1
+1:17: (x + 1) => 3
+1:17: (x + 1) => 3
+1:15: (x + 1) => 3
+2:1: f(2) => 3
+2:1: f(2) => 3
+`
+
+	var trace bytes.Buffer
+	program := parser.New(lexer.New(input)).Parse()
+	New(WithTrace(&trace)).Eval(program, object.NewEnvironment())
+
+	if trace.String() != expected {
+		t.Errorf("wrong trace.\nexpected=%q\ngot=%q", expected, trace.String())
+	}
+}
+
+func TestProfile(t *testing.T) {
+	input := `let double = fn(x) {
+  x * 2
+};
+let twice = fn(f, x) { f(f(x)) };
+twice(double, 1);
+collect(map(double, [1]));
+`
+	// Calls made by builtins, like map, start stacks of their own
+	expected := `double:1 1
+double:2 4
+main:1 3
+main:4 2
+main:5 5
+main:5;twice:4 7
+main:5;twice:4;double:1 2
+main:5;twice:4;double:2 8
+main:6 8
+`
+
+	profile := profiler.NewProfile()
+	program := parser.New(lexer.New(input)).Parse()
+	New(WithProfile(profile)).Eval(program, object.NewEnvironment())
+
+	var folded bytes.Buffer
+	profile.WriteFolded(&folded)
+	if folded.String() != expected {
+		t.Errorf("wrong profile.\nexpected=%q\ngot=%q", expected, folded.String())
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		lenient  string
+		expected string
+	}{
+		{"!true", "false", "false"},
+		{"!0", "true", "ERROR: unsupported operator: !INTEGER"},
+		{`!"a"`, "false", "ERROR: unsupported operator: !STRING"},
+		{"9223372036854775807 + 1", "-9223372036854775808", "ERROR: integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "9223372036854775807", "ERROR: integer overflow: -9223372036854775807 - 2"},
+		{"4611686018427387904 * 2", "-9223372036854775808", "ERROR: integer overflow: 4611686018427387904 * 2"},
+		{"let min = -9223372036854775807 - 1; -min", "-9223372036854775808", "ERROR: integer overflow: -(-9223372036854775808)"},
+		{"let min = -9223372036854775807 - 1; min / -1", "-9223372036854775808", "ERROR: integer overflow: -9223372036854775808 / -1"},
+		{"let min = -9223372036854775807 - 1; min // -1", "-9223372036854775808", "ERROR: integer overflow: -9223372036854775808 // -1"},
+		{"pow(2, 63)", "-9223372036854775808", "ERROR: integer overflow: pow(2, 63)"},
+		{"pow(2, 62) + 9 * -3 - 1", "4611686018427387876", "4611686018427387876"},
+		{"pow(-2, 63)", "-9223372036854775808", "-9223372036854775808"},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).Parse()
+
+		lenient := New().Eval(program, object.NewEnvironment())
+		if lenient.Inspect() != tc.lenient {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.lenient, lenient.Inspect())
+		}
+
+		strict := New(WithStrict()).Eval(program, object.NewEnvironment())
+		if strict.Inspect() != tc.expected {
+			t.Errorf("wrong strict result for %s. expected=%q, got=%q", tc.input, tc.expected, strict.Inspect())
+		}
+	}
+}
+
+func TestBuiltInFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("hello world")`, 11},
+		{`len(1)`, "invalid argument: 1 (INTEGER) not supported for len"},
+		{`append([], 1)`, []int{1}},
+		{`append([], 1, 2)`, []int{1, 2}},
+		{`append()`, "invalid argument: not enough arguments for append, expected>=1, got=0"},
+		{`append(1, 2)`, "invalid argument: first argument for append must be an array. got=1 (INTEGER)"},
+		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case []int:
+			arr, isArr := evaluated.(*object.Array)
+			if !isArr {
+				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if len(arr.Elems) != len(expected) {
+				t.Errorf("incorrect number of elements. expected=%d, got=%d",
+					len(expected), len(arr.Elems))
+				continue
+			}
+
+			for i, expectedElem := range expected {
+				testIntegerObject(t, arr.Elems[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
+func TestSpawnAndAwait(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let t = spawn(fn() { 1 + 2 }); await(t);", 3},
+		{"let t = spawn(|x, y| x * y, 6, 7); await(t);", 42},
+		{"let t = spawn(len, [1, 2]); await(t);", 2},
+		{"let x = 10; let t = spawn(fn() { let x = 1; x }); await(t) + x;", 11},
+		{`
+		let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) };
+		let tasks = [spawn(fib, 10), spawn(fib, 11), spawn(fib, 12)];
+		let results = join(tasks);
+		results[0] + results[1] + results[2];
+		`, 288},
+		{"await(spawn(fn() { foobar }))", "identifier not found: foobar"},
+		{"join([spawn(|| 1), spawn(|| foobar)])", "identifier not found: foobar"},
+		{"spawn()", "invalid argument: not enough arguments for spawn, expected>=1, got=0"},
+		{"spawn(1)", "invalid argument: first argument for spawn must be a function. got=1 (INTEGER)"},
+		{"await(1)", "invalid argument: await expects a task. got=1 (INTEGER)"},
+		{"join([1])", "invalid argument: join expects an array of tasks. got=1 (INTEGER)"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestChannels(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let c = chan(1); send(c, 5); recv(c);", 5},
+		{`
+		let c = chan();
+		spawn(fn() { send(c, 1); send(c, 2); close(c); });
+		recv(c) + recv(c);
+		`, 3},
+		{"let c = chan(1); close(c); recv(c);", nil},
+		{`
+		let results = chan();
+		let worker = fn(x) { send(results, x * x) };
+		spawn(worker, 3);
+		spawn(worker, 4);
+		recv(results) + recv(results);
+		`, 25},
+		{`
+		let a = chan();
+		let b = chan(1);
+		send(b, 7);
+		let i, v = select([a, b]);
+		i * 10 + v;
+		`, 17},
+		{"let c = chan(1); close(c); send(c, 1);", "send on closed channel"},
+		{"let c = chan(); close(c); close(c);", "close of closed channel"},
+		{"chan(-1)", "invalid argument: chan size must be a non-negative integer. got=-1 (INTEGER)"},
+		{"recv(1)", "invalid argument: recv expects a channel. got=1 (INTEGER)"},
+		{"select([1])", "invalid argument: select expects an array of channels. got=1 (INTEGER)"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let g = fn() { yield 1; yield 2; }; let it = g(); next(it) * 10 + next(it);", 12},
+		{"let g = fn() { yield 1; }; let it = g(); next(it); next(it);", nil},
+		{"let g = fn() { yield 1; return 5; yield 2; }; let it = g(); next(it); next(it);", nil},
+		{"let g = fn(n) { yield n; yield n * 2; }; let it = g(4); next(it) + next(it);", 12},
+		// The body only runs as far as the values asked for
+		{"let g = fn() { yield 1; 1 + true; }; next(g());", 1},
+		{"let g = fn() { yield 1; 1 + true; }; let it = g(); next(it); next(it);", "type mismatch: INTEGER + BOOLEAN"},
+		{`
+		let c = chan(1);
+		send(c, 3);
+		let it = {"next": fn() { recv(c) }};
+		close(c);
+		next(it);
+		`, 3},
+		{"next([1])", "invalid argument: next expects an iterator. got=[1] (ARRAY)"},
+		{"let g = fn() { yield 1; }; g()", "generator"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if evaluated.Inspect() == expected {
+				continue
+			}
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	var out bytes.Buffer
+	program := parser.New(lexer.New(`
+	let squares = fn(xs) { for (x in xs) { yield x * x; } };
+	for (x in squares([1, 2, 3])) { print(x); }
+	for (k, v in {"a": 1, "b": 2}) { print(k, v); }
+	for (c in "hi") { print(c); }
+	let pair = fn() { return 1, true; };
+	for (x in pair()) { print(x); }
+	let first = fn(xs, pred) { for (x in xs) { if (pred(x)) { return x; } } };
+	first([1, 5, 7], x => x > 2);
+	`)).Parse()
+
+	evaluated := New(WithStdout(&out)).Eval(program, object.NewEnvironment())
+	testIntegerObject(t, evaluated, 5)
+
+	expected := "1\n4\n9\na 1\nb 2\nh\ni\n1\ntrue\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (x in 5) {}", "not iterable: 5 (INTEGER)"},
+		{"for (a, b in [1]) {}", "wrong number of values to unpack. got=1, want=2"},
+		{"for (x in [1, 2]) { x + true; }", "type mismatch: INTEGER + BOOLEAN"},
+		{"let g = fn() { yield 1; 1 + true; }; for (x in g()) {}", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tc.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tc.expected, errObj.Message)
+		}
+	}
+}
+
+func TestDoWhileStatements(t *testing.T) {
+	var out bytes.Buffer
+	program := parser.New(lexer.New(`
+	let it = range(1, 4);
+	do { let x = next(it); print(x); } while (x != 2);
+	do { print("once"); } while (false);
+	let find = fn(it, n) { do { let x = next(it); if (x == n) { return x; } } while (true) };
+	find(range(4, 7), 5);
+	`)).Parse()
+
+	evaluated := New(WithStdout(&out)).Eval(program, object.NewEnvironment())
+	testIntegerObject(t, evaluated, 5)
+
+	expected := "1\n2\nonce\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"do { 1 + true; } while (true)", "type mismatch: INTEGER + BOOLEAN"},
+		{"do { let x = 1; } while (x + true)", "type mismatch: INTEGER + BOOLEAN"},
+		// Bindings of the body don't outlive the loop
+		{"do { let x = 1; } while (false); x", "identifier not found: x"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tc.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tc.expected, errObj.Message)
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (2) { 1 => "one", 2 => "two", _ => "many" }`, "two"},
+		{`match (7) { 1 => "one", _ => "many" }`, "many"},
+		{`match (7) { 1 => "one" }`, "null"},
+		{`match (-1) { -1 => "minus one" }`, "minus one"},
+		{`match (1.0) { 1 => "one" }`, "one"},
+		{`match ("a") { "b" => 1, "a" => 2 }`, "2"},
+		{"match (5) { n => n * 2 }", "10"},
+		{"match ([1, 2, 3]) { [a, ...rest] => rest }", "[2, 3]"},
+		{"match ([1]) { [a, ...rest] => rest }", "[]"},
+		{"match ([]) { [a, ...rest] => 1, [] => 2 }", "2"},
+		{"match ([1, 2]) { [a] => 1, [a, b, c] => 3, [a, b] => a + b }", "3"},
+		{"match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }", "6"},
+		{"match ([1, 2]) { [_, ..._] => 1 }", "1"},
+		{`match ({"name": "Ada", "age": 36}) { {name, age} => name }`, "Ada"},
+		{`match ({"name": "Ada"}) { {name, age} => 1, {name} => 2 }`, "2"},
+		{`match ({"kind": "circle", "r": 2}) { {"kind": "square"} => 1, {"kind": "circle", "r": r} => r }`, "2"},
+		{`match ({1: true}) { {1: v} => v }`, "true"},
+		{"let f = fn() { return 1, 2; }; match (f()) { (a, b, c) => 0, (a, b) => a + b }", "3"},
+		{"match (3) { n if n > 5 => 1, n if n > 1 => 2, _ => 3 }", "2"},
+		{"match ([4, 1]) { [a, b] if a < b => a, [a, b] => b }", "1"},
+		{"match (2) { n => { let m = n * 10; m + 1 } }", "21"},
+		{"let n = 1; match (2) { n if false => n, _ => n }", "1"},
+		{"let count = fn(xs) { match (xs) { [] => 0, [_, ...rest] => 1 + count(rest) } }; count([1, 2, 3])", "3"},
+		{`match (1) { is Str => "str", is Int => "int" }`, "int"},
+		{`match ("a") { is Int as n => n + 1, is Str as s => s + "b" }`, "ab"},
+		{"match (2.5) { is Int => 1 }", "null"},
+		{"match ([1, 2.5]) { [is Int as a, is Float as b] => a + b }", "3.5"},
+		{"match (len) { is Fn => 1 }", "1"},
+		{"match ({}[1]) { is Null => 1 }", "1"},
+		{"match (Int) { is Type as t => t }", "Int"},
+		{"match (12) { is Int as n if n > 10 => 1, is Int => 2 }", "1"},
+		{"let T = typeof([]); match ([]) { is T => 1 }", "1"},
+		{"let Int = 1; match (1) { is Int => 1 }", "ERROR: invalid argument: 1 (INTEGER) is not a type"},
+		{"match (1) { is Nope => 1 }", "ERROR: identifier not found: Nope"},
+		{"match (1 + true) { _ => 1 }", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{"match (1) { n if n + true => 1 }", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	shapes := `let Shape = interface { area, perimeter };
+let square = fn(s) { {"area": fn() { s * s }, "perimeter": fn() { 4 * s }} };
+let describe = fn(shape) { if (implements(shape, Shape)) { shape["area"]() } else { 0 } };
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{shapes + "Shape", "interface { area, perimeter }"},
+		{shapes + "implements(square(2), Shape)", "true"},
+		{shapes + "describe(square(3))", "9"},
+		{shapes + `describe({"area": fn() { 1 }})`, "0"},
+		{shapes + `implements({"area": 1, "perimeter": 2}, Shape)`, "false"},
+		{shapes + `implements({"area": len, "perimeter": len}, Shape)`, "true"},
+		{shapes + "implements([1, 2], Shape)", "false"},
+		{"implements(1, interface {})", "false"},
+		{"implements({}, interface {})", "true"},
+		{`implements({}, {"area": 1})`, "ERROR: invalid argument: implements expects an interface. got={area: 1} (HASH)"},
+		{"implements({})", "ERROR: wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"quote(5)", "QUOTE(5)"},
+		{"quote(foobar + barfoo)", "QUOTE((foobar + barfoo))"},
+		{"quote(unquote(4 + 4))", "QUOTE(8)"},
+		{"quote(8 + unquote(4 + 4))", "QUOTE((8 + 8))"},
+		{"let x = 8; quote(unquote(x) + y)", "QUOTE((8 + y))"},
+		{"quote(unquote(1.5 > 1))", "QUOTE(true)"},
+		{`quote(unquote("a") + unquote([1, {"b": 2}]))`, "QUOTE((a + [1, {b: 2}]))"},
+		{"let q = quote(4 + 4); quote(unquote(4 + 4) + unquote(q))", "QUOTE((8 + (4 + 4)))"},
+		{"quote(unquote(len))", "ERROR: invalid argument: cannot unquote builtin function (BUILTIN)"},
+		{"quote(unquote(1 + true))", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{"quote(1, 2)", "ERROR: wrong number of arguments. got=2, want=1"},
+		{"unquote(1)", "ERROR: unquote outside of quote"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	unless := `let unless = macro(cond, consequence, alternative) {
+  quote(if (!(unquote(cond))) { unquote(consequence) } else { unquote(alternative) })
+};
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let m = macro() { quote(x) }; m()", "x"},
+		{"let plus = macro(a, b) { quote(unquote(a) + unquote(b)) }; plus(2 + 3, 1)", "((2 + 3) + 1)"},
+		{"let reverse = macro(a, b) { quote(unquote(b) - unquote(a)) }; reverse(2 + 2, 10 - 5)", "((10 - 5) - (2 + 2))"},
+		{unless + `unless(10 > 5, print("no"), print("yes"))`, `if (!(10 > 5)) print(no) else print(yes)`},
+		{unless + `[unless(true, 1, 2), unless(false, 1, 2)]`, "[if (!true) 1 else 2, if (!false) 1 else 2]"},
+		{"let twice = macro(e) { let n = 2; quote(unquote(e) * unquote(n)) }; twice(f(x))", "(f(x) * 2)"},
+	}
+
+	for _, tc := range tests {
+		p := parser.New(lexer.New(tc.input))
+		program := p.Parse()
+		if len(p.Errs()) != 0 {
+			t.Fatalf("parser errors for %s: %v", tc.input, p.Errs())
+		}
+
+		expanded, err := ExpandMacros(program, object.NewEnvironment())
+		if err != nil {
+			t.Errorf("ExpandMacros returned error for %s: %s", tc.input, err)
+			continue
+		}
+		if expanded.String() != tc.expected {
+			t.Errorf("wrong expansion for %s. expected=%q, got=%q", tc.input, tc.expected, expanded.String())
+		}
+	}
+}
+
+func TestMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let unless = macro(cond, body) { quote(if (!(unquote(cond))) { unquote(body) }) };
+let calls = fn(n) { unless(n > 1, "small") };
+[calls(1), calls(2)]`, "[small, null]"},
+		{"let m = macro(x) { x + 1 }; m(1)", "ERROR: type mismatch: QUOTE + INTEGER"},
+		{"let m = macro(x) { 1 }; m(2)", "ERROR: invalid macro: m must return a quote. got=1 (INTEGER)"},
+		{"let m = macro(x) { x }; m()", "ERROR: wrong number of arguments. got=0, want=1"},
+		{"let f = fn() { macro(x) { x } }; f()", "ERROR: macros can only be defined by let statements at the top level"},
+	}
+
+	for _, tc := range tests {
+		script, err := NewScript(tc.input)
+		var evaluated object.Object
+		if err != nil {
+			evaluated = err.(*object.Error)
+		} else {
+			evaluated, _ = script.Run(New())
+		}
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestWithBuiltins(t *testing.T) {
+	double := func(args ...object.Object) object.Object {
+		return &object.Integer{Value: args[0].(*object.Integer).Value * 2}
+	}
+	shout := func(args ...object.Object) object.Object {
+		return &object.String{Value: "LEN"}
+	}
+	in := New(WithBuiltins(map[string]object.BuiltinFn{"double": double}), WithBuiltins(map[string]object.BuiltinFn{"len": shout}))
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"double(21)", "42"},
+		{"collect(map(double, [1, 2]))", "[2, 4]"},
+		{`len("abc")`, "LEN"},
+	}
+
+	for _, tc := range tests {
+		p := parser.New(lexer.New(tc.input))
+		evaluated := in.Eval(p.Parse(), object.NewEnvironment())
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+
+	if _, err := LoadPlugin("testdata/missing.so"); err == nil {
+		t.Errorf("expected an error loading a missing plugin")
+	}
+}
+
+func TestGeneratorStop(t *testing.T) {
+	finished := make(chan bool)
+	gen := object.NewGenerator(func(yield func(object.Object) bool) object.Object {
+		for i := int64(0); ; i++ {
+			if !yield(newInteger(i)) {
+				finished <- true
+				return nil
+			}
+		}
+	})
+
+	for i := int64(0); i < 3; i++ {
+		val, ok := gen.Next()
+		if !ok {
+			t.Fatalf("generator ended early")
+		}
+		testIntegerObject(t, val, i)
+	}
+
+	gen.Stop()
+	<-finished
+	if _, ok := gen.Next(); ok {
+		t.Errorf("expected stopped generator to be exhausted")
+	}
+}
+
+// Bound generators keep their bodies reachable, so they are only stopped by
+// closing the interpreter
+func TestCloseStopsGenerators(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	in := New()
+	program := parser.New(lexer.New(`
+	let count = fn() { for (i in range(1000)) { yield i; } };
+	let gens = [];
+	for (i in range(100)) { let g = count(); next(g); global gens = append(gens, g); }
+	let late = count();
+	`)).Parse()
+	env := object.NewEnvironment()
+	in.Eval(program, env)
+
+	if running := runtime.NumGoroutine() - before; running < 100 {
+		t.Fatalf("expected generators to be running. got=%d goroutines", running)
+	}
+
+	in.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if leaked := runtime.NumGoroutine() - before; leaked > 0 {
+		t.Errorf("%d goroutines left running after Close", leaked)
+	}
+
+	// Generators started after closing end right away
+	late, _ := env.Get("late")
+	val, _ := late.(*object.Generator).Next()
+	if err, isErr := val.(*object.Error); !isErr || err.Message != ErrGeneratorStopped {
+		t.Errorf("expected generator started after Close to fail. got=%v", val)
+	}
+}
+
+func TestFuelLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		fuel     int64
+		expected any
+	}{
+		{"let loop = fn() { loop() }; loop();", 1000, "fuel exhausted: evaluation exceeded 1000 steps"},
+		{"let f = fn(n) { if (n == 0) { return 0; } f(n - 1) }; f(100000);", 5000, "fuel exhausted: evaluation exceeded 5000 steps"},
+		{"1 + 2", 3, "fuel exhausted: evaluation exceeded 3 steps"},
+		{"1 + 2", 5, 3},
+		{"1 + 2", 0, 3},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).Parse()
+		evaluated := New(WithFuel(tc.fuel)).Eval(program, object.NewEnvironment())
+
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestFuelIsSharedAcrossEvaluations(t *testing.T) {
+	in := New(WithFuel(10))
+	env := object.NewEnvironment()
+	program := parser.New(lexer.New("1 + 2")).Parse()
+
+	testIntegerObject(t, in.Eval(program, env), 3)
+	testIntegerObject(t, in.Eval(program, env), 3)
+	if in.FuelUsed() != 10 {
+		t.Errorf("wrong amount of fuel used. expected=%d, got=%d", 10, in.FuelUsed())
+	}
+
+	if _, isErr := in.Eval(program, env).(*object.Error); !isErr {
+		t.Errorf("expected evaluation to fail once fuel ran out")
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		limit    int64
+		expected any
+	}{
+		{
+			`let grow = fn(s) { grow(s + s) }; grow("abcdefgh");`,
+			1 << 16,
+			"memory limit exceeded: allocated more than 65536 bytes",
+		},
+		{
+			"let grow = fn(arr) { grow([...arr, ...arr]) }; grow([1]);",
+			1 << 16,
+			"memory limit exceeded: allocated more than 65536 bytes",
+		},
+		{
+			"let grow = fn(arr) { grow(append(arr, 1)) }; grow([]);",
+			1 << 12,
+			"memory limit exceeded: allocated more than 4096 bytes",
+		},
+		{
+			"let xs = [1, 2, 3, 4, 5, 6, 7, 8]; match (xs) { [x, ...rest] => x }",
+			160,
+			"memory limit exceeded: allocated more than 160 bytes",
+		},
+		{
+			"let fill = fn(h, i) { h[i] = i; fill(h, i + 1) }; fill({}, 0);",
+			1 << 12,
+			"memory limit exceeded: allocated more than 4096 bytes",
+		},
+		{"let h = {}; for (i in [1, 1, 1, 1]) { h[1] = i; } len(h)", 256, 1},
+		{`len("ab" + "cd")`, 1 << 10, 4},
+		{"len([1, 2, 3])", 1 << 10, 3},
+		{"len([1, 2, 3])", 0, 3},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).Parse()
+		evaluated := New(WithMemoryLimit(tc.limit)).Eval(program, object.NewEnvironment())
+
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestDeterministicMode(t *testing.T) {
+	first := New(WithDeterministic(42))
+	second := New(WithDeterministic(42))
+
+	for i := 0; i < 5; i++ {
+		var a, b int64
+		first.withRand(func(r *rand.Rand) { a = r.Int63() })
+		second.withRand(func(r *rand.Rand) { b = r.Int63() })
+		if a != b {
+			t.Fatalf("random sequences diverged at %d. got=%d and %d", i, a, b)
+		}
+	}
+
+	if !first.now().Equal(DeterministicEpoch) {
+		t.Errorf("clock is not fixed. expected=%s, got=%s", DeterministicEpoch, first.now())
+	}
+}
+
+func TestOSBuiltins(t *testing.T) {
+	t.Setenv("BASEDLANG_TEST", "based")
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"args()", "[-v, input.txt]"},
+		{`env("BASEDLANG_TEST")`, "based"},
+		{`env("BASEDLANG_UNSET")`, "null"},
+		{`set_env("BASEDLANG_TEST", "cringe"); env("BASEDLANG_TEST")`, "cringe"},
+		{"cwd()", dir},
+		{"env(1)", "ERROR: invalid argument: env expects a string name. got=1 (INTEGER)"},
+		{`set_env("BASEDLANG_TEST", 1)`, "ERROR: invalid argument: set_env expects a string value. got=1 (INTEGER)"},
+		{"args(1)", "ERROR: wrong number of arguments. got=1, want=0"},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).Parse()
+		in := New(WithArgs([]string{"-v", "input.txt"}))
+		evaluated := in.Eval(program, object.NewEnvironment())
+
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestExitBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"exit(); 1", "exit(0)"},
+		{"let f = fn() { exit(2); 1 }; [f(), 3]", "exit(2)"},
+		{"let f = fn() { if (true) { exit(1) } }; f() + 1", "exit(1)"},
+		{"await(spawn(fn() { exit(4) }))", "exit(4)"},
+		{`exit("1")`, "ERROR: invalid argument: exit expects an integer code. got=1 (STRING)"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		input    string
+		caps     Capability
+		expected string
+	}{
+		{"now()", CapNone, "ERROR: permission denied: now needs the time capability"},
+		{"now()", CapTime, "0"},
+		{`http_get("http://localhost")`, CapAll &^ CapNet, "ERROR: permission denied: http_get needs the net capability"},
+		{`env("HOME")`, CapFS | CapNet, "ERROR: permission denied: env needs the process capability"},
+		{"cwd()", CapTime, "ERROR: permission denied: cwd needs the fs capability"},
+		{"len([1, 2]) + rand_int(1)", CapNone, "2"},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).Parse()
+		in := New(WithCapabilities(tc.caps), WithDeterministic(1))
+		evaluated := in.Eval(program, object.NewEnvironment())
+
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+		if err, isErr := evaluated.(*object.Error); isErr && err.Kind != object.PermissionError {
+			t.Errorf("wrong error kind for %s. expected=%s, got=%s", tc.input, object.PermissionError, err.Kind)
+		}
+	}
+
+	if s := (CapFS | CapTime).String(); s != "fs|time" {
+		t.Errorf("wrong capability string. expected=%q, got=%q", "fs|time", s)
+	}
+}
+
+func TestExecBuiltin(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	tests := []struct {
+		input    string
+		caps     Capability
+		expected string
+	}{
+		{`exec("sh", "-c", "echo out; echo err >&2; exit 3")`, CapAll, "{stdout: out\n, stderr: err\n, code: 3}"},
+		{`exec("sh", "-c", "printf ok")["stdout"]`, CapAll, "ok"},
+		{`exec("basedlang-missing-command")`, CapAll, `ERROR: exec error: exec: "basedlang-missing-command": executable file not found in $PATH`},
+		{`exec("sh", 1)`, CapAll, "ERROR: invalid argument: exec expects a string command. got=1 (INTEGER)"},
+		{"exec()", CapAll, "ERROR: wrong number of arguments. got=0, want>=1"},
+		{`exec("sh", "-c", "true")`, CapNone, "ERROR: permission denied: exec needs the process capability"},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).Parse()
+		in := New(WithCapabilities(tc.caps))
+		evaluated := in.Eval(program, object.NewEnvironment())
+
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"abs(-3)", "3"},
+		{"abs(-2.5)", "2.5"},
+		{"min(3, 1, 2)", "1"},
+		{"max(3, 1, 2)", "3"},
+		{"min(2, 1.5)", "1.5"},
+		{"max(2, 1.5)", "2.0"},
+		{"max([4, 9, 2])", "9"},
+		{"sqrt(16)", "4.0"},
+		{"sqrt(2.25)", "1.5"},
+		{"pow(2, 10)", "1024"},
+		{"pow(2, -1)", "0.5"},
+		{"pow(2.0, 3)", "8.0"},
+		{"floor(2.7)", "2"},
+		{"floor(-2.5)", "-3"},
+		{"ceil(2.1)", "3"},
+		{"round(2.5)", "3"},
+		{"round(7)", "7"},
+		{"round(sqrt(-1))", "ERROR: invalid argument: round can't convert NaN to an integer"},
+		{`abs("1")`, "ERROR: invalid argument: abs expects a number argument. got=1 (STRING)"},
+		{`max(1, true)`, "ERROR: invalid argument: max expects a number argument. got=true (BOOLEAN)"},
+		{"min([])", "ERROR: wrong number of arguments. got=0, want>=1"},
+		{`pow(2, "x")`, "ERROR: invalid argument: pow expects a number exponent. got=x (STRING)"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestConversionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"int(3)", "3"},
+		{"int(3.9)", "3"},
+		{"int(-3.9)", "-3"},
+		{`int("42")`, "42"},
+		{`int("-7")`, "-7"},
+		{"int(true)", "1"},
+		{`int("abc")`, `ERROR: invalid argument: int can't parse "abc" as an integer`},
+		{`int("4.2")`, `ERROR: invalid argument: int can't parse "4.2" as an integer`},
+		{`int("99999999999999999999")`, `ERROR: invalid argument: int can't parse "99999999999999999999" as an integer`},
+		{"int(pow(2.0, 70))", "ERROR: invalid argument: int can't convert 1.1805916207174113e+21 to an integer"},
+		{"int([1])", "ERROR: invalid argument: int can't convert [1] (ARRAY)"},
+		{"float(2)", "2.0"},
+		{`float("2.5")`, "2.5"},
+		{`float("1e400")`, "+Inf"},
+		{"float(false)", "0.0"},
+		{`float("abc")`, `ERROR: invalid argument: float can't parse "abc" as a float`},
+		{"float(fn() {})", "ERROR: invalid argument: float can't convert fn() {\n\n} (FUNCTION)"},
+		{"str(42)", "42"},
+		{"str(2.5) + \"!\"", "2.5!"},
+		{`str("a")`, "a"},
+		{`str([1, "a"])`, "[1, a]"},
+		{"str(Int)", "Int"},
+		{`bool("true")`, "true"},
+		{`bool("false")`, "false"},
+		{"bool(0)", "true"},
+		{"bool(if (false) { 1 })", "false"},
+		{`bool("yes")`, `ERROR: invalid argument: bool can't parse "yes" as a boolean`},
+		{"int(1, 2)", "ERROR: wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+
+	err := testEval(`int("abc")`).(*object.Error)
+	if err.Kind != object.ValueError {
+		t.Errorf("wrong error kind. expected=%s, got=%s", object.ValueError, err.Kind)
+	}
+}
+
+func TestSequenceBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"collect(range(4))", "[0, 1, 2, 3]"},
+		{"collect(range(2, 5))", "[2, 3, 4]"},
+		{"collect(range(5, 0, -2))", "[5, 3, 1]"},
+		{"collect(range(3, 1))", "[]"},
+		{"collect(take(3, map(|x| x * x, range(0, 1000000000000))))", "[0, 1, 4]"},
+		{"collect(drop(2, [1, 2, 3]))", "[3]"},
+		{"collect(drop(5, range(3)))", "[]"},
+		{`collect(zip(range(10), "ab"))`, "[(0, a), (1, b)]"},
+		{"collect(take(4, iterate(|x| x * 3, 1)))", "[1, 3, 9, 27]"},
+		{"let g = fn() { yield 1; yield 2; }; collect(map(|x| -x, g()))", "[-1, -2]"},
+		{"let it = range(3); next(it); collect(it)", "[1, 2]"},
+		{"range(3)", "iterator"},
+		{"collect(map(|x| x + true, range(3)))", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{"range(1, 5, 0)", "ERROR: invalid argument: range step must not be zero"},
+		{`range("a")`, "ERROR: invalid argument: range expects an integer stop. got=a (STRING)"},
+		{"range()", "ERROR: wrong number of arguments. got=0, want>=1"},
+		{"take(-1, [1])", "ERROR: invalid argument: take expects a non-negative count. got=-1 (INTEGER)"},
+		{"drop(1, 2)", "ERROR: invalid argument: drop expects an iterable to drop from. got=2 (INTEGER)"},
+		{"map(1, [])", "ERROR: invalid argument: map expects a function to call. got=1 (INTEGER)"},
+		{"collect(5)", "ERROR: invalid argument: collect expects an iterable argument. got=5 (INTEGER)"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+
+	in := New(WithFuel(1000))
+	evaluated := in.Eval(parser.New(lexer.New("collect(iterate(|x| x, 1))")).Parse(), object.NewEnvironment())
+	if evaluated.Inspect() != "ERROR: fuel exhausted: evaluation exceeded 1000 steps" {
+		t.Errorf("expected collecting an endless iterator to run out of fuel. got=%q", evaluated.Inspect())
+	}
+}
+
+func TestRandomBuiltins(t *testing.T) {
+	input := `[rand_int(100), rand_float(), shuffle([1, 2, 3, 4, 5])]`
+
+	run := func(seed int64) string {
+		program := parser.New(lexer.New(input)).Parse()
+		return New(WithSeed(seed)).Eval(program, object.NewEnvironment()).Inspect()
+	}
+
+	if run(7) != run(7) {
+		t.Errorf("same seed gave different results. got=%s and %s", run(7), run(7))
+	}
+
+	checks := []struct {
+		input    string
+		expected string
+	}{
+		{"rand_int(0)", "ERROR: invalid argument: rand_int expects a positive integer bound. got=0 (INTEGER)"},
+		{"shuffle(1)", "ERROR: invalid argument: shuffle expects an array. got=1 (INTEGER)"},
+		{"rand_float(1)", "ERROR: wrong number of arguments. got=1, want=0"},
+		{"let a = [1, 2, 3]; shuffle(a); a", "[1, 2, 3]"},
+		{"len(shuffle([1, 2, 3]))", "3"},
+	}
+	for _, tc := range checks {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		n := testEval("rand_int(3)").(*object.Integer).Value
+		f := testEval("rand_float()").(*object.Float).Value
+		if n < 0 || n >= 3 || f < 0 || f >= 1 {
+			t.Fatalf("random values out of range. got=%d and %g", n, f)
+		}
+	}
+}
+
+func TestTypeof(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"typeof(1)", "Int"},
+		{"typeof(1.5)", "Float"},
+		{`typeof("a")`, "Str"},
+		{"typeof(true)", "Bool"},
+		{"typeof(if (false) { 1 })", "Null"},
+		{"typeof([1])", "Array"},
+		{"typeof({})", "Hash"},
+		{"typeof(fn() {})", "Fn"},
+		{"typeof(len)", "Fn"},
+		{"typeof(Int)", "Type"},
+		{"typeof(1) == Int", "true"},
+		{"typeof(1) == Float", "false"},
+		{"typeof(typeof) == typeof(fn(x) { x })", "true"},
+		{`typeof("Int") == Int`, "false"},
+		{`let describe = fn(x) { if (typeof(x) == Str) { x } else { "not a string" } }; describe(1)`, "not a string"},
+		// Type names are predeclared like builtins, so bindings can shadow them
+		{"let Int = 1; Int", "1"},
+		{"typeof()", "ERROR: wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDbg(t *testing.T) {
+	input := `let x = 1;
+let y = dbg(x+1) * 2;
+dbg( [x, (y)] );
+dbg((x  * 2) -
+  1)`
+
+	var stderr bytes.Buffer
+	program := parser.New(lexer.New(input)).Parse()
+	evaluated := New(WithStderr(&stderr)).Eval(program, object.NewEnvironment())
+
+	if evaluated.Inspect() != "1" {
+		t.Errorf("dbg didn't return its argument. got=%s", evaluated.Inspect())
+	}
+	// The code is printed as written
+	expected := "[2:9] x+1 = 2\n[3:1] [x, (y)] = [1, 4]\n[4:1] (x  * 2) -\n  1 = 1\n"
+	if stderr.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, stderr.String())
+	}
+
+	// Without the source, it's printed the way the parser understood it
+	stderr.Reset()
+	program = parser.New(lexer.NewReader(strings.NewReader("dbg(1+2)"))).Parse()
+	New(WithStderr(&stderr)).Eval(program, object.NewEnvironment())
+	if expected := "[1:1] (1 + 2) = 3\n"; stderr.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, stderr.String())
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"dbg(1, 2)", "ERROR: wrong number of arguments. got=2, want=1"},
+		{"dbg(1 + true)", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var out bytes.Buffer
+	program := parser.New(lexer.New(`print(1 + 2); print("a", [1, true]); print();`)).Parse()
+
+	evaluated := New(WithStdout(&out)).Eval(program, object.NewEnvironment())
+	testNullObject(t, evaluated)
+
+	expected := "3\na [1, true]\n\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestInput(t *testing.T) {
+	var out bytes.Buffer
+	stdin := strings.NewReader("Ada\r\nLovelace\nlast")
+	program := parser.New(lexer.New(`[input("first? "), input(), input(), input()]`)).Parse()
+
+	evaluated := New(WithStdin(stdin), WithStdout(&out)).Eval(program, object.NewEnvironment())
+
+	if evaluated.Inspect() != "[Ada, Lovelace, last, null]" {
+		t.Errorf("wrong result. expected=%q, got=%q", "[Ada, Lovelace, last, null]", evaluated.Inspect())
+	}
+	if out.String() != "first? " {
+		t.Errorf("wrong output. expected=%q, got=%q", "first? ", out.String())
+	}
+
+	evaluated = testEval("input(1)")
+	expected := "ERROR: invalid argument: input expects a string prompt. got=1 (INTEGER)"
+	if evaluated.Inspect() != expected {
+		t.Errorf("wrong result. expected=%q, got=%q", expected, evaluated.Inspect())
+	}
+}
+
+func TestHTTPBuiltins(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Echo", r.Header.Get("X-Name"))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.Path, body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`http_get(url + "/a")["body"]`, "GET /a "},
+		{`http_get(url)["status"]`, "201"},
+		{`http_request("post", url + "/b", {"X-Name": "x"}, "hi")["body"]`, "POST /b hi"},
+		{`http_request("PUT", url, {"X-Name": 42})["headers"]["X-Echo"]`, "42"},
+		{`http_get(1)`, "ERROR: invalid argument: http_get expects a string url. got=1 (INTEGER)"},
+		{`http_request("GET", url, [1])`, "ERROR: invalid argument: http_request expects a hash of headers. got=[1] (ARRAY)"},
+		{`http_request("GET")`, "ERROR: wrong number of arguments. got=1, want>=2"},
+	}
+
+	for _, tc := range tests {
+		env := object.NewEnvironment()
+		env.Set("url", &object.String{Value: server.URL})
+
+		program := parser.New(lexer.New(tc.input)).Parse()
+		evaluated := New(WithHTTPClient(server.Client())).Eval(program, env)
+
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestServeHandler(t *testing.T) {
+	in := New()
+	program := parser.New(lexer.New(`fn(req) {
+		let route = {"/fail": 1, "/error": 2}[req["path"]];
+		if (route == 1) { return 1; }
+		if (route == 2) { return 1 + true; }
+		{
+			"status": 202,
+			"headers": {"X-Method": req["method"]},
+			"body": req["path"] + " " + req["query"]["q"] + " " + req["body"] + " " + req["headers"]["X-Name"],
+		}
+	}`)).Parse()
+	handler := in.Eval(program, object.NewEnvironment())
+
+	server := httptest.NewServer(in.httpHandler(handler))
+	defer server.Close()
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		expected           string
+	}{
+		{"POST", "/a?q=1", "hi", 202, "/a 1 hi x"},
+		{"GET", "/fail", "", 500, "ERROR: invalid response: handler must return a hash. got=1 (INTEGER)\n"},
+		{"GET", "/error", "", 500, "ERROR: type mismatch: INTEGER + BOOLEAN\n"},
+	}
+
+	for _, tc := range tests {
+		req, _ := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+		req.Header.Set("X-Name", "x")
+
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %s", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.status {
+			t.Errorf("wrong status for %s. expected=%d, got=%d", tc.path, tc.status, resp.StatusCode)
+		}
+		if string(body) != tc.expected {
+			t.Errorf("wrong body for %s. expected=%q, got=%q", tc.path, tc.expected, body)
+		}
+		if tc.status == 202 && resp.Header.Get("X-Method") != tc.method {
+			t.Errorf("wrong X-Method header. got=%q", resp.Header.Get("X-Method"))
+		}
+	}
+}
+
+func TestRegexBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`regex_match("^a+b$", "aaab")`, "true"},
+		{`regex_match("^a+b$", "aaabc")`, "false"},
+		{`regex_find_all("[0-9]+", "a1 b22 c")`, "[{match: 1, index: 1, groups: [], named: {}}, {match: 22, index: 4, groups: [], named: {}}]"},
+		{`regex_find_all("(?P<key>[a-z]+)=([0-9])?", "a=1 b=")[1]`, "{match: b=, index: 4, groups: [b, null], named: {key: b}}"},
+		{`len(regex_find_all("x", "abc"))`, "0"},
+		{`regex_replace("(\w+)@(\w+)", "me@host", "$2 at $1")`, "host at me"},
+		{`regex_match("(", "a")`, "ERROR: invalid argument: error parsing regexp: missing closing ): `(`"},
+		{`regex_match(1, "a")`, "ERROR: invalid argument: regex_match expects a string pattern. got=1 (INTEGER)"},
+		{`regex_replace("a", "b", 1)`, "ERROR: invalid argument: regex_replace expects a string replacement. got=1 (INTEGER)"},
+		{`regex_match("a")`, "ERROR: wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTimeBuiltins(t *testing.T) {
+	clock := time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"now()", "1709296200000"},
+		{"format_time(now())", "2024-03-01T12:30:00Z"},
+		{`format_time(now() + 90 * 60 * 1000, "15:04")`, "14:00"},
+		{`parse_time("2024-03-01T12:30:00Z") == now()`, "true"},
+		{`parse_time("01/03/2024", "02/01/2006")`, "1709251200000"},
+		{"sleep(1)", "null"},
+		{`parse_time("yesterday")`, `ERROR: invalid argument: parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`},
+		{`format_time("now")`, "ERROR: invalid argument: format_time expects an integer time. got=now (STRING)"},
+		{`format_time(1, 2)`, "ERROR: invalid argument: format_time expects a string layout. got=2 (INTEGER)"},
+		{"sleep()", "ERROR: wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).Parse()
+		in := New(WithClock(func() time.Time { return clock }))
+		evaluated := in.Eval(program, object.NewEnvironment())
+
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + -4, true];"
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(result.Elems) != 4 {
+		t.Fatalf("incorrect number of elements. expected=%d, got=%d", 4, len(result.Elems))
+	}
+	testIntegerObject(t, result.Elems[0], 1)
+	testIntegerObject(t, result.Elems[1], 4)
+	testIntegerObject(t, result.Elems[2], -1)
+	testBooleanObject(t, result.Elems[3], true)
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{"one": 10 - 9, two: 1 + 1, "thr" + "ee": 6 / 2, 4: 4, true: 5, false: 6, "one": 7}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("object is not Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	// Setting a key twice keeps its first position
+	if result.Inspect() != "{one: 7, two: 2, three: 3, 4: 4, true: 5, false: 6}" {
+		t.Errorf("result.Inspect() wrong. got=%q", result.Inspect())
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   7,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                             5,
+		FALSE.HashKey():                            6,
+	}
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong number of pairs. got=%d", len(result.Pairs))
+	}
+	for key, value := range expected {
+		pair, ok := result.Pairs[key]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+			continue
+		}
+		testIntegerObject(t, pair.Value, value)
+	}
+}
+
+func TestHashSpread(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = {"x": 1, "y": 2}; {...a, "z": 3}`, "{x: 1, y: 2, z: 3}"},
+		{`let a = {"x": 1, "y": 2}; {"x": 0, ...a}`, "{x: 1, y: 2}"},
+		{`let a = {"x": 1, "y": 2}; {...a, "x": 0}`, "{x: 0, y: 2}"},
+		{`{...{}, ...{1: true}}`, "{1: true}"},
+		{`let named = fn(n) { {"name": fn() { n }} };
+let greeter = {"greet": fn(self) { "hi " + self["name"]() }};
+let bob = {...named("bob"), ...greeter};
+bob["greet"](bob)`, "hi bob"},
+		{`let a = {"x": 1}; let b = {...a, "y": 2}; a`, "{x: 1}"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`len({"a": 1, "b": 2})`, 2},
+		{`{"name": "x"}[fn(x) { x }]`, "invalid argument: fn(x) {\nx\n} (FUNCTION) is unusable as a hash key"},
+		{`{[1]: 1}`, "invalid argument: [1] (ARRAY) is unusable as a hash key"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestFunctionLiteral(t *testing.T) {
+	input := "fn(x) { x + 2; };"
+	evaluated := testEval(input)
+	fn, isFunc := evaluated.(*object.Function)
+	if !isFunc {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(fn.Params) != 1 {
+		t.Fatalf("incorrect number of params. expected=%d, got=%d (%+v)", 1, len(fn.Params), fn.Params)
+	}
+	if fn.Params[0].String() != "x" {
+		t.Fatalf("incorrect parameter. expected='%s' got=%q", "x", fn.Params[0])
+	}
+	expectedBody := "(x + 2)"
+	if fn.Body.String() != expectedBody {
+		t.Fatalf("incorrect function body. expected=%q got=%q", expectedBody, fn.Body.String())
+	}
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{
+			"[1, 2, 3][0]",
+			1,
+		},
+		{
+			"[1, 2, 3][1]",
+			2,
+		},
+		{
+			"[1, 2, 3][2]",
+			3,
+		},
+		{
+			"let i = 0; [1][i];",
+			1,
+		},
+		{
+			"[1, 2, 3][1 + 1];",
+			3,
+		},
+		{
+			"[1, 2, !false][1 + 1];",
+			true,
+		},
+		{
+			"let myArray = [1, 2, 3]; myArray[2];",
+			3,
+		},
+		{
+			"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];",
+			6,
+		},
+		{
+			"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]",
+			2,
+		},
+		{
+			"[1, 2, 3][3]",
+			nil},
+		{
+			"[1, 2, 3][-1]",
+			3,
+		},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		switch val := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(val))
+		case bool:
+			testBooleanObject(t, evaluated, val)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestArraySliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []int
+	}{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4][2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][-2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:-1]", []int{1, 2, 3}},
+		{"[1, 2, 3, 4][1:10]", []int{2, 3, 4}},
+		{"[1, 2, 3, 4][3:1]", []int{}},
+		{"let i = 1; [1, 2, 3, 4][i:i + 2]", []int{2, 3}},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		arr, isArr := evaluated.(*object.Array)
+		if !isArr {
+			t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if len(arr.Elems) != len(tc.expected) {
+			t.Errorf("incorrect number of elements for %q. expected=%d, got=%d",
+				tc.input, len(tc.expected), len(arr.Elems))
+			continue
+		}
+
+		for i, expectedElem := range tc.expected {
+			testIntegerObject(t, arr.Elems[i], int64(expectedElem))
+		}
+	}
+}
+
+func TestArraySliceReturnsNewArray(t *testing.T) {
+	input := "let a = [1, 2, 3]; let b = a[:]; let c = append(b, 4); a"
+	evaluated := testEval(input)
+	arr, isArr := evaluated.(*object.Array)
+	if !isArr {
+		t.Fatalf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(arr.Elems) != 3 {
+		t.Fatalf("original array was modified. got=%s", arr.Inspect())
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let identity = fn(x) { return x; }; identity(5);", 5},
+		{"let double = fn(x) { x * 2; }; double(5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"let add = fn(x, y) { x + y; }; add(5);", "wrong number of arguments. got=1, want=2"},
+		{"fn(x) { x * 3; }(5)", 15},
+		{`
+		let muller = fn(x) { fn(y) { x * y } };
+		let fiveMul = muller(5)
+		fiveMul(3)
+		`, 15},
+	}
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestVariadicFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let f = fn(rest...) { len(rest) }; f();", 0},
+		{"let f = fn(rest...) { len(rest) }; f(1, 2, 3);", 3},
+		{"let f = fn(x, rest...) { x + rest[0] }; f(1, 2);", 3},
+		{"let f = fn(x, y, rest...) { y + len(rest) }; f(1, 2);", 2},
+		{"let f = fn(x, y, rest...) { y }; f(1);", "wrong number of arguments. got=1, want>=2"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestAnnotatedFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let add = fn(x: int, y: int) -> int { x + y }; add(1, 2);", 3},
+		{"let f = fn(x: any, g: fn) -> any { g(x) }; f([1, 2], len);", 2},
+		{"let f = fn(rest: int...) -> int { len(rest) }; f(1, 2, 3);", 3},
+		{"let f = fn(x: int) { x }; f(true);", "type mismatch: argument x must be int. got=true (BOOLEAN)"},
+		{`let f = fn(x, rest: str...) { x }; f(1, "a", 2);`, "type mismatch: argument rest must be str. got=2 (INTEGER)"},
+		{`let f = fn(x) -> str { x }; f(1);`, "type mismatch: result must be str. got=1 (INTEGER)"},
+		{"let f = fn() -> int { return true; }; f();", "type mismatch: result must be int. got=true (BOOLEAN)"},
+		{"let f = fn(x) -> int { if (x) { 1 } }; f(false);", "type mismatch: result must be int. got=null (NULL)"},
+		{"let f = fn(x: num) { x }; f(1);", "unknown type: num"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestSpreadExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected any
 	}{
-		{`len("")`, 0},
-		{`len("four")`, 4},
-		{`len("hello world")`, 11},
-		{`len(1)`, "invalid argument: 1 (INTEGER) not supported for len"},
-		{`append([], 1)`, []int{1}},
-		{`append([], 1, 2)`, []int{1, 2}},
-		{`append()`, "invalid argument: not enough arguments for append, expected>=1, got=0"},
-		{`append(1, 2)`, "invalid argument: first argument for append must be an array. got=1 (INTEGER)"},
-		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
-		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{"let a = [2, 3]; [1, ...a, 4]", []int{1, 2, 3, 4}},
+		{"let a = []; [1, ...a]", []int{1}},
+		{"[...[1, 2][1:], ...[3]]", []int{2, 3}},
+		{"let add = fn(x, y) { x + y }; let a = [1, 2]; add(...a)", 3},
+		{"let add = fn(x, y, z) { x + y + z }; add(1, ...[2, 3])", 6},
+		{"let f = fn(rest...) { rest }; f(...[1, 2], 3)", []int{1, 2, 3}},
+		{"[...1]", "invalid argument: cannot spread 1 (INTEGER), expected an array"},
+		{"len(...[1, 2])", "wrong number of arguments. got=2, want=1"},
+		{`{...[1]}`, "invalid argument: cannot spread [1] (ARRAY), expected a hash"},
 	}
 
 	for _, tc := range tests {
@@ -119,13 +1976,10 @@ func TestBuiltInFunctions(t *testing.T) {
 				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
 				continue
 			}
-
 			if len(arr.Elems) != len(expected) {
-				t.Errorf("incorrect number of elements. expected=%d, got=%d",
-					len(expected), len(arr.Elems))
+				t.Errorf("incorrect number of elements. expected=%d, got=%d", len(expected), len(arr.Elems))
 				continue
 			}
-
 			for i, expectedElem := range expected {
 				testIntegerObject(t, arr.Elems[i], int64(expectedElem))
 			}
@@ -133,123 +1987,81 @@ func TestBuiltInFunctions(t *testing.T) {
 	}
 }
 
-func TestArrayLiterals(t *testing.T) {
-	input := "[1, 2 * 2, 3 + -4, true];"
-	evaluated := testEval(input)
-	result, ok := evaluated.(*object.Array)
-	if !ok {
-		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+func TestArrowFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let double = |x| x * 2; double(5);", 10},
+		{"let add = |x, y| x + y; add(2, 3);", 5},
+		{"let double = x => x * 2; double(5);", 10},
+		{"let adder = x => y => x + y; adder(2)(3);", 5},
+		{"let apply = fn(f, x) { f(x) }; apply(|x| x - 1, 5);", 4},
+		{"(|| 7)()", 7},
 	}
-	if len(result.Elems) != 4 {
-		t.Fatalf("incorrect number of elements. expected=%d, got=%d", 4, len(result.Elems))
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		testIntegerObject(t, evaluated, tc.expected)
 	}
-	testIntegerObject(t, result.Elems[0], 1)
-	testIntegerObject(t, result.Elems[1], 4)
-	testIntegerObject(t, result.Elems[2], -1)
-	testBooleanObject(t, result.Elems[3], true)
 }
 
-func TestFunctionLiteral(t *testing.T) {
-	input := "fn(x) { x + 2; };"
-	evaluated := testEval(input)
-	fn, isFunc := evaluated.(*object.Function)
-	if !isFunc {
-		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
-	}
-	if len(fn.Params) != 1 {
-		t.Fatalf("incorrect number of params. expected=%d, got=%d (%+v)", 1, len(fn.Params), fn.Params)
-	}
-	if fn.Params[0].String() != "x" {
-		t.Fatalf("incorrect parameter. expected='%s' got=%q", "x", fn.Params[0])
+func TestPipelineExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let double = |x| x * 2; 5 |> double", 10},
+		{"let sub = |x, y| x - y; 5 |> sub(2)", 3},
+		{"let inc = |x| x + 1; let double = |x| x * 2; 1 |> inc |> double |> inc", 5},
+		{"[1, 2, 3] |> len", 3},
+		{"[1, 2] |> append(3) |> len", 3},
 	}
-	expectedBody := "(x + 2)"
-	if fn.Body.String() != expectedBody {
-		t.Fatalf("incorrect function body. expected=%q got=%q", expectedBody, fn.Body.String())
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		testIntegerObject(t, evaluated, tc.expected)
 	}
 }
 
-func TestArrayIndexExpressions(t *testing.T) {
+func TestCachedIntegersAreNotMutated(t *testing.T) {
+	evaluated := testEval("let a = 5; let b = -a; a + b + 5;")
+	testIntegerObject(t, evaluated, 5)
+
+	evaluated = testEval("let a = 5; -a; a;")
+	testIntegerObject(t, evaluated, 5)
+}
+
+func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected any
+		expected int64
 	}{
-		{
-			"[1, 2, 3][0]",
-			1,
-		},
-		{
-			"[1, 2, 3][1]",
-			2,
-		},
-		{
-			"[1, 2, 3][2]",
-			3,
-		},
-		{
-			"let i = 0; [1][i];",
-			1,
-		},
-		{
-			"[1, 2, 3][1 + 1];",
-			3,
-		},
-		{
-			"[1, 2, !false][1 + 1];",
-			true,
-		},
-		{
-			"let myArray = [1, 2, 3]; myArray[2];",
-			3,
-		},
-		{
-			"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];",
-			6,
-		},
-		{
-			"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]",
-			2,
-		},
-		{
-			"[1, 2, 3][3]",
-			nil},
-		{
-			"[1, 2, 3][-1]",
-			3,
-		},
+		{"let a = 5; a;", 5},
+		{"let a = 5 * 5; a;", 25},
+		{"let a = 5; let b = a; b;", 5},
+		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
 	}
 
 	for _, tc := range tests {
 		evaluated := testEval(tc.input)
-
-		switch val := tc.expected.(type) {
-		case int:
-			testIntegerObject(t, evaluated, int64(val))
-		case bool:
-			testBooleanObject(t, evaluated, val)
-		default:
-			testNullObject(t, evaluated)
-		}
+		testIntegerObject(t, evaluated, tc.expected)
 	}
 }
 
-func TestFunctionApplication(t *testing.T) {
+func TestMultipleReturnValues(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected any
 	}{
-		{"let identity = fn(x) { x; }; identity(5);", 5},
-		{"let identity = fn(x) { return x; }; identity(5);", 5},
-		{"let double = fn(x) { x * 2; }; double(5);", 10},
-		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
-		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
-		{"let add = fn(x, y) { x + y; }; add(5);", "wrong number of arguments. got=1, want=2"},
-		{"fn(x) { x * 3; }(5)", 15},
-		{`
-		let muller = fn(x) { fn(y) { x * y } };
-		let fiveMul = muller(5)
-		fiveMul(3)
-		`, 15},
+		{"let f = fn() { return 1, 2; }; let x, y = f(); x;", 1},
+		{"let f = fn() { return 1, 2; }; let x, y = f(); y;", 2},
+		{"let divmod = fn(a, b) { return a // b, a - a // b * b; }; let q, r = divmod(7, 2); q * 10 + r;", 31},
+		{"let f = fn() { return 1, 2; }; let x, y, z = f();", "wrong number of values to unpack. got=2, want=3"},
+		{"let x, y = 5;", "wrong number of values to unpack. got=1, want=2"},
+		{"let f = fn() { return 1, foobar; }; f();", "identifier not found: foobar"},
 	}
+
 	for _, tc := range tests {
 		evaluated := testEval(tc.input)
 		switch expected := tc.expected.(type) {
@@ -268,21 +2080,17 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
-func TestLetStatements(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected int64
-	}{
-		{"let a = 5; a;", 5},
-		{"let a = 5 * 5; a;", 25},
-		{"let a = 5; let b = a; b;", 5},
-		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+func TestTupleValue(t *testing.T) {
+	evaluated := testEval("let f = fn() { return 1, true; }; f();")
+	tuple, isTuple := evaluated.(*object.Tuple)
+	if !isTuple {
+		t.Fatalf("object is not Tuple. got=%T (%+v)", evaluated, evaluated)
 	}
-
-	for _, tc := range tests {
-		evaluated := testEval(tc.input)
-		testIntegerObject(t, evaluated, tc.expected)
+	if len(tuple.Elems) != 2 {
+		t.Fatalf("incorrect number of elements. expected=%d, got=%d", 2, len(tuple.Elems))
 	}
+	testIntegerObject(t, tuple.Elems[0], 1)
+	testBooleanObject(t, tuple.Elems[1], true)
 }
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -313,6 +2121,47 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2.5", "2.5"},
+		{"-2.5", "-2.5"},
+		{"1.5 + 1.5", "3.0"},
+		{"1 + 0.5", "1.5"},
+		{"7 / 2.0", "3.5"},
+		{"2.0 * 3 - 1", "5.0"},
+		{"1.0 / 0", "+Inf"},
+		// Integers that don't divide evenly result in floats
+		{"7 / 2", "3.5"},
+		{"-1 / 4", "-0.25"},
+		{"6 / 3", "2"},
+		{"-6 / 3", "-2"},
+		// While // rounds down to a whole number
+		{"7 // 2", "3"},
+		{"-7 // 2", "-4"},
+		{"7 // -2", "-4"},
+		{"-7 // -2", "3"},
+		{"6 // -3", "-2"},
+		{"7.5 // 2", "3.0"},
+		{"-7 // 2.0", "-4.0"},
+		{"1 // 0", "ERROR: division by zero"},
+		{"1.0 // 0", "+Inf"},
+		{"1 < 1.5", "true"},
+		{"2.0 == 2", "true"},
+		{"!0.0", "true"},
+		{"1.5 + true", "ERROR: type mismatch: FLOAT + BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -334,6 +2183,18 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, [2, \"a\"]] == [1, [2, \"a\"]]", true},
+		{"[1, 2] == [2, 1]", false},
+		{"[1] == [1.0]", true},
+		{"{\"a\": 1, \"b\": [2]} == {\"b\": [2], \"a\": 1}", true},
+		{"{\"a\": 1} == {\"a\": 2}", false},
+		{"{\"a\": 1} != {}", true},
+		{"let a = [1]; a == a", true},
+		{"[] == {}", false},
+		{"[if (false) { 1 }] == [if (false) { 2 }]", true},
+		{"fn() {} == fn() {}", false},
 	}
 
 	for _, tc := range tests {
@@ -354,6 +2215,20 @@ func TestEvalString(t *testing.T) {
 	}
 }
 
+func TestStringLiteralsAreInterned(t *testing.T) {
+	evaluated := testEval(`let a = "key"; let b = "key"; [a, b, "key" + ""]`)
+	arr, isArr := evaluated.(*object.Array)
+	if !isArr {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if arr.Elems[0] != arr.Elems[1] {
+		t.Errorf("equal string literals do not share the same object")
+	}
+	if arr.Elems[0] == arr.Elems[2] {
+		t.Errorf("computed string was unexpectedly interned")
+	}
+}
+
 func TestStringConcatenation(t *testing.T) {
 	input := `"Hello" + " " + "World!"`
 	evaluated := testEval(input)
@@ -366,6 +2241,164 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" < "b"`, true},
+		{`"b" < "a"`, false},
+		{`"abc" < "abd"`, true},
+		{`"ab" < "abc"`, true},
+		{`"" < "a"`, true},
+		{`"Z" < "a"`, true},
+		{`"a" <= "a"`, true},
+		{`"b" <= "a"`, false},
+		{`"b" > "a"`, true},
+		{`"a" > "a"`, false},
+		{`"a" >= "a"`, true},
+		{`"a" >= "b"`, false},
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"a" != "a"`, false},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		testBooleanObject(t, evaluated, tc.expected)
+	}
+}
+
+func TestIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let xs = [1, 2, 3]; xs[0] = 10; xs", "[10, 2, 3]"},
+		{"let xs = [1, 2, 3]; xs[-1] = 30; xs", "[1, 2, 30]"},
+		{"let grid = [[0, 0], [0, 0]]; grid[1][0] = 1; grid", "[[0, 0], [1, 0]]"},
+		{`let h = {"a": 1}; h["a"] = 2; h["b"] = 3; h`, "{a: 2, b: 3}"},
+		{"let h = {}; h[true] = 1; h[2] = 2; h", "{true: 1, 2: 2}"},
+		// Everything referring to the same array sees the change
+		{"let xs = [1]; let f = fn(ys) { ys[0] = 2 }; f(xs); xs", "[2]"},
+		{"let xs = [1, 2]; let ys = xs; ys[0] = 0; xs", "[0, 2]"},
+		// But arrays appended to the same one don't share elements
+		{"let xs = append([1], 2); let a = append(xs, 3); let b = append(xs, 4); a[0] = 0; [xs, a, b]", "[[1, 2], [0, 2, 3], [1, 2, 4]]"},
+		{"let xs = [1, 2]; let s = xs[:]; s[0] = 0; xs", "[1, 2]"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"let xs = [1, 2]; xs[2] = 0", "invalid argument: index 2 out of range for length 2"},
+		{"let xs = [1, 2]; xs[-3] = 0", "invalid argument: index -3 out of range for length 2"},
+		{"let xs = []; xs[0] = 0", "invalid argument: index 0 out of range for length 0"},
+		{`let xs = [1]; xs["a"] = 0`, "invalid argument: index a (STRING) is not an integer"},
+		{"let h = {}; h[[1]] = 0", "invalid argument: [1] (ARRAY) is unusable as a hash key"},
+		{`let s = "ab"; s[0] = "c"`, "unsupported operator: index assignment not supported on ab (STRING)"},
+		{"let n = 1; n[0] = 0", "unsupported operator: index assignment not supported on 1 (INTEGER)"},
+		{"let xs = [1]; xs[0] = 1 + true", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range errors {
+		err, isErr := testEval(tc.input).(*object.Error)
+		if !isErr {
+			t.Errorf("no error for %q", tc.input)
+			continue
+		}
+		if err.Message != tc.expected {
+			t.Errorf("wrong message for %q. expected=%q, got=%q", tc.input, tc.expected, err.Message)
+		}
+	}
+}
+
+// Tasks share arrays and hashes, so this is best run with the race detector
+func TestConcurrentIndexAssignment(t *testing.T) {
+	input := `
+	let h = {};
+	let xs = [0, 0, 0, 0];
+	let work = fn(i) {
+		for (j in range(100)) {
+			h[i * 100 + j] = xs[3 - i];
+			xs[i] = xs[i] + 1;
+		}
+	};
+	join([spawn(work, 0), spawn(work, 1), spawn(work, 2), spawn(work, 3)]);
+	[len(h), xs]
+	`
+	expected := "[400, [100, 100, 100, 100]]"
+
+	if evaluated := testEval(input); evaluated.Inspect() != expected {
+		t.Errorf("wrong result. expected=%q, got=%q", expected, evaluated.Inspect())
+	}
+}
+
+func TestConditionalAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let h = {"a": 1, "b": false}; h["a"] ||= 2; h["b"] ||= 3; h["c"] ||= 4; h`, "{a: 1, b: 3, c: 4}"},
+		{`let h = {"a": 0, "b": false}; h["a"] ??= 2; h["b"] ??= 3; h["c"] ??= 4; h`, "{a: 0, b: false, c: 4}"},
+		{"let xs = [false, 1]; xs[0] ||= 2; xs[1] ||= 3; xs", "[2, 1]"},
+		{`let counts = {}; for (w in ["a", "b", "a"]) { counts[w] ??= 0; counts[w] = counts[w] + 1; } counts`, "{a: 2, b: 1}"},
+		// The value is only evaluated when it's assigned
+		{`let calls = [0]; let f = fn() { calls[0] = calls[0] + 1; 5 }; let h = {"a": 1}; h["a"] ||= f(); h["a"] ??= f(); h["b"] ??= f(); [h, calls]`, "[{a: 1, b: 5}, [1]]"},
+		// The target is evaluated once
+		{`let n = [0]; let next = fn() { n[0] = n[0] + 1; n[0] }; let h = {}; h[next()] ??= "a"; h[next()] ||= "b"; [h, n]`, "[{1: a, 2: b}, [2]]"},
+		{`let n = [0]; let xs = [false, false]; let at = fn(ys) { n[0] = n[0] + 1; ys }; at(xs)[0] ||= 1; [xs, n]`, "[[1, false], [1]]"},
+		// Whatever names scripts bind
+		{`let Null = 5; let typeof = fn(x) { Null }; let h = {}; h["a"] ??= 1; h["b"] ||= 2; h`, "{a: 1, b: 2}"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStringBuilder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let sb = builder(); write(sb, "a", "b"); write(sb, "c"); str(sb)`, "abc"},
+		{`str(builder("x = ", 1, ", ys = ", [true, 2.5]))`, "x = 1, ys = [true, 2.5]"},
+		{`let sb = builder(); for (i in range(3)) { write(sb, i, ","); } str(sb)`, "0,1,2,"},
+		{`str(write(write(builder(), "a"), "b"))`, "ab"},
+		{`len(builder("héllo"))`, "6"},
+		{`typeof(builder()) == StringBuilder`, "true"},
+		{`str(builder()) == ""`, "true"},
+		{`write("a", "b")`, "ERROR: invalid argument: write expects a string builder. got=a (STRING)"},
+		{`write()`, "ERROR: wrong number of arguments. got=0, want>=1"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+
+	// Writes count towards the memory limit like the strings they would make
+	program := parser.New(lexer.New(`let sb = builder(); for (i in range(100)) { write(sb, "0123456789"); }`)).Parse()
+	res := New(WithMemoryLimit(500)).Eval(program, object.NewEnvironment())
+	if err, isErr := res.(*object.Error); !isErr || err.Kind != object.LimitError {
+		t.Errorf("expected a limit error. got=%s", res.Inspect())
+	}
+}
+
 func TestIfElseExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -464,6 +2497,14 @@ func TestReturnStatements(t *testing.T) {
 			testNullObject(t, evaluated)
 		}
 	}
+
+	// A return value bound by a let stays intact across evaluations
+	env := object.NewEnvironment()
+	in := New()
+	in.Eval(parser.New(lexer.New("let x = if (true) { return 5; };")).Parse(), env)
+	for i := 0; i < 3; i++ {
+		testIntegerObject(t, in.Eval(parser.New(lexer.New("x")).Parse(), env), 5)
+	}
 }
 
 func TestBangOperator(t *testing.T) {
@@ -526,3 +2567,61 @@ func testEval(input string) object.Object {
 
 	return Eval(program, env)
 }
+
+func BenchmarkFibonacci(b *testing.B) {
+	benchmarkEval(b, `
+	let fib = fn(n) {
+		if (n < 2) { return n; }
+		return fib(n - 1) + fib(n - 2);
+	};
+	fib(20);
+	`)
+}
+
+func BenchmarkCountdown(b *testing.B) {
+	benchmarkEval(b, `
+	let countdown = fn(n, acc) {
+		if (n == 0) { return acc; }
+		return countdown(n - 1, acc + n * 2 - n);
+	};
+	countdown(2000, 0);
+	`)
+}
+
+func BenchmarkArraySum(b *testing.B) {
+	benchmarkEval(b, `
+	let sum = fn(arr, i, acc) {
+		if (i == len(arr)) { return acc; }
+		return sum(arr, i + 1, acc + arr[i]);
+	};
+	sum([1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16], 0, 0);
+	`)
+}
+
+// Concatenating copies the string built so far every time, which is quadratic
+// in the number of parts, while a builder appends in place
+func BenchmarkStringConcat(b *testing.B) {
+	benchmarkEval(b, `
+	let s = "";
+	for (i in range(2000)) { global s = s + "part"; }
+	`)
+}
+
+func BenchmarkStringBuilder(b *testing.B) {
+	benchmarkEval(b, `
+	let sb = builder();
+	for (i in range(2000)) { write(sb, "part"); }
+	str(sb);
+	`)
+}
+
+func benchmarkEval(b *testing.B, input string) {
+	program := parser.New(lexer.New(input)).Parse()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Eval(program, object.NewEnvironment())
+	}
+}
@@ -0,0 +1,403 @@
+package evaluator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/coverage"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/profiler"
+)
+
+const (
+	ErrFuelExhausted       = "fuel exhausted: evaluation exceeded %d steps"
+	ErrMemoryLimitExceeded = "memory limit exceeded: allocated more than %d bytes"
+)
+
+// Rough sizes used to account for the memory taken by created objects
+const (
+	objectSize = 16 // the object itself along with the interface pointing at it
+	elemSize   = 16 // a single slot in an array or tuple
+)
+
+// Interpreter holds the configuration and bookkeeping of evaluating programs.
+// A single interpreter can evaluate many programs and is shared by the tasks
+// they spawn.
+type Interpreter struct {
+	builtins map[string]*object.Builtin
+	// Builtins provided by the host, like those loaded from plugins
+	extraBuiltins map[string]object.BuiltinFn
+
+	// Fuel budgets how many nodes may be evaluated, 0 means unlimited
+	maxFuel  int64
+	fuelUsed atomic.Int64
+
+	// Approximate bytes of strings, arrays and hashes that may be created, 0
+	// means unlimited
+	maxMemory  int64
+	memoryUsed atomic.Int64
+
+	// Sources of nondeterminism, which builtins must go through so that they can
+	// be pinned by WithSeed and WithClock
+	randMu sync.Mutex
+	random *rand.Rand
+	clock  func() time.Time
+
+	coverage *coverage.Profile
+
+	profile   *profiler.Profile
+	mainFrame *frame
+
+	// Where every evaluated node is written along with its result, if anywhere
+	traceMu sync.Mutex
+	trace   io.Writer
+
+	stdout     io.Writer
+	stderr     io.Writer
+	httpClient *http.Client
+
+	// Where input reads lines from, shared by tasks so lines aren't read twice
+	stdinMu sync.Mutex
+	stdin   *bufio.Reader
+
+	// Strict mode turns questionable behaviors into errors
+	strict bool
+
+	// Capabilities builtins are allowed to use
+	caps Capability
+
+	// Arguments the script was invoked with, returned by args()
+	args []string
+
+	// Generators whose bodies are running, which Close stops
+	genMu      sync.Mutex
+	generators map[*object.Generator]struct{}
+	closed     bool
+}
+
+type Option func(in *Interpreter)
+
+// WithFuel limits the interpreter to evaluating at most steps nodes over its
+// lifetime. Evaluation past that aborts with a fuel exhausted error, which
+// keeps untrusted scripts from running forever.
+func WithFuel(steps int64) Option {
+	return func(in *Interpreter) {
+		in.maxFuel = steps
+	}
+}
+
+// WithMemoryLimit limits the approximate number of bytes taken by the strings,
+// arrays, tuples and hashes created over the interpreter's lifetime. Creating objects
+// past that aborts with a memory limit error, protecting the host from scripts
+// that build huge values.
+func WithMemoryLimit(bytes int64) Option {
+	return func(in *Interpreter) {
+		in.maxMemory = bytes
+	}
+}
+
+// WithSeed seeds the random number generator used by builtins
+func WithSeed(seed int64) Option {
+	return func(in *Interpreter) {
+		in.random = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithClock replaces the clock used by builtins that read the current time
+func WithClock(clock func() time.Time) Option {
+	return func(in *Interpreter) {
+		in.clock = clock
+	}
+}
+
+// DeterministicEpoch is the time the clock is fixed at in deterministic mode
+var DeterministicEpoch = time.Unix(0, 0).UTC()
+
+// WithDeterministic makes the same script always produce the same output by
+// seeding random builtins with seed and fixing the clock at DeterministicEpoch.
+// Values without an inherent order, like hashes, are always iterated in
+// insertion order so they need no pinning.
+func WithDeterministic(seed int64) Option {
+	return func(in *Interpreter) {
+		WithSeed(seed)(in)
+		WithClock(func() time.Time { return DeterministicEpoch })(in)
+	}
+}
+
+// WithStdout redirects what scripts print, which goes to os.Stdout by default
+func WithStdout(w io.Writer) Option {
+	return func(in *Interpreter) {
+		in.stdout = w
+	}
+}
+
+// WithStderr redirects what dbg prints, which goes to os.Stderr by default
+func WithStderr(w io.Writer) Option {
+	return func(in *Interpreter) {
+		in.stderr = w
+	}
+}
+
+// WithStdin sets where scripts read input from, which is os.Stdin by default
+func WithStdin(r io.Reader) Option {
+	return func(in *Interpreter) {
+		in.stdin = bufio.NewReader(r)
+	}
+}
+
+// WithHTTPClient sets the client the HTTP builtins send requests with, which
+// is http.DefaultClient by default
+func WithHTTPClient(client *http.Client) Option {
+	return func(in *Interpreter) {
+		in.httpClient = client
+	}
+}
+
+// WithStrict turns questionable behaviors into errors. Negating anything but a
+// boolean with ! is a type error rather than treating zero as falsy, and
+// integer arithmetic that overflows is an overflow error rather than wrapping
+// around.
+func WithStrict() Option {
+	return func(in *Interpreter) {
+		in.strict = true
+	}
+}
+
+// WithCapabilities only allows builtins to use caps, which is CapAll by
+// default. Builtins needing a capability outside caps fail with a permission
+// error.
+func WithCapabilities(caps Capability) Option {
+	return func(in *Interpreter) {
+		in.caps = caps
+	}
+}
+
+// WithArgs sets the arguments scripts read with args(), which are empty by
+// default
+func WithArgs(args []string) Option {
+	return func(in *Interpreter) {
+		in.args = args
+	}
+}
+
+// WithCoverage records every statement the interpreter executes into profile
+func WithCoverage(profile *coverage.Profile) Option {
+	return func(in *Interpreter) {
+		in.coverage = profile
+	}
+}
+
+// WithTrace writes every node the interpreter evaluates to w along with its
+// result, innermost nodes first, for debugging the language itself
+func WithTrace(w io.Writer) Option {
+	return func(in *Interpreter) {
+		in.trace = w
+	}
+}
+
+// traceNode writes a line to the trace for n having evaluated to res. Programs
+// are left out since they are the whole script, and statements without a value
+// only show their code. Results are kept to the line, so functions have the
+// line breaks of their bodies replaced with spaces.
+func (in *Interpreter) traceNode(n ast.Node, res object.Object) {
+	if _, isProgram := n.(*ast.Program); isProgram {
+		return
+	}
+
+	pos := n.Pos()
+	line := fmt.Sprintf("%d:%d: %s", pos.Line, pos.Column, n.String())
+	if res != nil {
+		line += " => " + strings.ReplaceAll(res.Inspect(), "\n", " ")
+	}
+
+	// Spawned tasks evaluate concurrently
+	in.traceMu.Lock()
+	defer in.traceMu.Unlock()
+	fmt.Fprintln(in.trace, line)
+}
+
+func New(opts ...Option) *Interpreter {
+	in := &Interpreter{
+		random:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		clock:      time.Now,
+		stdout:     os.Stdout,
+		stderr:     os.Stderr,
+		stdin:      bufio.NewReader(os.Stdin),
+		httpClient: http.DefaultClient,
+		caps:       CapAll,
+	}
+
+	for _, opt := range opts {
+		opt(in)
+	}
+
+	in.builtins = make(map[string]*object.Builtin, len(builtins))
+	for name, fn := range builtins {
+		fn := fn
+		if needs := builtinCapabilities[name]; !in.allowed(needs) {
+			fn = disabledBuiltin(name, needs&^in.caps)
+		}
+		in.builtins[name] = &object.Builtin{
+			Fn: func(args ...object.Object) object.Object {
+				return fn(in, args...)
+			},
+		}
+	}
+	for name, fn := range in.extraBuiltins {
+		in.builtins[name] = &object.Builtin{Fn: fn}
+	}
+
+	return in
+}
+
+// Eval evaluates n with a fresh interpreter using the default options
+func Eval(n ast.Node, env *object.Environment) object.Object {
+	in := New()
+	defer in.Close()
+	return in.Eval(n, env)
+}
+
+// Close stops the bodies of the generators created by the interpreter that
+// haven't finished, which would otherwise keep their goroutines for the life of
+// the process, since what they yield to keeps them reachable. Generators asked
+// for values afterwards end right away. Hosts should close interpreters once
+// they're done evaluating with them.
+func (in *Interpreter) Close() {
+	in.genMu.Lock()
+	in.closed = true
+	running := make([]*object.Generator, 0, len(in.generators))
+	for g := range in.generators {
+		running = append(running, g)
+	}
+	in.genMu.Unlock()
+
+	// Stopping waits for generators being asked for values, whose bodies may
+	// need genMu to finish
+	for _, g := range running {
+		g.Stop()
+	}
+}
+
+// Call calls fn, a function or builtin, with args just like a script calling it
+// would, so hosts can use basedlang functions as callbacks. It returns an error
+// object when fn isn't callable or the call fails.
+func (in *Interpreter) Call(fn object.Object, args ...object.Object) object.Object {
+	return in.applyFunction(fn, args)
+}
+
+// CallByName calls the function bound to name in env, which may also be the
+// name of a builtin
+func (in *Interpreter) CallByName(env *object.Environment, name string, args ...object.Object) object.Object {
+	fn, exists := env.Get(name)
+	if !exists {
+		builtin, isBuiltin := in.builtins[name]
+		if !isBuiltin {
+			return newError(object.NameError, ErrIdentifierNotFound, name)
+		}
+		fn = builtin
+	}
+	return in.Call(fn, args...)
+}
+
+// FuelUsed reports how many evaluation steps were spent so far
+func (in *Interpreter) FuelUsed() int64 {
+	return in.fuelUsed.Load()
+}
+
+// consumeFuel spends a single evaluation step, returning an error once the
+// budget runs out
+func (in *Interpreter) consumeFuel() *object.Error {
+	if in.maxFuel <= 0 {
+		return nil
+	}
+	if in.fuelUsed.Add(1) > in.maxFuel {
+		return newError(object.LimitError, ErrFuelExhausted, in.maxFuel)
+	}
+	return nil
+}
+
+// MemoryUsed reports the approximate number of bytes allocated so far. It is only
+// tracked when a memory limit is set.
+func (in *Interpreter) MemoryUsed() int64 {
+	return in.memoryUsed.Load()
+}
+
+// track accounts for a newly created object, returning an error instead of obj
+// once the memory limit is exceeded
+func (in *Interpreter) track(obj object.Object) object.Object {
+	if in.maxMemory <= 0 {
+		return obj
+	}
+
+	var size int64
+	switch obj := obj.(type) {
+	case *object.String:
+		size = objectSize + int64(len(obj.Value))
+	case *object.Array:
+		size = objectSize + elemSize*int64(obj.Len())
+	case *object.Tuple:
+		size = objectSize + elemSize*int64(len(obj.Elems))
+	case *object.Hash:
+		// Every pair holds a key and a value
+		size = objectSize + 2*elemSize*int64(obj.Len())
+	default:
+		size = objectSize
+	}
+
+	if err := in.allocate(size); err != nil {
+		return err
+	}
+	return obj
+}
+
+// allocate accounts for size bytes taken by objects growing in place, like
+// string builders, returning an error once the memory limit is exceeded
+func (in *Interpreter) allocate(size int64) *object.Error {
+	if in.maxMemory <= 0 {
+		return nil
+	}
+	if in.memoryUsed.Add(size) > in.maxMemory {
+		return newError(object.LimitError, ErrMemoryLimitExceeded, in.maxMemory)
+	}
+	return nil
+}
+
+// withRand runs fn with exclusive access to the interpreter's random number
+// generator, which isn't safe to share between tasks on its own
+func (in *Interpreter) withRand(fn func(r *rand.Rand)) {
+	in.randMu.Lock()
+	defer in.randMu.Unlock()
+	fn(in.random)
+}
+
+// now reads the interpreter's clock
+func (in *Interpreter) now() time.Time {
+	return in.clock()
+}
+
+// readLine reads the next line of input without its line ending. The last line
+// doesn't need one, io.EOF is only returned once there is nothing left to read.
+func (in *Interpreter) readLine() (string, error) {
+	in.stdinMu.Lock()
+	defer in.stdinMu.Unlock()
+
+	line, err := in.stdin.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
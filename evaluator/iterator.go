@@ -0,0 +1,245 @@
+package evaluator
+
+import (
+	"unicode/utf8"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrNotIterable           = "not iterable: %s (%s)"
+	ErrArgShouldBeIterator   = "invalid argument: next expects an iterator. got=%s (%s)"
+	ErrYieldOutsideGenerator = "yield outside of a generator"
+	ErrGeneratorStopped      = "generator stopped"
+)
+
+// yieldBinding is where the body of a generator finds the yielder it yields
+// to. It is a keyword, so no binding made by a program can shadow it.
+const yieldBinding = "yield"
+
+// yielder hands the values a generator's body yields to its consumer
+type yielder struct {
+	yield func(object.Object) bool
+}
+
+func (y *yielder) Type() object.ObjectType { return "YIELDER" }
+func (y *yielder) Inspect() string         { return "yielder" }
+
+func init() {
+	// next returns the following value of an iterator, or null once it is
+	// exhausted, just like recv does for a closed channel
+	builtins["next"] = func(in *Interpreter, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(args), 1)
+		}
+
+		next := in.iterator(args[0])
+		if next == nil {
+			return newError(object.TypeError, ErrArgShouldBeIterator, args[0].Inspect(), args[0].Type())
+		}
+
+		val, ok := next()
+		if !ok {
+			return NULL
+		}
+		return val
+	}
+}
+
+// newGenerator creates the generator returned by calling fn, whose body runs in
+// env as values are asked for. The interpreter keeps track of the generator
+// while its body runs, so that Close can stop it.
+func (in *Interpreter) newGenerator(fn *object.Function, env *object.Environment) *object.Generator {
+	var g *object.Generator
+	g = object.NewGenerator(func(yield func(object.Object) bool) object.Object {
+		if !in.startGenerator(g) {
+			return newError(object.ValueError, ErrGeneratorStopped)
+		}
+		defer in.endGenerator(g)
+
+		env.Set(yieldBinding, &yielder{yield: yield})
+
+		res := unwrapReturnValue(in.Eval(fn.Body, env))
+		if isError(res) {
+			return res
+		}
+		// Returning ends the generator, without a value of its own
+		return nil
+	})
+	return g
+}
+
+// startGenerator records that the body of g started running, unless the
+// interpreter was closed
+func (in *Interpreter) startGenerator(g *object.Generator) bool {
+	in.genMu.Lock()
+	defer in.genMu.Unlock()
+
+	if in.closed {
+		return false
+	}
+	if in.generators == nil {
+		in.generators = make(map[*object.Generator]struct{})
+	}
+	in.generators[g] = struct{}{}
+	return true
+}
+
+// endGenerator records that the body of g finished
+func (in *Interpreter) endGenerator(g *object.Generator) {
+	in.genMu.Lock()
+	delete(in.generators, g)
+	in.genMu.Unlock()
+}
+
+func (in *Interpreter) evalYieldStatement(ys *ast.YieldStatement, env *object.Environment) object.Object {
+	val := in.Eval(ys.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	y, exists := env.Get(yieldBinding)
+	if !exists {
+		return newError(object.ValueError, ErrYieldOutsideGenerator)
+	}
+
+	// The generator was stopped, so its body unwinds like it would on an error
+	if !y.(*yielder).yield(val) {
+		return newError(object.ValueError, ErrGeneratorStopped)
+	}
+
+	return nil
+}
+
+// evalForStatement runs the body of fs in an environment of its own for every
+// value, so that functions created by one iteration don't see the bindings of
+// later ones
+func (in *Interpreter) evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := in.Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	next := in.iterate(iterable)
+	if next == nil {
+		return newError(object.TypeError, ErrNotIterable, iterable.Inspect(), iterable.Type())
+	}
+
+	for {
+		val, ok := next()
+		if !ok {
+			return nil
+		}
+		if isError(val) {
+			return val
+		}
+
+		loopEnv := object.NewLocalEnvironment(env)
+		if len(fs.Names) > 1 {
+			if err := evalTupleUnpacking(fs.Names, val, loopEnv); err != nil {
+				return err
+			}
+		} else {
+			loopEnv.Set(fs.Name.Value, val)
+		}
+
+		res := in.Eval(fs.Body, loopEnv)
+		if isError(res) {
+			return res
+		}
+		if _, isRetVal := res.(*object.ReturnValue); isRetVal {
+			return res
+		}
+	}
+}
+
+// evalDoWhileStatement runs the body of ds in an environment of its own until
+// its condition, evaluated in the same environment, stops holding
+func (in *Interpreter) evalDoWhileStatement(ds *ast.DoWhileStatement, env *object.Environment) object.Object {
+	for {
+		loopEnv := object.NewLocalEnvironment(env)
+
+		res := in.Eval(ds.Body, loopEnv)
+		if isError(res) {
+			return res
+		}
+		if _, isRetVal := res.(*object.ReturnValue); isRetVal {
+			return res
+		}
+
+		cond := in.Eval(ds.Condition, loopEnv)
+		if isError(cond) {
+			return cond
+		}
+		if !isTruthy(cond) {
+			return nil
+		}
+	}
+}
+
+// iterate returns a function producing the values of obj one by one, or nil if
+// obj can't be iterated over. Arrays and tuples produce their elements, strings
+// their characters and hashes (key, value) tuples, unless they are iterators.
+func (in *Interpreter) iterate(obj object.Object) func() (object.Object, bool) {
+	if next := in.iterator(obj); next != nil {
+		return next
+	}
+
+	var elems []object.Object
+	switch obj := obj.(type) {
+	case *object.Array:
+		elems = obj.Elements()
+	case *object.Tuple:
+		elems = obj.Elems
+	case *object.String:
+		// Only ASCII characters are interned, which bounds what strings
+		// iterated over add to the table
+		for _, ch := range obj.Value {
+			if ch < utf8.RuneSelf {
+				elems = append(elems, object.InternString(string(ch)))
+			} else {
+				elems = append(elems, &object.String{Value: string(ch)})
+			}
+		}
+	case *object.Hash:
+		obj.Each(func(key, value object.Object) {
+			elems = append(elems, &object.Tuple{Elems: []object.Object{key, value}})
+		})
+	default:
+		return nil
+	}
+
+	i := 0
+	return func() (object.Object, bool) {
+		if i >= len(elems) {
+			return nil, false
+		}
+		i++
+		return elems[i-1], true
+	}
+}
+
+// iterator returns the function producing the next value of obj when it is an
+// iterator, or nil when it isn't. Besides builtin iterators like generators,
+// hashes with a next function are iterators too, ending once it returns null.
+func (in *Interpreter) iterator(obj object.Object) func() (object.Object, bool) {
+	switch obj := obj.(type) {
+	case object.Iterator:
+		return obj.Next
+	case *object.Hash:
+		fn, exists := obj.Get(object.InternString("next"))
+		if !exists || (fn.Type() != object.FUNCTION && fn.Type() != object.BUILTIN) {
+			return nil
+		}
+		return func() (object.Object, bool) {
+			val := in.applyFunction(fn, nil)
+			if val == nil || val == NULL {
+				return nil, false
+			}
+			return val, true
+		}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,211 @@
+package evaluator
+
+import (
+	"strconv"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/token"
+)
+
+const (
+	ErrUnquoteOutsideQuote = "unquote outside of quote"
+	ErrCannotUnquote       = "invalid argument: cannot unquote %s (%s)"
+	ErrMacroOutsideLet     = "macros can only be defined by let statements at the top level"
+	ErrMacroResult         = "invalid macro: %s must return a quote. got=%s (%s)"
+)
+
+// ExpandMacros takes the macros defined by top-level let statements out of
+// program, binding them in macros, and then replaces every call to a macro with
+// the code the macro returns. Macros are given the code of their arguments as
+// quotes rather than their values. The returned program is a copy, so program
+// itself is left as it was.
+//
+// Passing the same macros to every call lets macros defined by one program be
+// used by the next, like the lines of the REPL.
+func ExpandMacros(program *ast.Program, macros *object.Environment) (*ast.Program, *object.Error) {
+	stmts := []ast.Statement{}
+	for _, stmt := range program.Statements {
+		if name, m := macroDefinition(stmt); m != nil {
+			macros.Set(name, &object.Macro{Params: m.Params, Body: m.Body, Env: macros})
+			continue
+		}
+		stmts = append(stmts, stmt)
+	}
+	defined := &ast.Program{Statements: stmts}
+
+	in := New()
+	var expandErr *object.Error
+	expanded := ast.Modify(defined, func(node ast.Node) ast.Node {
+		call, isCall := node.(*ast.CallExpression)
+		if !isCall || expandErr != nil {
+			return node
+		}
+		m, name := macroCalled(call, macros)
+		if m == nil {
+			return node
+		}
+
+		res := in.expandMacro(m, name, call)
+		if err, isErr := res.(*object.Error); isErr {
+			if err.Pos == (token.Position{}) {
+				err.Pos, err.End = call.Pos(), ast.End(call)
+			}
+			expandErr = err
+			return node
+		}
+		return res.(*object.Quote).Node
+	})
+	if expandErr != nil {
+		return nil, expandErr
+	}
+
+	return expanded.(*ast.Program), nil
+}
+
+// macroDefinition returns the name and macro bound by stmt if it's a let
+// statement like let unless = macro(cond, body) { ... }
+func macroDefinition(stmt ast.Statement) (string, *ast.MacroLiteral) {
+	let, isLet := stmt.(*ast.LetStatement)
+	if !isLet || len(let.Names) != 1 {
+		return "", nil
+	}
+	m, isMacro := let.Value.(*ast.MacroLiteral)
+	if !isMacro {
+		return "", nil
+	}
+	return let.Name.Value, m
+}
+
+func macroCalled(call *ast.CallExpression, macros *object.Environment) (*object.Macro, string) {
+	id, isIdent := call.Function.(*ast.Identifier)
+	if !isIdent {
+		return nil, ""
+	}
+	obj, exists := macros.Get(id.Value)
+	if !exists {
+		return nil, ""
+	}
+	m, isMacro := obj.(*object.Macro)
+	if !isMacro {
+		return nil, ""
+	}
+	return m, id.Value
+}
+
+// expandMacro runs the body of m with the arguments of call quoted, returning
+// the quote it results in
+func (in *Interpreter) expandMacro(m *object.Macro, name string, call *ast.CallExpression) object.Object {
+	if len(call.Args) != len(m.Params) {
+		return newError(object.ArgumentError, ErrWrongNumberOfArgs, len(call.Args), len(m.Params))
+	}
+
+	env := object.NewLocalEnvironment(m.Env)
+	for i, param := range m.Params {
+		env.Set(param.Value, &object.Quote{Node: call.Args[i]})
+	}
+
+	res := unwrapReturnValue(in.Eval(m.Body, env))
+	if isError(res) {
+		return res
+	}
+	if _, isQuote := res.(*object.Quote); !isQuote {
+		return newError(object.TypeError, ErrMacroResult, name, res.Inspect(), res.Type())
+	}
+	return res
+}
+
+// evalQuote returns the code of node without evaluating it, except for the
+// calls to unquote within it which are replaced by the code of their values
+func (in *Interpreter) evalQuote(node ast.Node, env *object.Environment) object.Object {
+	var unquoteErr object.Object
+	quoted := ast.Modify(node, func(n ast.Node) ast.Node {
+		call, isCall := n.(*ast.CallExpression)
+		if !isCall || !isCallTo(call, "unquote") || unquoteErr != nil {
+			return n
+		}
+		if len(call.Args) != 1 {
+			unquoteErr = newError(object.ArgumentError, ErrWrongNumberOfArgs, len(call.Args), 1)
+			return n
+		}
+
+		val := in.Eval(call.Args[0], env)
+		if isError(val) {
+			unquoteErr = val
+			return n
+		}
+		unquoted, ok := objectToNode(val, call.Token)
+		if !ok {
+			unquoteErr = newError(object.TypeError, ErrCannotUnquote, val.Inspect(), val.Type())
+			return n
+		}
+		return unquoted
+	})
+	if unquoteErr != nil {
+		return unquoteErr
+	}
+
+	return &object.Quote{Node: quoted}
+}
+
+func isCallTo(call *ast.CallExpression, name string) bool {
+	id, isIdent := call.Function.(*ast.Identifier)
+	return isIdent && id.Value == name
+}
+
+// objectToNode builds the literal that evaluates to obj, placing its tokens at
+// tok. Only quotes and values that literals can be written for have one.
+func objectToNode(obj object.Object, tok token.Token) (ast.Expression, bool) {
+	switch obj := obj.(type) {
+	case *object.Quote:
+		expr, isExpr := obj.Node.(ast.Expression)
+		return expr, isExpr
+	case *object.Integer:
+		tok.Type, tok.Literal = token.INT, strconv.FormatInt(obj.Value, 10)
+		return &ast.IntLiteral{Token: tok, Value: obj.Value}, true
+	case *object.Float:
+		tok.Type, tok.Literal = token.FLOAT, strconv.FormatFloat(obj.Value, 'f', -1, 64)
+		return &ast.FloatLiteral{Token: tok, Value: obj.Value}, true
+	case *object.Boolean:
+		tok.Type, tok.Literal = token.FALSE, "false"
+		if obj.Value {
+			tok.Type, tok.Literal = token.TRUE, "true"
+		}
+		return &ast.BooleanLiteral{Token: tok, Value: obj.Value}, true
+	case *object.String:
+		tok.Type, tok.Literal = token.STRING, obj.Value
+		return &ast.StringLiteral{Token: tok, Value: obj.Value}, true
+	case *object.Array:
+		elems, ok := objectsToNodes(obj.Elements(), tok)
+		tok.Type, tok.Literal = token.LBRACKET, "["
+		return &ast.ArrayLiteral{Token: tok, Elems: elems}, ok
+	case *object.Tuple:
+		elems, ok := objectsToNodes(obj.Elems, tok)
+		tok.Type, tok.Literal = token.LPAREN, "("
+		return &ast.TupleExpression{Token: tok, Elems: elems}, ok
+	case *object.Hash:
+		keys, values := []object.Object{}, []object.Object{}
+		obj.Each(func(key, value object.Object) {
+			keys = append(keys, key)
+			values = append(values, value)
+		})
+		keyNodes, keysOk := objectsToNodes(keys, tok)
+		valueNodes, valuesOk := objectsToNodes(values, tok)
+		tok.Type, tok.Literal = token.LBRACE, "{"
+		return &ast.HashLiteral{Token: tok, Keys: keyNodes, Values: valueNodes}, keysOk && valuesOk
+	default:
+		return nil, false
+	}
+}
+
+func objectsToNodes(objs []object.Object, tok token.Token) ([]ast.Expression, bool) {
+	nodes := make([]ast.Expression, len(objs))
+	for i, obj := range objs {
+		node, ok := objectToNode(obj, tok)
+		if !ok {
+			return nil, false
+		}
+		nodes[i] = node
+	}
+	return nodes, true
+}
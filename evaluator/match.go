@@ -0,0 +1,135 @@
+package evaluator
+
+import (
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const ErrNotAType = "invalid argument: %s (%s) is not a type"
+
+// evalMatchExpression evaluates the body of the first arm matching the subject,
+// or null if none does. Every arm gets an environment of its own, so the names
+// bound by an arm that ends up not matching never leak into the next one.
+func (in *Interpreter) evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := in.Eval(me.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		armEnv := object.NewLocalEnvironment(env)
+
+		matched, err := in.matchPattern(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := in.Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return in.Eval(arm.Body, armEnv)
+	}
+
+	return NULL
+}
+
+// matchPattern reports whether val matches pattern, binding the names in the
+// pattern to the parts of val they match in env
+func (in *Interpreter) matchPattern(pattern ast.Pattern, val object.Object, env *object.Environment) (bool, object.Object) {
+	switch p := pattern.(type) {
+	case *ast.WildcardPattern:
+		return true, nil
+	case *ast.BindingPattern:
+		env.Set(p.Name.Value, val)
+		return true, nil
+	case *ast.LiteralPattern:
+		literal := in.Eval(p.Value, env)
+		if isError(literal) {
+			return false, literal
+		}
+		return object.Equal(literal, val), nil
+	case *ast.ArrayPattern:
+		arr, isArr := val.(*object.Array)
+		if !isArr || arr.Len() < len(p.Elems) || p.Rest == nil && arr.Len() != len(p.Elems) {
+			return false, nil
+		}
+		elems := arr.Elements()
+		if matched, err := in.matchPatterns(p.Elems, elems, env); !matched {
+			return false, err
+		}
+		if p.Rest == nil {
+			return true, nil
+		}
+		rest := in.track(&object.Array{Elems: elems[len(p.Elems):]})
+		if isError(rest) {
+			return false, rest
+		}
+		return in.matchPattern(p.Rest, rest, env)
+	case *ast.TuplePattern:
+		tuple, isTuple := val.(*object.Tuple)
+		if !isTuple || len(tuple.Elems) != len(p.Elems) {
+			return false, nil
+		}
+		return in.matchPatterns(p.Elems, tuple.Elems, env)
+	case *ast.HashPattern:
+		hash, isHash := val.(*object.Hash)
+		if !isHash {
+			return false, nil
+		}
+		for i, keyNode := range p.Keys {
+			key := in.Eval(keyNode, env)
+			if isError(key) {
+				return false, key
+			}
+			hashKey, isHashable := key.(object.Hashable)
+			if !isHashable {
+				return false, newError(object.TypeError, ErrUnusableHashKey, key.Inspect(), key.Type())
+			}
+			value, exists := hash.Get(hashKey)
+			if !exists {
+				return false, nil
+			}
+			if matched, err := in.matchPattern(p.Values[i], value, env); !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	case *ast.TypePattern:
+		t := in.Eval(p.Type, env)
+		if isError(t) {
+			return false, t
+		}
+		typ, isType := t.(*object.TypeValue)
+		if !isType {
+			return false, newError(object.TypeError, ErrNotAType, t.Inspect(), t.Type())
+		}
+		if object.TypeOf(val).Name != typ.Name {
+			return false, nil
+		}
+		if p.Binding != nil {
+			return in.matchPattern(p.Binding, val, env)
+		}
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (in *Interpreter) matchPatterns(patterns []ast.Pattern, vals []object.Object, env *object.Environment) (bool, object.Object) {
+	for i, pattern := range patterns {
+		if matched, err := in.matchPattern(pattern, vals[i], env); !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,53 @@
+package evaluator
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+// PluginSymbol is the variable plugins export their builtins in, declared in
+// the plugin's main package like
+//
+//	var Builtins = map[string]object.BuiltinFn{
+//		"fib": func(args ...object.Object) object.Object { ... },
+//	}
+const PluginSymbol = "Builtins"
+
+// LoadPlugin opens the Go plugin at path, built with -buildmode=plugin against
+// the same version of this module, and returns the builtins it exports. Plugins
+// run natively in the host process, so they are not limited by capabilities,
+// fuel or memory limits.
+func LoadPlugin(path string) (map[string]object.BuiltinFn, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sym, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	fns, isBuiltins := sym.(*map[string]object.BuiltinFn)
+	if !isBuiltins {
+		return nil, fmt.Errorf("plugin %s: %s is a %T, not a map[string]object.BuiltinFn", path, PluginSymbol, sym)
+	}
+
+	return *fns, nil
+}
+
+// WithBuiltins makes fns available to scripts next to the standard builtins,
+// replacing any of the same name. It's how builtins loaded from plugins are
+// installed.
+func WithBuiltins(fns map[string]object.BuiltinFn) Option {
+	return func(in *Interpreter) {
+		if in.extraBuiltins == nil {
+			in.extraBuiltins = make(map[string]object.BuiltinFn)
+		}
+		for name, fn := range fns {
+			in.extraBuiltins[name] = fn
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package evaluator
+
+import (
+	"sync/atomic"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/profiler"
+)
+
+// frameBinding is where the environment of a function call holds the frame of
+// the call while profiling. It is a keyword, so no binding made by a program
+// can shadow it.
+const frameBinding = "fn"
+
+// frame is a function call being profiled. Calls made by builtins, like those
+// to the functions given to map or spawn, have no caller and start stacks of
+// their own.
+type frame struct {
+	function string
+	caller   *frame
+	// Line being evaluated in the call, read by the calls it makes to find
+	// where they were called from, which may be on other goroutines
+	line atomic.Int64
+}
+
+func (f *frame) Type() object.ObjectType { return "FRAME" }
+func (f *frame) Inspect() string         { return "frame " + f.function }
+
+// WithProfile counts every node the interpreter evaluates into profile, under
+// the stack of function calls it was evaluated in. Functions are named after
+// the let statement binding them, and code outside of any function belongs to
+// main.
+func WithProfile(profile *profiler.Profile) Option {
+	return func(in *Interpreter) {
+		in.profile = profile
+		in.mainFrame = &frame{function: "main"}
+	}
+}
+
+// frameOf returns the frame of the call env belongs to
+func (in *Interpreter) frameOf(env *object.Environment) *frame {
+	if f, exists := env.Get(frameBinding); exists {
+		return f.(*frame)
+	}
+	return in.mainFrame
+}
+
+// enterFrame marks env as the environment of a call to fn made from caller,
+// which is nil when a builtin made the call
+func (in *Interpreter) enterFrame(env *object.Environment, fn *object.Function, caller *object.Environment) {
+	f := &frame{function: fn.Name}
+	if f.function == "" {
+		f.function = "anonymous"
+	}
+	if caller != nil {
+		f.caller = in.frameOf(caller)
+	}
+	env.Set(frameBinding, f)
+}
+
+// sample records n being evaluated in env to the profile
+func (in *Interpreter) sample(n ast.Node, env *object.Environment) {
+	line := n.Pos().Line
+	f := in.frameOf(env)
+	f.line.Store(int64(line))
+
+	stack := []profiler.Frame{{Function: f.function, Line: line}}
+	for c := f.caller; c != nil; c = c.caller {
+		stack = append(stack, profiler.Frame{Function: c.function, Line: int(c.line.Load())})
+	}
+	in.profile.Record(stack)
+}
@@ -0,0 +1,70 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+// Script is a program parsed once so that it can be run many times, sparing
+// hosts that run the same script for every request from parsing it again. A
+// script is never modified by running it, so it can be run concurrently.
+type Script struct {
+	program *ast.Program
+}
+
+// ParseError reports the problems that kept a script from parsing
+type ParseError struct {
+	Diagnostics []parser.Diagnostic
+}
+
+func (e *ParseError) Error() string {
+	msgs := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		msgs[i] = d.String()
+	}
+	return "parse error: " + strings.Join(msgs, "; ")
+}
+
+// NewScript parses src and expands its macros, failing with a *ParseError when
+// it isn't a valid program and with an *object.Error when a macro fails
+func NewScript(src string) (*Script, error) {
+	p := parser.New(lexer.New(src))
+	program := p.Parse()
+	if len(p.Errs()) != 0 {
+		return nil, &ParseError{Diagnostics: p.Errs()}
+	}
+	program, err := ExpandMacros(program, object.NewEnvironment())
+	if err != nil {
+		return nil, err
+	}
+	return &Script{program: program}, nil
+}
+
+// ScriptFromProgram wraps an already parsed program, like one decoded from a
+// compiled file
+func ScriptFromProgram(program *ast.Program) *Script {
+	return &Script{program: program}
+}
+
+// Program returns the parsed program of the script
+func (s *Script) Program() *ast.Program {
+	return s.program
+}
+
+// Run evaluates the script with in in a fresh environment. The environment is
+// returned along with the result so hosts can look up what the script bound,
+// like functions to call later.
+func (s *Script) Run(in *Interpreter) (object.Object, *object.Environment) {
+	env := object.NewEnvironment()
+	return s.RunIn(in, env), env
+}
+
+// RunIn evaluates the script with in in env, which lets hosts provide bindings
+// to the script beforehand
+func (s *Script) RunIn(in *Interpreter, env *object.Environment) object.Object {
+	return in.Eval(s.program, env)
+}
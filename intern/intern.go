@@ -0,0 +1,51 @@
+// Package intern keeps one canonical copy of the short strings names and small
+// constants are made of, shared by the front end and the runtime.
+package intern
+
+import "sync"
+
+// MaxLen bounds which strings get interned, so that only identifiers and small
+// constants end up living in the table for the lifetime of the process.
+const MaxLen = 64
+
+// MaxEntries bounds how many strings the table holds, since it never shrinks.
+// Processes parsing source for a long time, like served REPLs, stop interning
+// new strings once it's full.
+const MaxEntries = 1 << 16
+
+var table = struct {
+	sync.RWMutex
+	strs map[string]string
+}{strs: make(map[string]string)}
+
+// String returns the canonical copy of s. Interned strings share their backing
+// array, so comparing two of them short-circuits on the pointer. Only names and
+// literals known when parsing should be interned, not values made at runtime.
+// Strings longer than MaxLen, or new ones once the table is full, are returned
+// as is.
+func String(s string) string {
+	if len(s) > MaxLen {
+		return s
+	}
+
+	table.RLock()
+	str, exists := table.strs[s]
+	table.RUnlock()
+	if exists {
+		return str
+	}
+
+	table.Lock()
+	defer table.Unlock()
+
+	if str, exists = table.strs[s]; !exists {
+		if len(table.strs) >= MaxEntries {
+			return s
+		}
+		// Copy so the table doesn't pin the whole source the string was sliced from
+		str = string([]byte(s))
+		table.strs[str] = str
+	}
+
+	return str
+}
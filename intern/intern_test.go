@@ -0,0 +1,42 @@
+package intern
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestString(t *testing.T) {
+	src := "let name = name;"
+	first, second := String(src[4:8]), String(src[11:15])
+
+	if first != "name" {
+		t.Fatalf("wrong string. expected=%q, got=%q", "name", first)
+	}
+	if unsafe.StringData(first) != unsafe.StringData(second) {
+		t.Errorf("equal strings not shared")
+	}
+	if unsafe.StringData(first) == unsafe.StringData(src[4:8]) {
+		t.Errorf("interned string pins its source")
+	}
+
+	long := strings.Repeat("x", MaxLen+1)
+	if unsafe.StringData(String(long)) != unsafe.StringData(long) {
+		t.Errorf("long string was copied")
+	}
+}
+
+func TestStringFullTable(t *testing.T) {
+	for i := 0; i < MaxEntries; i++ {
+		String("name" + strconv.Itoa(i))
+	}
+
+	fresh := "not" + strconv.Itoa(MaxEntries)
+	if unsafe.StringData(String(fresh)) != unsafe.StringData(fresh) {
+		t.Errorf("string interned past MaxEntries")
+	}
+	if len(table.strs) > MaxEntries {
+		t.Errorf("table grew past MaxEntries. got=%d", len(table.strs))
+	}
+}
@@ -1,30 +1,163 @@
 package lexer
 
 import (
+	"io"
+	"strings"
+
 	"github.com/nayyara-airlangga/basedlang/token"
 )
 
+// chunkSize is how many bytes are read at a time from readers
+const chunkSize = 4096
+
 type Lexer struct {
 	input        string
 	position     int  // current position
 	nextPosition int  // position after current
 	ch           byte // current char being read
+
+	line   int // line of the current char
+	column int // column of the current char
+
+	// Reader the input is streamed from, nil once it's exhausted or when the
+	// whole input was given up front. Streamed input only holds the current
+	// token and what was read past it.
+	streamed bool
+	src      io.Reader
+	err      error
+	chunk    []byte // buffer chunks are read into, reused across reads
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
+	l.readCh()
+	l.skipShebang()
+	return l
+}
+
+// NewReader creates a lexer streaming its input from r, reading it in chunks as
+// tokens need them instead of all at once. Running into an error reading r
+// ends the tokens with EOF, after which Err reports the error.
+func NewReader(r io.Reader) *Lexer {
+	l := &Lexer{streamed: true, src: r, line: 1}
+	l.readCh()
+	l.skipShebang()
+	return l
+}
+
+// skipShebang skips a #! line starting the input, so scripts can be made
+// executable with a line like #!/usr/bin/env basedlang
+func (l *Lexer) skipShebang() {
+	if l.ch != '#' || l.peekCh() != '!' {
+		return
+	}
+	for l.ch != '\n' && l.ch != 0 {
+		l.readCh()
+	}
+}
+
+// Err returns the error reading the input failed with, if any
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// fill reads from the reader until input holds the byte at index i or the
+// reader runs out
+func (l *Lexer) fill(i int) {
+	if i < len(l.input) || l.src == nil {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(l.input)
+	if l.chunk == nil {
+		l.chunk = make([]byte, chunkSize)
+	}
+	for i >= b.Len() && l.src != nil {
+		n, err := l.src.Read(l.chunk)
+		b.Write(l.chunk[:n])
+		if err != nil {
+			if err != io.EOF {
+				l.err = err
+			}
+			l.src = nil
+		}
+	}
+	l.input = b.String()
+}
+
+// discard drops the streamed input before the current char, which no token
+// needs anymore
+func (l *Lexer) discard() {
+	if !l.streamed {
+		return
+	}
+	// Past the end of the input, which lexers keep reading EOF from
+	start := min(l.position, len(l.input))
+	l.input = l.input[start:]
+	l.nextPosition -= start
+	l.position -= start
+}
+
+// Text returns the input from start up to end, or false when the lexer doesn't
+// hold that part of it, like when the input is streamed from a reader
+func (l *Lexer) Text(start, end token.Position) (string, bool) {
+	if l.streamed {
+		return "", false
+	}
+	from, to := offsetOf(l.input, start), offsetOf(l.input, end)
+	if from < 0 || to < from || to > len(l.input) {
+		return "", false
+	}
+	return l.input[from:to], true
+}
+
+// offsetOf returns the byte offset of pos in input, or -1 if input has no such
+// line
+func offsetOf(input string, pos token.Position) int {
+	offset := 0
+	for line := 1; line < pos.Line; line++ {
+		i := strings.IndexByte(input[offset:], '\n')
+		if i < 0 {
+			return -1
+		}
+		offset += i + 1
+	}
+	return offset + pos.Column - 1
+}
+
+// NewAt creates a lexer that starts reading input at the byte offset, which is
+// at pos in the source, so tokens are placed where they are in the whole input
+func NewAt(input string, offset int, pos token.Position) *Lexer {
+	l := &Lexer{input: input, nextPosition: offset, line: pos.Line, column: pos.Column - 1}
 	l.readCh()
 	return l
 }
 
 func (l *Lexer) peekCh() byte {
+	l.fill(l.nextPosition)
 	if l.nextPosition >= len(l.input) {
 		return 0
 	}
 	return l.input[l.nextPosition]
 }
 
+func (l *Lexer) peekChAt(offset int) byte {
+	l.fill(l.nextPosition + offset)
+	if l.nextPosition+offset >= len(l.input) {
+		return 0
+	}
+	return l.input[l.nextPosition+offset]
+}
+
 func (l *Lexer) readCh() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
+	l.fill(l.nextPosition)
 	if l.nextPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -48,6 +181,24 @@ func (l *Lexer) readString() string {
 	return l.input[pos:l.position]
 }
 
+// readDocComment reads a /// comment up to the end of its line, returning its
+// text without the slashes and the space usually following them
+func (l *Lexer) readDocComment() string {
+	for i := 0; i < 3; i++ {
+		l.readCh()
+	}
+	if l.ch == ' ' {
+		l.readCh()
+	}
+
+	pos := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readCh()
+	}
+
+	return strings.TrimRight(l.input[pos:l.position], "\r")
+}
+
 func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
@@ -66,16 +217,28 @@ func (l *Lexer) readIdent(checkFn func(ch byte) bool) string {
 	return l.input[pos:l.position]
 }
 
+// readNumber reads an integer, or a float when the digits are followed by a
+// fractional part. A dot without digits after it is left alone.
+func (l *Lexer) readNumber() token.Token {
+	pos := l.position
+	l.readIdent(isDigit)
+
+	if l.ch != '.' || !isDigit(l.peekCh()) {
+		return newIdentToken(token.INT, l.input[pos:l.position])
+	}
+
+	l.readCh()
+	l.readIdent(isDigit)
+
+	return newIdentToken(token.FLOAT, l.input[pos:l.position])
+}
+
 func (l *Lexer) skipWhitespaces() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' || l.ch == '\n' {
 		l.readCh()
 	}
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
-	return token.Token{Type: tokenType, Literal: string(ch)}
-}
-
 func newIdentToken(tokenType token.TokenType, ident string) token.Token {
 	return token.Token{Type: tokenType, Literal: ident}
 }
@@ -89,71 +252,109 @@ func newEOFToken() token.Token {
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhitespaces()
+	l.discard()
+
+	line, column := l.line, l.column
+
+	tok := l.readToken()
+	tok.Line = line
+	tok.Column = column
+
+	return tok
+}
+
+// readToken reads the token starting at the current char. Tokens whose text is
+// always the same share the literal of their type, and the others slice the
+// input, so reading a token never allocates.
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
-		if l.peekCh() == '=' {
-			ch := l.ch
-			l.readCh()
-			lit := string(ch) + string(l.ch)
-			tok = newIdentToken(token.EQ, lit)
-		} else {
-			tok = newToken(token.ASSIGN, l.ch)
+		switch l.peekCh() {
+		case '=':
+			tok = l.fixedToken(token.EQ)
+		case '>':
+			tok = l.fixedToken(token.ARROW)
+		default:
+			tok = l.fixedToken(token.ASSIGN)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.fixedToken(token.PLUS)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekCh() == '>' {
+			tok = l.fixedToken(token.RARROW)
+		} else {
+			tok = l.fixedToken(token.MINUS)
+		}
 	case '!':
 		if l.peekCh() == '=' {
-			ch := l.ch
-			l.readCh()
-			lit := string(ch) + string(l.ch)
-			tok = newIdentToken(token.NEQ, lit)
+			tok = l.fixedToken(token.NEQ)
 		} else {
-			tok = newToken(token.BANG, l.ch)
+			tok = l.fixedToken(token.BANG)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		if l.peekCh() == '/' && l.peekChAt(1) == '/' {
+			return newIdentToken(token.DOC_COMMENT, l.readDocComment())
+		}
+		if l.peekCh() == '/' {
+			tok = l.fixedToken(token.FLOOR_DIV)
+		} else {
+			tok = l.fixedToken(token.SLASH)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		tok = l.fixedToken(token.ASTERISK)
 	case '<':
 		if l.peekCh() == '=' {
-			ch := l.ch
-			l.readCh()
-			lit := string(ch) + string(l.ch)
-			tok = newIdentToken(token.LTE, lit)
+			tok = l.fixedToken(token.LTE)
 		} else {
-			tok = newToken(token.LT, l.ch)
+			tok = l.fixedToken(token.LT)
 		}
 	case '>':
 		if l.peekCh() == '=' {
-			ch := l.ch
-			l.readCh()
-			lit := string(ch) + string(l.ch)
-			tok = newIdentToken(token.GTE, lit)
+			tok = l.fixedToken(token.GTE)
 		} else {
-			tok = newToken(token.GT, l.ch)
+			tok = l.fixedToken(token.GT)
+		}
+	case '|':
+		if l.peekCh() == '>' {
+			tok = l.fixedToken(token.PIPELINE)
+		} else if l.peekCh() == '|' && l.peekChAt(1) == '=' {
+			tok = l.fixedToken(token.OR_ASSIGN)
+		} else {
+			tok = l.fixedToken(token.PIPE)
+		}
+	case '?':
+		if l.peekCh() == '?' && l.peekChAt(1) == '=' {
+			tok = l.fixedToken(token.NULLISH_ASSIGN)
+		} else {
+			tok = l.illegalToken()
 		}
 	case ';':
-		tok = newToken(token.SEMICOLON, l.ch)
+		tok = l.fixedToken(token.SEMICOLON)
 	case ',':
-		tok = newToken(token.COMMA, l.ch)
+		tok = l.fixedToken(token.COMMA)
+	case ':':
+		tok = l.fixedToken(token.COLON)
+	case '.':
+		if l.peekCh() == '.' && l.peekChAt(1) == '.' {
+			tok = l.fixedToken(token.ELLIPSIS)
+		} else {
+			tok = l.illegalToken()
+		}
 	case '(':
-		tok = newToken(token.LPAREN, l.ch)
+		tok = l.fixedToken(token.LPAREN)
 	case ')':
-		tok = newToken(token.RPAREN, l.ch)
+		tok = l.fixedToken(token.RPAREN)
 	case '{':
-		tok = newToken(token.LBRACE, l.ch)
+		tok = l.fixedToken(token.LBRACE)
 	case '}':
-		tok = newToken(token.RBRACE, l.ch)
+		tok = l.fixedToken(token.RBRACE)
 	case '[':
-		tok = newToken(token.LBRACKET, l.ch)
+		tok = l.fixedToken(token.LBRACKET)
 	case ']':
-		tok = newToken(token.RBRACKET, l.ch)
+		tok = l.fixedToken(token.RBRACKET)
 	case '"':
 		tok = newStringToken(l.readString())
 	case 0:
@@ -161,17 +362,36 @@ func (l *Lexer) NextToken() token.Token {
 	default:
 		if isLetter(l.ch) {
 			ident := l.readIdent(isLetter)
-			tok = newIdentToken(token.LookupType(ident), ident)
-			return tok
+			tokType := token.LookupType(ident)
+			// Keywords don't hold on to the input, which may be a large chunk of
+			// a streamed one
+			if lit, fixed := token.Literal(tokType); fixed {
+				ident = lit
+			}
+			return newIdentToken(tokType, ident)
 		} else if isDigit(l.ch) {
-			num := l.readIdent(isDigit)
-			tok = newIdentToken(token.INT, num)
-			return tok
+			return l.readNumber()
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			tok = l.illegalToken()
 		}
 	}
 
 	l.readCh()
 	return tok
 }
+
+// fixedToken makes a token of type t, whose text is always the same, out of
+// the chars starting at the current one. All but its last char are read, like
+// for tokens of a single char.
+func (l *Lexer) fixedToken(t token.TokenType) token.Token {
+	lit, _ := token.Literal(t)
+	for i := 1; i < len(lit); i++ {
+		l.readCh()
+	}
+	return token.Token{Type: t, Literal: lit}
+}
+
+// illegalToken makes a token of the current char, which no token starts with
+func (l *Lexer) illegalToken() token.Token {
+	return token.Token{Type: token.ILLEGAL, Literal: l.input[l.position:l.nextPosition]}
+}
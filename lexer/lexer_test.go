@@ -1,11 +1,52 @@
 package lexer
 
 import (
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/nayyara-airlangga/basedlang/token"
 )
 
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  x + "ab";
+`
+
+	expectedPositions := []struct {
+		literal string
+		line    int
+		column  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"+", 2, 5},
+		{"ab", 2, 7},
+		{";", 2, 11},
+		{"", 3, 1},
+	}
+
+	l := New(input)
+
+	for i, ep := range expectedPositions {
+		tok := l.NextToken()
+
+		if tok.Literal != ep.literal {
+			t.Fatalf("expectedPositions[%d] - literal wrong. expected=%q, got=%q", i, ep.literal, tok.Literal)
+		}
+		if tok.Line != ep.line || tok.Column != ep.column {
+			t.Errorf("expectedPositions[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, ep.line, ep.column, tok.Line, tok.Column)
+		}
+	}
+}
+
 func TestNextToken(t *testing.T) {
 	input := `let five = 5;
 let ten = 10;
@@ -31,6 +72,18 @@ if (5 < 10) {
 "foobar";
 "foo bar";
 [1, true, "foo bar"];
+arr[1:];
+fn(rest...) {};
+|x| x => x;
+x |> f;
+fn(a: int) -> bool {};
+xs[0] ||= 1;
+h[k] ??= || 0;
+/// Adds one.
+///
+let inc = 1 / 2;
+3.25 + 0.5;
+7 // 2;
 `
 
 	expectedTokens := []struct {
@@ -130,6 +183,75 @@ if (5 < 10) {
 		{token.STRING, "foo bar"},
 		{token.RBRACKET, "]"},
 		{token.SEMICOLON, ";"},
+		{token.IDENT, "arr"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COLON, ":"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "rest"},
+		{token.ELLIPSIS, "..."},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
+		{token.PIPE, "|"},
+		{token.IDENT, "x"},
+		{token.PIPE, "|"},
+		{token.IDENT, "x"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PIPELINE, "|>"},
+		{token.IDENT, "f"},
+		{token.SEMICOLON, ";"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.RARROW, "->"},
+		{token.IDENT, "bool"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "xs"},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.OR_ASSIGN, "||="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "h"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "k"},
+		{token.RBRACKET, "]"},
+		{token.NULLISH_ASSIGN, "??="},
+		{token.PIPE, "|"},
+		{token.PIPE, "|"},
+		{token.INT, "0"},
+		{token.SEMICOLON, ";"},
+		{token.DOC_COMMENT, "Adds one."},
+		{token.DOC_COMMENT, ""},
+		{token.LET, "let"},
+		{token.IDENT, "inc"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.FLOAT, "3.25"},
+		{token.PLUS, "+"},
+		{token.FLOAT, "0.5"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "7"},
+		{token.FLOOR_DIV, "//"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
 		{token.EOF, ""},
 	}
 
@@ -147,3 +269,135 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestShebang(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{
+			"#!/usr/bin/env basedlang\nlet x;",
+			[]token.Token{
+				{Type: token.LET, Literal: "let", Line: 2, Column: 1},
+				{Type: token.IDENT, Literal: "x", Line: 2, Column: 5},
+				{Type: token.SEMICOLON, Literal: ";", Line: 2, Column: 6},
+			},
+		},
+		{"#!/usr/bin/env basedlang", []token.Token{}},
+		// Only the very start of the input may be a shebang
+		{
+			"x\n#!",
+			[]token.Token{
+				{Type: token.IDENT, Literal: "x", Line: 1, Column: 1},
+				{Type: token.ILLEGAL, Literal: "#", Line: 2, Column: 1},
+				{Type: token.BANG, Literal: "!", Line: 2, Column: 2},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		lexers := map[string]*Lexer{"string": New(tc.input), "reader": NewReader(strings.NewReader(tc.input))}
+		for name, l := range lexers {
+			for i, expected := range tc.expected {
+				tok := l.NextToken()
+				if tok.Type != expected.Type || tok.Literal != expected.Literal || tok.Pos() != expected.Pos() {
+					t.Fatalf("%s %q: tokens[%d] wrong. expected=%+v, got=%+v", name, tc.input, i, expected, tok)
+				}
+			}
+			if tok := l.NextToken(); tok.Type != token.EOF {
+				t.Errorf("%s %q: expected EOF. got=%+v", name, tc.input, tok)
+			}
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	input := `/// Adds two numbers
+let add = fn(x, y) { x + y };
+let s = "a string spanning more than a single read";
+[1.5, ...rest] |> map(|x| x >= 2.25) != true;
+`
+
+	readers := map[string]func() io.Reader{
+		"whole":    func() io.Reader { return strings.NewReader(input) },
+		"one byte": func() io.Reader { return iotest.OneByteReader(strings.NewReader(input)) },
+		"half":     func() io.Reader { return iotest.HalfReader(strings.NewReader(input)) },
+	}
+
+	for name, newReader := range readers {
+		expected := New(input)
+		l := NewReader(newReader())
+
+		for i := 0; ; i++ {
+			want, got := expected.NextToken(), l.NextToken()
+			if got != want {
+				t.Fatalf("%s: tokens[%d] wrong. expected=%+v, got=%+v", name, i, want, got)
+			}
+			if want.Type == token.EOF {
+				break
+			}
+		}
+		if l.Err() != nil {
+			t.Errorf("%s: unexpected error %s", name, l.Err())
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	src := "let x = 1 +\n  2;"
+	if text, ok := New(src).Text(token.Position{Line: 1, Column: 9}, token.Position{Line: 2, Column: 4}); !ok || text != "1 +\n  2" {
+		t.Errorf("wrong text. expected=%q, got=%q (%t)", "1 +\n  2", text, ok)
+	}
+	if _, ok := New(src).Text(token.Position{Line: 1, Column: 1}, token.Position{Line: 3, Column: 1}); ok {
+		t.Errorf("expected no text past the last line")
+	}
+	if _, ok := NewReader(strings.NewReader(src)).Text(token.Position{Line: 1, Column: 1}, token.Position{Line: 1, Column: 4}); ok {
+		t.Errorf("expected no text for streamed input")
+	}
+}
+
+func TestNewReaderPastEOF(t *testing.T) {
+	l := NewReader(strings.NewReader("x"))
+	for _, expected := range []token.TokenType{token.IDENT, token.EOF, token.EOF, token.EOF} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("wrong token type. expected=%q, got=%q", expected, tok.Type)
+		}
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	readErr := errors.New("connection reset")
+	l := NewReader(io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(readErr)))
+
+	for _, expected := range []token.TokenType{token.LET, token.IDENT, token.EOF} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("wrong token type. expected=%q, got=%q", expected, tok.Type)
+		}
+	}
+	if !errors.Is(l.Err(), readErr) {
+		t.Errorf("wrong error. expected=%v, got=%v", readErr, l.Err())
+	}
+}
+
+// benchmarkSource is a large file of lines using most kinds of tokens
+var benchmarkSource = strings.Repeat(`let add = fn(a, b) { if (a >= b) { return a == b; } a |> f; [1, 2.5, "s"] };
+`, 1000)
+
+func BenchmarkLexer(b *testing.B) {
+	benchmarkLexer(b, func() *Lexer { return New(benchmarkSource) })
+}
+
+func BenchmarkLexerReader(b *testing.B) {
+	benchmarkLexer(b, func() *Lexer { return NewReader(strings.NewReader(benchmarkSource)) })
+}
+
+func benchmarkLexer(b *testing.B, newLexer func() *Lexer) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchmarkSource)))
+
+	for i := 0; i < b.N; i++ {
+		l := newLexer()
+		for l.NextToken().Type != token.EOF {
+		}
+	}
+}
@@ -1,15 +1,148 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"maps"
 	"os"
-	"runtime"
+	"strings"
 
-	"github.com/nayyara-airlangga/basedlang/repl"
+	"github.com/nayyara-airlangga/basedlang/evaluator"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/parser"
+	"github.com/nayyara-airlangga/basedlang/token"
 )
 
+const version = "v0.0.1"
+
+const usage = `Usage:
+  basedlang [repl flags]          start the REPL
+  basedlang repl [flags]          start the REPL
+  basedlang -e <code>             evaluate code and print its result
+  basedlang run [flags] <file> [args...]
+                                  run a script, reading it from stdin if file is -
+  basedlang - [args...]           run a script read from stdin
+  basedlang <file> [args...]      run a script, like one starting with #!/usr/bin/env basedlang
+  basedlang check [flags] <files...>
+                                  report problems in scripts without running them
+  basedlang build [-o out] <file> compile a script to a .basedc file that run loads directly
+  basedlang doc [flags] <file>    render the documentation of a script as Markdown or HTML
+  basedlang diff <a> <b>          report the declarations added, removed or changed from a to b
+`
+
 func main() {
-	fmt.Printf("Basedlang v0.0.1 on %s %s\n", runtime.GOOS, runtime.GOARCH)
-	fmt.Println("Type away!")
-	repl.Start(os.Stdin, os.Stdout)
+	args := os.Args[1:]
+
+	if len(args) > 0 && (args[0] == "-e" || args[0] == "--eval") {
+		os.Exit(evalCmd(args[1:]))
+	}
+
+	// A script piped in without arguments is run instead of being fed to the REPL
+	// line by line
+	if len(args) == 0 && !isTerminal(os.Stdin) || len(args) > 0 && args[0] == "-" {
+		var scriptArgs []string
+		if len(args) > 0 {
+			scriptArgs = args[1:]
+		}
+		os.Exit(runCmd(append([]string{"-"}, scriptArgs...)))
+	}
+
+	// Flags without a command are meant for the REPL
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && !isHelpFlag(args[0]) {
+		os.Exit(replCmd(args))
+	}
+
+	switch args[0] {
+	case "repl":
+		os.Exit(replCmd(args[1:]))
+	case "run":
+		os.Exit(runCmd(args[1:]))
+	case "check":
+		os.Exit(checkCmd(args[1:]))
+	case "build":
+		os.Exit(buildCmd(args[1:]))
+	case "doc":
+		os.Exit(docCmd(args[1:]))
+	case "diff":
+		os.Exit(diffCmd(args[1:]))
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		// Scripts starting with #!/usr/bin/env basedlang are run as basedlang
+		// script [args...]
+		if info, err := os.Stat(args[0]); err == nil && !info.IsDir() {
+			os.Exit(runCmd(args))
+		}
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+		os.Exit(2)
+	}
+}
+
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "--help"
+}
+
+// isTerminal reports whether f is an interactive terminal, as opposed to a pipe
+// or a regular file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorSupported reports whether ANSI colors should be written to f, honoring
+// the NO_COLOR convention and dumb terminals
+func colorSupported(f *os.File) bool {
+	if _, noColor := os.LookupEnv("NO_COLOR"); noColor {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// printParserErrors reports errors from parsing the script called name to
+// stderr, quoting the source they point at
+func printParserErrors(name, src string, errors []parser.Diagnostic) {
+	fmt.Fprintf(os.Stderr, "%s: parser errors:\n", name)
+	for _, d := range errors {
+		fmt.Fprintf(os.Stderr, "\t%s\n", d)
+		printSnippet("\t\t", src, d.Pos, d.End)
+	}
+}
+
+// printRuntimeError reports an error that stopped the script called name to
+// stderr, quoting the source it happened at when src is known
+func printRuntimeError(name, src string, err *object.Error) {
+	if err.Pos == (token.Position{}) {
+		fmt.Fprintln(os.Stderr, err.Inspect())
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "%s:%d:%d: %s\n", name, err.Pos.Line, err.Pos.Column, err.Inspect())
+	if src != "" {
+		printSnippet("\t", src, err.Pos, err.End)
+	}
+}
+
+func printSnippet(indent, src string, start, end token.Position) {
+	if line, caret, ok := token.Snippet(src, start, end); ok {
+		fmt.Fprintf(os.Stderr, "%s%s\n%s%s\n", indent, line, indent, caret)
+	}
+}
+
+// pluginFlag adds a -plugin flag to fs, which may be repeated, loading the
+// builtins of every plugin it's given into builtins
+func pluginFlag(fs *flag.FlagSet, builtins map[string]object.BuiltinFn) {
+	fs.Func("plugin", "load builtins from the Go plugin at `path`, may be repeated", func(path string) error {
+		fns, err := evaluator.LoadPlugin(path)
+		if err != nil {
+			return err
+		}
+		maps.Copy(builtins, fns)
+		return nil
+	})
 }
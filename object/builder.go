@@ -0,0 +1,38 @@
+package object
+
+import (
+	"strings"
+	"sync"
+)
+
+// StringBuilder accumulates strings in place, so that building a string out of
+// many parts takes time linear in its length instead of copying everything
+// built so far on every concatenation. Tasks may write to the same builder.
+type StringBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+// WriteString appends s to what was built so far
+func (sb *StringBuilder) WriteString(s string) {
+	sb.mu.Lock()
+	sb.b.WriteString(s)
+	sb.mu.Unlock()
+}
+
+// String returns what was built so far
+func (sb *StringBuilder) String() string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.b.String()
+}
+
+// Len returns the length in bytes of what was built so far
+func (sb *StringBuilder) Len() int {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.b.Len()
+}
+
+func (sb *StringBuilder) Type() ObjectType { return STRING_BUILDER }
+func (sb *StringBuilder) Inspect() string  { return sb.String() }
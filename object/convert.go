@@ -0,0 +1,184 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// FromGo converts a Go value to the object holding the same data. Integers,
+// floats, booleans, strings and nil convert to their basedlang counterparts,
+// slices and arrays to arrays, and maps keyed by integers, booleans or strings
+// to hashes with their keys in sorted order. Objects are returned as is.
+func FromGo(v any) (Object, error) {
+	switch v := v.(type) {
+	case nil:
+		return NullValue, nil
+	case Object:
+		return v, nil
+	case bool:
+		return boolObject(v), nil
+	case string:
+		return &String{Value: v}, nil
+	case int:
+		return &Integer{Value: int64(v)}, nil
+	case int64:
+		return &Integer{Value: v}, nil
+	case float64:
+		return &Float{Value: v}, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return boolObject(rv.Bool()), nil
+	case reflect.String:
+		return &String{Value: rv.String()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("object: %d overflows an integer", rv.Uint())
+		}
+		return &Integer{Value: int64(rv.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: rv.Float()}, nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return NullValue, nil
+		}
+		return FromGo(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return NullValue, nil
+		}
+		elems := make([]Object, rv.Len())
+		for i := range elems {
+			elem, err := FromGo(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = elem
+		}
+		return &Array{Elems: elems}, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return NullValue, nil
+		}
+		return mapFromGo(rv)
+	default:
+		return nil, fmt.Errorf("object: cannot convert %T", v)
+	}
+}
+
+func mapFromGo(rv reflect.Value) (Object, error) {
+	type pair struct {
+		key   Hashable
+		value reflect.Value
+	}
+
+	pairs := make([]pair, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		key, err := FromGo(iter.Key().Interface())
+		if err != nil {
+			return nil, err
+		}
+		hashable, ok := key.(Hashable)
+		if !ok {
+			return nil, fmt.Errorf("object: cannot use %s as a hash key", key.Type())
+		}
+		pairs = append(pairs, pair{hashable, iter.Value()})
+	}
+
+	// Go maps have no order, so the keys are sorted to keep hashes deterministic
+	sort.Slice(pairs, func(i, j int) bool {
+		a, b := pairs[i].key.HashKey(), pairs[j].key.HashKey()
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Int != b.Int {
+			return a.Int < b.Int
+		}
+		return a.Str < b.Str
+	})
+
+	hash := NewHash()
+	for _, p := range pairs {
+		value, err := FromGo(p.value.Interface())
+		if err != nil {
+			return nil, err
+		}
+		hash.Set(p.key, value)
+	}
+	return hash, nil
+}
+
+// ToGo converts an object to the Go value holding the same data, the inverse of
+// FromGo. Integers become int64, floats float64, arrays and tuples []any, and
+// hashes map[string]any when all of their keys are strings or map[any]any
+// otherwise. Objects without a Go counterpart, like functions, are returned
+// as is.
+func ToGo(obj Object) any {
+	switch obj := obj.(type) {
+	case nil, *Null:
+		return nil
+	case *Integer:
+		return obj.Value
+	case *Float:
+		return obj.Value
+	case *Boolean:
+		return obj.Value
+	case *String:
+		return obj.Value
+	case *Array:
+		return sliceToGo(obj.Elements())
+	case *Tuple:
+		return sliceToGo(obj.Elems)
+	case *Hash:
+		return hashToGo(obj)
+	default:
+		return obj
+	}
+}
+
+func sliceToGo(elems []Object) []any {
+	values := make([]any, len(elems))
+	for i, elem := range elems {
+		values[i] = ToGo(elem)
+	}
+	return values
+}
+
+func hashToGo(hash *Hash) any {
+	stringKeys := true
+	pairs := hash.Entries()
+	for _, pair := range pairs {
+		if pair.Key.Type() != STRING {
+			stringKeys = false
+			break
+		}
+	}
+
+	if stringKeys {
+		m := make(map[string]any, len(pairs))
+		for _, pair := range pairs {
+			m[pair.Key.(*String).Value] = ToGo(pair.Value)
+		}
+		return m
+	}
+
+	m := make(map[any]any, len(pairs))
+	for _, pair := range pairs {
+		m[ToGo(pair.Key)] = ToGo(pair.Value)
+	}
+	return m
+}
+
+func boolObject(b bool) *Boolean {
+	if b {
+		return TrueValue
+	}
+	return FalseValue
+}
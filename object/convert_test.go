@@ -0,0 +1,80 @@
+package object
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFromGo(t *testing.T) {
+	type celsius float32
+
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{"hi", "hi"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{celsius(21.5), "21.5"},
+		{[]int{1, 2}, "[1, 2]"},
+		{[2]any{"a", nil}, "[a, null]"},
+		{map[string]any{"b": 2, "a": []string{"x"}}, "{a: [x], b: 2}"},
+		{map[int]bool{3: true, 1: false}, "{1: false, 3: true}"},
+		{&Integer{Value: 5}, "5"},
+	}
+
+	for _, tc := range tests {
+		obj, err := FromGo(tc.input)
+		if err != nil {
+			t.Errorf("error converting %#v: %s", tc.input, err)
+			continue
+		}
+		if obj.Inspect() != tc.expected {
+			t.Errorf("wrong object for %#v. expected=%q, got=%q", tc.input, tc.expected, obj.Inspect())
+		}
+	}
+
+	if obj, _ := FromGo(false); obj != FalseValue {
+		t.Errorf("booleans must convert to the shared objects. got=%p", obj)
+	}
+
+	for _, invalid := range []any{uint64(math.MaxUint64), struct{}{}, map[float64]int{1: 1}} {
+		if _, err := FromGo(invalid); err == nil {
+			t.Errorf("expected an error converting %#v", invalid)
+		}
+	}
+}
+
+func TestToGo(t *testing.T) {
+	hash := NewHash()
+	hash.Set(&String{Value: "list"}, &Array{Elems: []Object{&Integer{Value: 1}, &Float{Value: 1.5}, NullValue}})
+	hash.Set(&String{Value: "ok"}, TrueValue)
+
+	mixed := NewHash()
+	mixed.Set(&Integer{Value: 1}, &String{Value: "one"})
+	mixed.Set(&String{Value: "two"}, &Tuple{Elems: []Object{FalseValue}})
+
+	fn := &Function{}
+
+	tests := []struct {
+		input    Object
+		expected any
+	}{
+		{NullValue, nil},
+		{&Integer{Value: 3}, int64(3)},
+		{&String{Value: "s"}, "s"},
+		{hash, map[string]any{"list": []any{int64(1), 1.5, nil}, "ok": true}},
+		{mixed, map[any]any{int64(1): "one", "two": []any{false}}},
+		{fn, fn},
+	}
+
+	for _, tc := range tests {
+		value := ToGo(tc.input)
+		if !reflect.DeepEqual(value, tc.expected) {
+			t.Errorf("wrong value for %s. expected=%#v, got=%#v", tc.input.Inspect(), tc.expected, value)
+		}
+	}
+}
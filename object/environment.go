@@ -1,14 +1,34 @@
 package object
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Environment is safe for concurrent use, so spawned tasks can share the
+// bindings of the scope they were created in.
+//
+// Hosts evaluating scripts on many goroutines against shared globals should
+// give each evaluation a local environment enclosing the globals, or a clone of
+// them. Either way, bindings made by one evaluation stay invisible to the
+// others, while reading the globals needs no copying in the former case.
 type Environment struct {
+	mu    sync.RWMutex
 	store map[string]Object
 	outer *Environment
+
+	// Whether programs are evaluated in the environment, which makes it the
+	// one global statements rebind names in
+	global atomic.Bool
 }
 
 func NewEnvironment() *Environment {
 	return &Environment{store: make(map[string]Object)}
 }
 
+// NewLocalEnvironment creates an environment enclosed by outer. Bindings are
+// looked up in outer when missing, but are only ever made in the new
+// environment.
 func NewLocalEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
@@ -16,14 +36,81 @@ func NewLocalEnvironment(outer *Environment) *Environment {
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
 	obj, exists := e.store[name]
+	e.mu.RUnlock()
 	if !exists && e.outer != nil {
-		obj, exists = e.outer.store[name]
+		obj, exists = e.outer.Get(name)
 	}
 	return obj, exists
 }
 
 func (e *Environment) Set(name string, val Object) Object {
+	e.mu.Lock()
 	e.store[name] = val
+	e.mu.Unlock()
 	return val
 }
+
+// MarkGlobal makes e the environment global statements rebind names in, when
+// they are evaluated in e or in the environments it encloses
+func (e *Environment) MarkGlobal() { e.global.Store(true) }
+
+// Global returns the innermost environment marked global out of e and the ones
+// enclosing it, or the outermost one if none is marked
+func (e *Environment) Global() *Environment {
+	env := e
+	for !env.global.Load() && env.outer != nil {
+		env = env.outer
+	}
+	return env
+}
+
+// Bindings returns a copy of the bindings made in e itself, which Restore puts
+// back. The bound objects themselves are shared.
+func (e *Environment) Bindings() map[string]Object {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	bindings := make(map[string]Object, len(e.store))
+	for name, obj := range e.store {
+		bindings[name] = obj
+	}
+	return bindings
+}
+
+// Restore replaces the bindings made in e itself with a copy of bindings. e
+// stays the same environment, so functions that closed over it see the
+// restored bindings and the ones made afterwards.
+func (e *Environment) Restore(bindings map[string]Object) {
+	store := make(map[string]Object, len(bindings))
+	for name, obj := range bindings {
+		store[name] = obj
+	}
+
+	e.mu.Lock()
+	e.store = store
+	e.mu.Unlock()
+}
+
+// Clone copies every binding visible from e into a new environment, so that
+// bindings made later on either side don't affect the other. The bound objects
+// themselves are shared.
+func (e *Environment) Clone() *Environment {
+	// Inner bindings shadow outer ones, so the outermost are copied first
+	var chain []*Environment
+	for env := e; env != nil; env = env.outer {
+		chain = append(chain, env)
+	}
+
+	clone := NewEnvironment()
+	for i := len(chain) - 1; i >= 0; i-- {
+		env := chain[i]
+		env.mu.RLock()
+		for name, obj := range env.store {
+			clone.store[name] = obj
+		}
+		env.mu.RUnlock()
+	}
+	return clone
+}
@@ -0,0 +1,99 @@
+package object
+
+// Equal reports whether a and b are structurally equal. Numbers are compared by
+// value whether they are integers or floats, strings by their contents, and
+// arrays, tuples and hashes element by element, regardless of the order hash
+// pairs were inserted in, and types by name. Any other objects are only equal to
+// themselves.
+func Equal(a, b Object) bool {
+	return equal(a, b, make(map[[2]Object]bool))
+}
+
+// equal compares a and b, where seen holds the pairs of composite values that
+// are already being compared further up. Meeting such a pair again means the
+// values are cyclic, and they are equal as long as the rest of them are.
+func equal(a, b Object, seen map[[2]Object]bool) bool {
+	if a == b {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value == b.Value
+		case *Float:
+			return float64(a.Value) == b.Value
+		}
+	case *Float:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value == float64(b.Value)
+		case *Float:
+			return a.Value == b.Value
+		}
+	case *Boolean:
+		if b, isBool := b.(*Boolean); isBool {
+			return a.Value == b.Value
+		}
+	case *String:
+		if b, isStr := b.(*String); isStr {
+			return a.Value == b.Value
+		}
+	case *Null:
+		_, isNull := b.(*Null)
+		return isNull
+	case *TypeValue:
+		if b, isType := b.(*TypeValue); isType {
+			return a.Name == b.Name
+		}
+	case *Array:
+		if b, isArr := b.(*Array); isArr {
+			return visit(a, b, seen, func() bool { return equalElems(a.Elements(), b.Elements(), seen) })
+		}
+	case *Tuple:
+		if b, isTuple := b.(*Tuple); isTuple {
+			return visit(a, b, seen, func() bool { return equalElems(a.Elems, b.Elems, seen) })
+		}
+	case *Hash:
+		if b, isHash := b.(*Hash); isHash {
+			return visit(a, b, seen, func() bool { return equalPairs(a, b, seen) })
+		}
+	}
+
+	return false
+}
+
+func visit(a, b Object, seen map[[2]Object]bool, compare func() bool) bool {
+	pair := [2]Object{a, b}
+	if seen[pair] {
+		return true
+	}
+	seen[pair] = true
+	return compare()
+}
+
+func equalElems(a, b []Object, seen map[[2]Object]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equal(a[i], b[i], seen) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalPairs(a, b *Hash, seen map[[2]Object]bool) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for _, pair := range a.Entries() {
+		other, exists := b.Get(pair.Key.(Hashable))
+		if !exists || !equal(pair.Value, other, seen) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package object
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	hash := func(pairs ...Object) *Hash {
+		h := NewHash()
+		for i := 0; i < len(pairs); i += 2 {
+			h.Set(pairs[i].(Hashable), pairs[i+1])
+		}
+		return h
+	}
+	arr := func(elems ...Object) *Array { return &Array{Elems: elems} }
+	num := func(v int64) *Integer { return &Integer{Value: v} }
+	str := func(v string) *String { return &String{Value: v} }
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{num(1), num(1), true},
+		{num(1), &Float{Value: 1}, true},
+		{num(1), str("1"), false},
+		{str("a"), str("a"), true},
+		{NullValue, NullValue, true},
+		{TrueValue, FalseValue, false},
+		{arr(num(1), str("a")), arr(num(1), str("a")), true},
+		{arr(num(1), arr(num(2))), arr(num(1), arr(num(2))), true},
+		{arr(num(1), arr(num(2))), arr(num(1), arr(num(3))), false},
+		{arr(num(1)), arr(num(1), num(2)), false},
+		{arr(num(1)), &Tuple{Elems: []Object{num(1)}}, false},
+		{hash(str("a"), num(1), str("b"), num(2)), hash(str("b"), num(2), str("a"), num(1)), true},
+		{hash(str("a"), arr(num(1))), hash(str("a"), arr(num(1))), true},
+		{hash(str("a"), num(1)), hash(str("a"), num(2)), false},
+		{hash(str("a"), num(1)), hash(str("b"), num(1)), false},
+		{hash(num(1), num(1)), hash(str("1"), num(1)), false},
+		{IntType, TypeOf(num(1)), true},
+		{&TypeValue{Name: "Int"}, IntType, true},
+		{IntType, StrType, false},
+		{IntType, str("Int"), false},
+	}
+
+	for _, tc := range tests {
+		if got := Equal(tc.a, tc.b); got != tc.expected {
+			t.Errorf("Equal(%s, %s) = %t, expected=%t", tc.a.Inspect(), tc.b.Inspect(), got, tc.expected)
+		}
+	}
+}
+
+func TestEqualCyclic(t *testing.T) {
+	a := &Array{Elems: []Object{&Integer{Value: 1}, nil}}
+	a.Elems[1] = a
+	b := &Array{Elems: []Object{&Integer{Value: 1}, nil}}
+	b.Elems[1] = b
+	if !Equal(a, b) {
+		t.Errorf("expected cyclic arrays with equal elements to be equal")
+	}
+
+	c := &Array{Elems: []Object{&Integer{Value: 2}, nil}}
+	c.Elems[1] = c
+	if Equal(a, c) {
+		t.Errorf("expected cyclic arrays with different elements to differ")
+	}
+
+	h := NewHash()
+	h.Set(&String{Value: "self"}, h)
+	g := NewHash()
+	g.Set(&String{Value: "self"}, g)
+	if !Equal(h, g) {
+		t.Errorf("expected cyclic hashes with equal pairs to be equal")
+	}
+}
@@ -0,0 +1,69 @@
+package object
+
+import (
+	"fmt"
+
+	"github.com/nayyara-airlangga/basedlang/token"
+)
+
+// ErrorKind categorizes runtime errors, so that hosts can react to kinds of
+// failures without parsing messages
+type ErrorKind string
+
+const (
+	// TypeError is an operation applied to values of the wrong type
+	TypeError ErrorKind = "TypeError"
+	// ValueError is an operation given a value of the right type that it still
+	// can't work with, like an invalid pattern or a closed channel
+	ValueError ErrorKind = "ValueError"
+	// NameError is a reference to an identifier or type that doesn't exist
+	NameError ErrorKind = "NameError"
+	// ArgumentError is a call with the wrong number of arguments
+	ArgumentError ErrorKind = "ArgumentError"
+	// ZeroDivisionError is an integer division by zero
+	ZeroDivisionError ErrorKind = "ZeroDivisionError"
+	// OverflowError is integer arithmetic whose result doesn't fit, only
+	// reported in strict mode
+	OverflowError ErrorKind = "OverflowError"
+	// LimitError is a script running past the fuel or memory it was given
+	LimitError ErrorKind = "LimitError"
+	// PermissionError is a builtin used without the capability it needs
+	PermissionError ErrorKind = "PermissionError"
+	// IOError is a failure of the host, like a missing file or a network error
+	IOError ErrorKind = "IOError"
+	// RaisedError is a failure signaled by the script itself with raise
+	RaisedError ErrorKind = "RaisedError"
+)
+
+// Error is a runtime error, which aborts evaluation until the host sees it. It
+// also implements the error interface so hosts can handle it like any other Go
+// error.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+
+	// Pos and End delimit the source that failed, both the zero position when
+	// the error didn't happen while evaluating a node
+	Pos token.Position
+	End token.Position
+
+	// Cause is the underlying error, if any, like the Go error a builtin failed
+	// with
+	Cause error
+
+	// Value is the value a RaisedError was raised with
+	Value Object
+}
+
+func (e *Error) Type() ObjectType { return ERROR }
+func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+
+// Error formats the error along with its kind and position, if known
+func (e *Error) Error() string {
+	if e.Pos == (token.Position{}) {
+		return fmt.Sprintf("%s: %s", e.Kind, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Pos.Line, e.Pos.Column, e.Kind, e.Message)
+}
+
+func (e *Error) Unwrap() error { return e.Cause }
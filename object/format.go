@@ -0,0 +1,147 @@
+package object
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormatOptions controls how Format prints objects. The zero value prints the
+// same as Inspect.
+type FormatOptions struct {
+	// MaxDepth is how many levels of nested arrays, tuples and hashes are
+	// printed before the deeper ones are elided as [...], (...) or {...}. Zero
+	// means no limit.
+	MaxDepth int
+	// MaxElems is how many elements or pairs of each array, tuple and hash are
+	// printed before the rest are summarized as ... N more. Zero means no limit.
+	MaxElems int
+	// QuoteStrings prints strings as quoted literals, telling "1" apart from 1
+	QuoteStrings bool
+	// Indent, when set, spreads arrays, tuples and hashes holding other ones
+	// over multiple lines, indenting every level by it
+	Indent string
+}
+
+// Format prints obj according to opts. Unlike Inspect, it stops at values that
+// contain themselves rather than recursing forever.
+func Format(obj Object, opts FormatOptions) string {
+	f := &formatter{opts: opts, visiting: make(map[Object]bool)}
+	f.format(obj, 0)
+	return f.out.String()
+}
+
+type formatter struct {
+	opts FormatOptions
+	out  strings.Builder
+
+	// Composite values being printed further up, to detect cycles
+	visiting map[Object]bool
+}
+
+func (f *formatter) format(obj Object, depth int) {
+	switch obj := obj.(type) {
+	case *String:
+		if f.opts.QuoteStrings {
+			f.out.WriteString(strconv.Quote(obj.Value))
+		} else {
+			f.out.WriteString(obj.Value)
+		}
+	case *Array:
+		elems := obj.Elements()
+		f.formatElems(obj, "[", "]", len(elems), depth, func(i int) {
+			f.format(elems[i], depth+1)
+		})
+	case *Tuple:
+		f.formatElems(obj, "(", ")", len(obj.Elems), depth, func(i int) {
+			f.format(obj.Elems[i], depth+1)
+		})
+	case *Hash:
+		pairs := obj.Entries()
+		f.formatElems(obj, "{", "}", len(pairs), depth, func(i int) {
+			pair := pairs[i]
+			f.format(pair.Key, depth+1)
+			f.out.WriteString(": ")
+			f.format(pair.Value, depth+1)
+		})
+	default:
+		f.out.WriteString(obj.Inspect())
+	}
+}
+
+// formatElems prints the n elements of the composite obj between opening and
+// closing, calling elem to print each one
+func (f *formatter) formatElems(obj Object, opening, closing string, n, depth int, elem func(i int)) {
+	if n > 0 && (f.visiting[obj] || f.opts.MaxDepth > 0 && depth >= f.opts.MaxDepth) {
+		f.out.WriteString(opening + "..." + closing)
+		return
+	}
+	f.visiting[obj] = true
+	defer delete(f.visiting, obj)
+
+	shown := n
+	if f.opts.MaxElems > 0 && n > f.opts.MaxElems {
+		shown = f.opts.MaxElems
+	}
+
+	multiline := f.opts.Indent != "" && f.nested(obj)
+	sep, inner, outer := ", ", "", ""
+	if multiline {
+		inner = "\n" + strings.Repeat(f.opts.Indent, depth+1)
+		outer = "\n" + strings.Repeat(f.opts.Indent, depth)
+		sep = "," + inner
+	}
+
+	f.out.WriteString(opening)
+	if n > 0 {
+		f.out.WriteString(inner)
+	}
+	for i := 0; i < shown; i++ {
+		if i > 0 {
+			f.out.WriteString(sep)
+		}
+		elem(i)
+	}
+	if shown < n {
+		if shown > 0 {
+			f.out.WriteString(sep)
+		}
+		fmt.Fprintf(&f.out, "... %d more", n-shown)
+	}
+	if n > 0 {
+		f.out.WriteString(outer)
+	}
+	f.out.WriteString(closing)
+}
+
+// nested reports whether obj holds arrays, tuples or hashes, which is when it
+// is worth spreading over multiple lines
+func (f *formatter) nested(obj Object) bool {
+	var elems []Object
+	switch obj := obj.(type) {
+	case *Array:
+		elems = obj.Elements()
+	case *Tuple:
+		elems = obj.Elems
+	case *Hash:
+		obj.Each(func(key, value Object) { elems = append(elems, value) })
+	}
+
+	for _, e := range elems {
+		switch e := e.(type) {
+		case *Array:
+			if e.Len() > 0 {
+				return true
+			}
+		case *Tuple:
+			if len(e.Elems) > 0 {
+				return true
+			}
+		case *Hash:
+			if e.Len() > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package object
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	num := func(v int64) *Integer { return &Integer{Value: v} }
+	arr := func(elems ...Object) *Array { return &Array{Elems: elems} }
+	hash := NewHash()
+	hash.Set(&String{Value: "name"}, &String{Value: "ada"})
+	hash.Set(&String{Value: "langs"}, arr(&String{Value: "go"}, &String{Value: "based"}))
+
+	cyclic := arr(num(1), nil)
+	cyclic.Elems[1] = cyclic
+
+	tests := []struct {
+		obj      Object
+		opts     FormatOptions
+		expected string
+	}{
+		{arr(num(1), &String{Value: "a"}), FormatOptions{}, "[1, a]"},
+		{arr(num(1), &String{Value: "a"}), FormatOptions{QuoteStrings: true}, `[1, "a"]`},
+		{hash, FormatOptions{QuoteStrings: true}, `{"name": "ada", "langs": ["go", "based"]}`},
+		{arr(num(1), num(2), num(3)), FormatOptions{MaxElems: 2}, "[1, 2, ... 1 more]"},
+		{arr(num(1), arr(num(2), arr(num(3)))), FormatOptions{MaxDepth: 2}, "[1, [2, [...]]]"},
+		{arr(arr(), NewHash()), FormatOptions{MaxDepth: 1}, "[[], {}]"},
+		{cyclic, FormatOptions{}, "[1, [...]]"},
+		{arr(num(1), num(2)), FormatOptions{Indent: "  "}, "[1, 2]"},
+		{hash, FormatOptions{Indent: "  ", QuoteStrings: true}, "{\n  \"name\": \"ada\",\n  \"langs\": [\"go\", \"based\"]\n}"},
+		{arr(arr(num(1)), num(2), num(3)), FormatOptions{Indent: "\t", MaxElems: 2}, "[\n\t[1],\n\t2,\n\t... 1 more\n]"},
+		{&Tuple{Elems: []Object{num(1), arr(num(2))}}, FormatOptions{Indent: " "}, "(\n 1,\n [2]\n)"},
+	}
+
+	for _, tc := range tests {
+		if got := Format(tc.obj, tc.opts); got != tc.expected {
+			t.Errorf("incorrect format with %+v. expected=%q, got=%q", tc.opts, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package object
+
+import (
+	"bytes"
+	"sync"
+)
+
+// HashKey identifies a key of a hash by value, so that equal integers, booleans
+// and strings find the same pair whatever object holds them
+type HashKey struct {
+	Type ObjectType
+	Int  int64  // integers, and booleans as 0 or 1
+	Str  string // strings
+}
+
+// Hashable is implemented by the objects usable as hash keys
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey { return HashKey{Type: INTEGER, Int: i.Value} }
+
+func (b *Boolean) HashKey() HashKey {
+	if b.Value {
+		return HashKey{Type: BOOLEAN, Int: 1}
+	}
+	return HashKey{Type: BOOLEAN}
+}
+
+func (s *String) HashKey() HashKey { return HashKey{Type: STRING, Str: s.Value} }
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash maps keys to values, iterating in the order keys were first inserted so
+// that printing and looping over a hash is deterministic. Tasks may share a
+// hash, so once it's been made its pairs are only read and written through its
+// methods.
+type Hash struct {
+	mu    sync.RWMutex
+	Pairs map[HashKey]HashPair
+	Keys  []HashKey // insertion order
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Set binds key to value, keeping the position of a key that was already set
+func (h *Hash) Set(key Hashable, value Object) {
+	hk := key.HashKey()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.Pairs[hk]; !exists {
+		h.Keys = append(h.Keys, hk)
+	}
+	h.Pairs[hk] = HashPair{Key: key, Value: value}
+}
+
+// Get returns the value bound to key
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	pair, exists := h.Pairs[key.HashKey()]
+	return pair.Value, exists
+}
+
+// Len returns the number of pairs in the hash
+func (h *Hash) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.Keys)
+}
+
+// Entries returns a copy of the pairs in insertion order, which later
+// assignments don't change
+func (h *Hash) Entries() []HashPair {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	pairs := make([]HashPair, len(h.Keys))
+	for i, hk := range h.Keys {
+		pairs[i] = h.Pairs[hk]
+	}
+	return pairs
+}
+
+// Each calls fn for every pair in insertion order. fn sees the pairs as they
+// were when Each was called, and may set pairs of the hash itself.
+func (h *Hash) Each(fn func(key, value Object)) {
+	for _, pair := range h.Entries() {
+		fn(pair.Key, pair.Value)
+	}
+}
+
+func (h *Hash) Type() ObjectType { return HASH }
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString("{")
+	i := 0
+	h.Each(func(key, value Object) {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(key.Inspect() + ": " + value.Inspect())
+		i++
+	})
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -0,0 +1,42 @@
+package object
+
+import (
+	"sync"
+
+	"github.com/nayyara-airlangga/basedlang/intern"
+)
+
+var interned = struct {
+	sync.RWMutex
+	strs map[string]*String
+}{strs: make(map[string]*String)}
+
+// InternString returns the shared String object holding the interned copy of
+// s, which like for intern.String should be known when parsing. Strings longer
+// than intern.MaxLen are not worth keeping around and get a fresh object
+// instead, as do new ones once the table holds intern.MaxEntries strings.
+func InternString(s string) *String {
+	if len(s) > intern.MaxLen {
+		return &String{Value: s}
+	}
+
+	interned.RLock()
+	str, exists := interned.strs[s]
+	interned.RUnlock()
+	if exists {
+		return str
+	}
+
+	interned.Lock()
+	defer interned.Unlock()
+
+	if str, exists = interned.strs[s]; !exists {
+		if len(interned.strs) >= intern.MaxEntries {
+			return &String{Value: s}
+		}
+		str = &String{Value: intern.String(s)}
+		interned.strs[str.Value] = str
+	}
+
+	return str
+}
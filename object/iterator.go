@@ -0,0 +1,130 @@
+package object
+
+import "sync"
+
+// Iterator is implemented by objects producing their values on demand, which
+// for loops and the next builtin consume
+type Iterator interface {
+	Object
+	// Next returns the next value, or false once there are none left
+	Next() (Object, bool)
+}
+
+// Lazy is an iterator computing each of its values only when it is asked for
+type Lazy struct {
+	mu   sync.Mutex
+	next func() (Object, bool)
+	done bool
+}
+
+// NewLazy creates an iterator producing the values next returns, until it
+// returns false for the first time
+func NewLazy(next func() (Object, bool)) *Lazy {
+	return &Lazy{next: next}
+}
+
+func (l *Lazy) Next() (Object, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.done {
+		return nil, false
+	}
+	val, ok := l.next()
+	if !ok {
+		l.done = true
+	}
+	return val, ok
+}
+
+func (l *Lazy) Type() ObjectType { return ITERATOR }
+func (l *Lazy) Inspect() string  { return "iterator" }
+
+// Generator runs a function body on a goroutine of its own, pausing it every
+// time it yields until the next value is asked for. The goroutine of a body
+// that is never finished lives on until Stop is called, which is up to whoever
+// created the generator.
+type Generator struct {
+	mu   sync.Mutex
+	body func(yield func(Object) bool) Object
+
+	started bool
+	done    bool
+
+	values chan Object
+	resume chan struct{}
+	stop   chan struct{}
+}
+
+// NewGenerator creates a generator running body once the first value is asked
+// for. Yielding hands a value out and reports whether the body should go on,
+// and it should return as soon as it's told not to. What body returns, if not
+// nil, is handed out as the last value.
+func NewGenerator(body func(yield func(Object) bool) Object) *Generator {
+	return &Generator{body: body}
+}
+
+func (g *Generator) Next() (Object, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.done {
+		return nil, false
+	}
+
+	if !g.started {
+		g.started = true
+		g.values = make(chan Object)
+		g.resume = make(chan struct{})
+		g.stop = make(chan struct{})
+		go runGenerator(g.body, g.values, g.resume, g.stop)
+	} else {
+		g.resume <- struct{}{}
+	}
+
+	val, ok := <-g.values
+	if !ok {
+		g.done = true
+		return nil, false
+	}
+	return val, true
+}
+
+func runGenerator(body func(yield func(Object) bool) Object, values chan<- Object, resume, stop <-chan struct{}) {
+	defer close(values)
+
+	yield := func(val Object) bool {
+		select {
+		case values <- val:
+		case <-stop:
+			return false
+		}
+		select {
+		case <-resume:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	if last := body(yield); last != nil {
+		select {
+		case values <- last:
+		case <-stop:
+		}
+	}
+}
+
+// Stop ends the generator early, unwinding its body from where it last yielded
+func (g *Generator) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.started && !g.done {
+		close(g.stop)
+	}
+	g.done = true
+}
+
+func (g *Generator) Type() ObjectType { return GENERATOR }
+func (g *Generator) Inspect() string  { return "generator" }
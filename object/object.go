@@ -3,6 +3,9 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/nayyara-airlangga/basedlang/ast"
 )
@@ -12,13 +15,27 @@ type ObjectType string
 const (
 	ERROR        ObjectType = "ERROR"
 	INTEGER      ObjectType = "INTEGER"
+	FLOAT        ObjectType = "FLOAT"
 	BOOLEAN      ObjectType = "BOOLEAN"
 	STRING       ObjectType = "STRING"
 	NULL         ObjectType = "NULL"
 	RETURN_VALUE ObjectType = "RETURN_VALUE"
 	FUNCTION     ObjectType = "FUNCTION"
 	ARRAY        ObjectType = "ARRAY"
+	TUPLE        ObjectType = "TUPLE"
 	BUILTIN      ObjectType = "BUILTIN"
+	TASK         ObjectType = "TASK"
+	CHANNEL      ObjectType = "CHANNEL"
+	HASH         ObjectType = "HASH"
+	EXIT         ObjectType = "EXIT"
+	GENERATOR    ObjectType = "GENERATOR"
+	ITERATOR     ObjectType = "ITERATOR"
+	INTERFACE    ObjectType = "INTERFACE"
+	QUOTE        ObjectType = "QUOTE"
+	MACRO        ObjectType = "MACRO"
+	TYPE         ObjectType = "TYPE"
+
+	STRING_BUILDER ObjectType = "STRING_BUILDER"
 )
 
 type Object interface {
@@ -26,12 +43,15 @@ type Object interface {
 	Inspect() string
 }
 
-type Error struct {
-	Message string
+// Exit signals that the program asked to stop with Code as its status. It
+// unwinds evaluation like an error does, leaving it to the host to decide what
+// stopping means.
+type Exit struct {
+	Code int64
 }
 
-func (e *Error) Type() ObjectType { return ERROR }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Exit) Type() ObjectType { return EXIT }
+func (e *Exit) Inspect() string  { return fmt.Sprintf("exit(%d)", e.Code) }
 
 type Integer struct {
 	Value int64
@@ -40,6 +60,22 @@ type Integer struct {
 func (i *Integer) Type() ObjectType { return INTEGER }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT }
+
+// Inspect always shows a fractional part or an exponent, so that floats with an
+// integral value don't look like integers
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -59,6 +95,13 @@ type Null struct{}
 func (n *Null) Type() ObjectType { return NULL }
 func (n *Null) Inspect() string  { return "null" }
 
+// The only null and boolean objects, which are compared by identity
+var (
+	NullValue  = &Null{}
+	TrueValue  = &Boolean{Value: true}
+	FalseValue = &Boolean{Value: false}
+)
+
 type ReturnValue struct {
 	Value Object
 }
@@ -67,9 +110,14 @@ func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Function struct {
-	Params []*ast.Identifier
-	Body   *ast.BlockStatement
-	Env    *Environment
+	// Name is what a let statement bound the function literal to, if anything
+	Name       string
+	Params     []*ast.Identifier
+	Variadic   bool
+	ReturnType *ast.TypeAnnotation
+	Body       *ast.BlockStatement
+	Env        *Environment
+	Generator  bool
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION }
@@ -81,31 +129,119 @@ func (f *Function) Inspect() string {
 
 	for i, p := range f.Params {
 		out.WriteString(p.String())
+		if f.Variadic && i+1 == len(f.Params) {
+			out.WriteString("...")
+		}
 		if i+1 != len(f.Params) {
 			out.WriteString(", ")
 		}
 	}
 
-	out.WriteString(") {\n")
+	out.WriteString(") ")
+	if f.ReturnType != nil {
+		out.WriteString("-> " + f.ReturnType.String() + " ")
+	}
+	out.WriteString("{\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
 
 	return out.String()
 }
 
+// Quote is code that wasn't evaluated, which macros take and return
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE }
+func (q *Quote) Inspect() string  { return "QUOTE(" + q.Node.String() + ")" }
+
+// Macro is a macro defined before the program runs, to be expanded wherever
+// it's called
+type Macro struct {
+	Params []*ast.Identifier
+	Body   *ast.BlockStatement
+	Env    *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO }
+func (m *Macro) Inspect() string {
+	params := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		params[i] = p.String()
+	}
+	return "macro(" + strings.Join(params, ", ") + ") {\n" + m.Body.String() + "\n}"
+}
+
+// Interface names the methods a value must provide. Values are hashes, whose
+// methods are the functions bound to string keys.
+type Interface struct {
+	Methods []string
+}
+
+// ImplementedBy reports whether obj provides every method of the interface
+func (i *Interface) ImplementedBy(obj Object) bool {
+	hash, isHash := obj.(*Hash)
+	if !isHash {
+		return false
+	}
+
+	for _, name := range i.Methods {
+		method, exists := hash.Get(InternString(name))
+		if !exists || method.Type() != FUNCTION && method.Type() != BUILTIN {
+			return false
+		}
+	}
+	return true
+}
+
+func (i *Interface) Type() ObjectType { return INTERFACE }
+func (i *Interface) Inspect() string {
+	return "interface { " + strings.Join(i.Methods, ", ") + " }"
+}
+
+// Array is a list of values whose elements can be assigned to. Tasks may share
+// an array, so once it's been made its elements are only read and written
+// through its methods.
 type Array struct {
+	mu    sync.RWMutex
 	Elems []Object
 }
 
+// Len returns the number of elements, which never changes
+func (a *Array) Len() int { return len(a.Elems) }
+
+// Get returns the element at index i
+func (a *Array) Get(i int) Object {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Elems[i]
+}
+
+// Set binds the element at index i to value
+func (a *Array) Set(i int, value Object) {
+	a.mu.Lock()
+	a.Elems[i] = value
+	a.mu.Unlock()
+}
+
+// Elements returns a copy of the elements, which later assignments don't change
+func (a *Array) Elements() []Object {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]Object(nil), a.Elems...)
+}
+
 func (a *Array) Type() ObjectType { return ARRAY }
 func (a *Array) Inspect() string {
 	var out bytes.Buffer
 
 	out.WriteString("[")
 
-	for i, e := range a.Elems {
+	elems := a.Elements()
+	for i, e := range elems {
 		out.WriteString(e.Inspect())
-		if i+1 != len(a.Elems) {
+		if i+1 != len(elems) {
 			out.WriteString(", ")
 		}
 	}
@@ -115,6 +251,28 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+type Tuple struct {
+	Elems []Object
+}
+
+func (t *Tuple) Type() ObjectType { return TUPLE }
+func (t *Tuple) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+
+	for i, e := range t.Elems {
+		out.WriteString(e.Inspect())
+		if i+1 != len(t.Elems) {
+			out.WriteString(", ")
+		}
+	}
+
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type BuiltinFn func(args ...Object) Object
 
 type Builtin struct {
@@ -123,3 +281,78 @@ type Builtin struct {
 
 func (b *Builtin) Type() ObjectType { return BUILTIN }
 func (b *Builtin) Inspect() string  { return "builtin function" }
+
+// Task is a handle to a function running on its own goroutine
+type Task struct {
+	done   chan struct{}
+	result Object
+}
+
+// NewTask starts run on a new goroutine and returns its handle right away
+func NewTask(run func() Object) *Task {
+	t := &Task{done: make(chan struct{})}
+
+	go func() {
+		defer close(t.done)
+		t.result = run()
+	}()
+
+	return t
+}
+
+// Await blocks until the task finishes and returns its result
+func (t *Task) Await() Object {
+	<-t.done
+	return t.result
+}
+
+func (t *Task) Type() ObjectType { return TASK }
+func (t *Task) Inspect() string  { return "task" }
+
+// Channel passes values between tasks, backed by a Go channel
+type Channel struct {
+	ch chan Object
+}
+
+// NewChannel creates a channel buffering up to size values, or an unbuffered one
+// when size is 0
+func NewChannel(size int) *Channel {
+	return &Channel{ch: make(chan Object, size)}
+}
+
+// Send blocks until val is handed over and reports false if the channel is closed
+func (c *Channel) Send(val Object) (sent bool) {
+	defer func() {
+		if recover() != nil {
+			sent = false
+		}
+	}()
+
+	c.ch <- val
+	return true
+}
+
+// Recv blocks until a value arrives and reports false once the channel is closed
+// and drained
+func (c *Channel) Recv() (Object, bool) {
+	val, ok := <-c.ch
+	return val, ok
+}
+
+// Close reports false if the channel was already closed
+func (c *Channel) Close() (closed bool) {
+	defer func() {
+		if recover() != nil {
+			closed = false
+		}
+	}()
+
+	close(c.ch)
+	return true
+}
+
+// Chan exposes the underlying channel for selecting over many channels at once
+func (c *Channel) Chan() chan Object { return c.ch }
+
+func (c *Channel) Type() ObjectType { return CHANNEL }
+func (c *Channel) Inspect() string  { return "channel" }
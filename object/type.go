@@ -0,0 +1,55 @@
+package object
+
+// TypeValue is a type as a value, which typeof returns and names like Int and
+// Str are bound to. Types compare equal by name, so typeof(x) == Int works.
+type TypeValue struct {
+	Name string
+}
+
+func (t *TypeValue) Type() ObjectType { return TYPE }
+func (t *TypeValue) Inspect() string  { return t.Name }
+
+// Types holds the type values predeclared in every script, by name
+var Types = map[string]*TypeValue{}
+
+// typesOf maps the types of objects to the type values they have, where several
+// object types may share one, like functions and builtins
+var typesOf = map[ObjectType]*TypeValue{}
+
+var (
+	IntType       = newType("Int", INTEGER)
+	FloatType     = newType("Float", FLOAT)
+	BoolType      = newType("Bool", BOOLEAN)
+	StrType       = newType("Str", STRING)
+	BuilderType   = newType("StringBuilder", STRING_BUILDER)
+	NullType      = newType("Null", NULL)
+	FnType        = newType("Fn", FUNCTION, BUILTIN)
+	ArrayType     = newType("Array", ARRAY)
+	TupleType     = newType("Tuple", TUPLE)
+	HashType      = newType("Hash", HASH)
+	TaskType      = newType("Task", TASK)
+	ChanType      = newType("Chan", CHANNEL)
+	GeneratorType = newType("Generator", GENERATOR)
+	IteratorType  = newType("Iterator", ITERATOR)
+	InterfaceType = newType("Interface", INTERFACE)
+	QuoteType     = newType("Quote", QUOTE)
+	TypeType      = newType("Type", TYPE)
+)
+
+func newType(name string, of ...ObjectType) *TypeValue {
+	t := &TypeValue{Name: name}
+	Types[name] = t
+	for _, objType := range of {
+		typesOf[objType] = t
+	}
+	return t
+}
+
+// TypeOf returns the type value of obj. Objects without a predeclared type, like
+// the ones plugins define, get a type named after their object type.
+func TypeOf(obj Object) *TypeValue {
+	if t, exists := typesOf[obj.Type()]; exists {
+		return t
+	}
+	return &TypeValue{Name: string(obj.Type())}
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/nayyara-airlangga/basedlang/token"
+)
+
+// Severity tells whether a diagnostic stops the program from running
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Diagnostic is a problem found while parsing, in a form tools can act on
+type Diagnostic struct {
+	Severity Severity
+	Pos      token.Position
+	End      token.Position // just past the offending source
+	Message  string
+
+	// The token that was expected and the one found instead, set when the
+	// parser stopped at an unexpected token
+	Expected token.TokenType
+	Got      token.TokenType
+}
+
+// String formats the diagnostic the way the REPL and CLI report it
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s", d.Pos.Line, d.Pos.Column, d.Message)
+}
+
+// errorAt records an error about the source from pos up to end
+func (p *Parser) errorAt(pos, end token.Position, format string, args ...any) {
+	p.errors = append(p.errors, Diagnostic{
+		Severity: SeverityError,
+		Pos:      pos,
+		End:      end,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/token"
+)
+
+// RegisterToken makes the lexer's tokens spelled literal have the type t from
+// now on. Only names, like mod, and single characters the lexer doesn't know,
+// like % or @, can be given a type, since other spellings already mean
+// something.
+func (p *Parser) RegisterToken(literal string, t token.TokenType) {
+	if p.tokens == nil {
+		p.tokens = make(map[string]token.TokenType)
+	}
+	p.tokens[literal] = t
+
+	// The first tokens were read before anything could be registered
+	p.curTok = p.retype(p.curTok)
+	p.peekTok = p.retype(p.peekTok)
+}
+
+// retype gives tok the type registered for its spelling, if any
+func (p *Parser) retype(tok token.Token) token.Token {
+	if tok.Type != token.IDENT && tok.Type != token.ILLEGAL {
+		return tok
+	}
+	if t, exists := p.tokens[tok.Literal]; exists {
+		tok.Type = t
+	}
+	return tok
+}
+
+// RegisterPrefix parses expressions starting with a token of type t using fn,
+// replacing how they were parsed before
+func (p *Parser) RegisterPrefix(t token.TokenType, fn PrefixParseFn) {
+	p.registerPrefix(t, fn)
+}
+
+// RegisterInfix parses expressions continuing with a token of type t using fn,
+// binding as tightly as pr. Both replace what t had before.
+func (p *Parser) RegisterInfix(t token.TokenType, pr Precedence, fn InfixParseFn) {
+	if p.precedences == nil {
+		p.precedences = make(map[token.TokenType]Precedence)
+	}
+	p.precedences[t] = pr
+	p.registerInfix(t, fn)
+}
+
+// ParsePrefixOperator is a PrefixParseFn for operators like -x, building a
+// prefix expression out of the current token and the operand after it
+func (p *Parser) ParsePrefixOperator() ast.Expression {
+	return p.parsePrefixExpression()
+}
+
+// ParseInfixOperator is an InfixParseFn for left-associative operators like
+// x + y, building an infix expression out of the current token and operands
+func (p *Parser) ParseInfixOperator(left ast.Expression) ast.Expression {
+	return p.parseInfixExpression(left)
+}
+
+// ParseExpression parses the expression starting at the current token, stopping
+// before any operator binding no tighter than pr
+func (p *Parser) ParseExpression(pr Precedence) ast.Expression {
+	return p.parseExpression(pr)
+}
+
+// CurToken returns the token being parsed
+func (p *Parser) CurToken() token.Token { return p.curTok }
+
+// PeekToken returns the token after the one being parsed
+func (p *Parser) PeekToken() token.Token { return p.peekTok }
+
+// NextToken moves on to the next token
+func (p *Parser) NextToken() { p.nextToken() }
+
+// ExpectPeek moves on to the next token if it has type t, and otherwise
+// records an error and returns false
+func (p *Parser) ExpectPeek(t token.TokenType) bool { return p.expectPeek(t) }
+
+// Errorf records an error about the token being parsed
+func (p *Parser) Errorf(format string, args ...any) {
+	p.errorAt(p.curTok.Pos(), p.curTok.End(), format, args...)
+}
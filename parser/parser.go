@@ -3,23 +3,30 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/intern"
 	"github.com/nayyara-airlangga/basedlang/lexer"
 	"github.com/nayyara-airlangga/basedlang/token"
 )
 
-// Pratt parser function types
+// Pratt parser function types. A prefix function starts at the current token,
+// while an infix function has the current token after its left operand. Both
+// leave the current token at the last one of the expression they parse.
 type (
-	prefixParseFn func() ast.Expression
-	infixParseFn  func(left ast.Expression) ast.Expression
+	PrefixParseFn func() ast.Expression
+	InfixParseFn  func(left ast.Expression) ast.Expression
 )
 
-type precedence int
+// Precedence orders operators by how tightly they bind their operands
+type Precedence int
 
 const (
-	_ precedence = iota
+	_ Precedence = iota
 	LOWEST
+	PIPELINE    // x |> f
+	ARROW       // x => x
 	EQUALS      // ==
 	LESSGREATER // > or <
 	SUM         // +
@@ -35,36 +42,65 @@ type Parser struct {
 	curTok  token.Token
 	peekTok token.Token
 
-	prefixParseFns map[token.TokenType]prefixParseFn
-	infixParseFns  map[token.TokenType]infixParseFn
+	// Doc comments right before curTok and peekTok
+	curDoc  string
+	peekDoc string
 
-	errors []string
+	prefixParseFns map[token.TokenType]PrefixParseFn
+	infixParseFns  map[token.TokenType]InfixParseFn
+
+	// Operators registered through the extension API
+	tokens      map[string]token.TokenType
+	precedences map[token.TokenType]Precedence
+
+	// Function whose body is being parsed, which yields make a generator
+	fn *ast.FunctionLiteral
+
+	errors []Diagnostic
+	// Whether the last statement parsed ran into errors, which leaves the
+	// tokens after where it stopped to be parsed as statements of their own
+	failed bool
 }
 
-func (p *Parser) registerPrefix(t token.TokenType, fn prefixParseFn) {
+func (p *Parser) registerPrefix(t token.TokenType, fn PrefixParseFn) {
 	p.prefixParseFns[t] = fn
 }
 
-func (p *Parser) registerInfix(t token.TokenType, fn infixParseFn) {
+func (p *Parser) registerInfix(t token.TokenType, fn InfixParseFn) {
 	p.infixParseFns[t] = fn
 }
 
 func (p *Parser) nextToken() {
-	p.curTok = p.peekTok
-	p.peekTok = p.l.NextToken()
+	p.curTok, p.curDoc = p.peekTok, p.peekDoc
+	p.peekTok, p.peekDoc = p.readToken()
+}
+
+// readToken reads the next token from the lexer, gathering the doc comments
+// before it so that they never reach the parse functions
+func (p *Parser) readToken() (token.Token, string) {
+	doc := []string{}
+
+	tok := p.l.NextToken()
+	for tok.Type == token.DOC_COMMENT {
+		doc = append(doc, tok.Literal)
+		tok = p.l.NextToken()
+	}
+
+	return p.retype(tok), strings.Join(doc, "\n")
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []Diagnostic{}}
 
 	// Set curTok and peekTok
 	p.nextToken()
 	p.nextToken()
 
 	// Register prefix functions
-	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
+	p.prefixParseFns = make(map[token.TokenType]PrefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.STRING, p.parseString)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
@@ -74,9 +110,14 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.PIPE, p.parseArrowFunction)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.INTERFACE, p.parseInterfaceLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
 
 	// Register infix functions
-	p.infixParseFns = make(map[token.TokenType]infixParseFn)
+	p.infixParseFns = make(map[token.TokenType]InfixParseFn)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NEQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
@@ -87,13 +128,17 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
+	p.registerInfix(token.FLOOR_DIV, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.ARROW, p.parseSingleParamArrowFunction)
+	p.registerInfix(token.PIPELINE, p.parsePipelineExpression)
 
 	return p
 }
 
-func (p *Parser) Errs() []string { return p.errors }
+// Errs returns the problems found while parsing, in the order they were found
+func (p *Parser) Errs() []Diagnostic { return p.errors }
 
 func (p *Parser) Parse() *ast.Program {
 	program := &ast.Program{Statements: []ast.Statement{}}
@@ -110,25 +155,52 @@ func (p *Parser) Parse() *ast.Program {
 }
 
 func (p *Parser) parseStatement() ast.Statement {
+	errs := len(p.errors)
+	stmt := p.parseStatementKind()
+	p.failed = len(p.errors) > errs
+	return stmt
+}
+
+func (p *Parser) parseStatementKind() ast.Statement {
 	switch p.curTok.Type {
 	case token.LET:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.YIELD:
+		return p.parseYieldStatement()
+	case token.RAISE:
+		return p.parseRaiseStatement()
+	case token.GLOBAL:
+		return p.parseGlobalStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.DO:
+		return p.parseDoWhileStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.curTok}
+	stmt := &ast.LetStatement{Token: p.curTok, Doc: p.curDoc}
 
 	// Expects an identifier after the let keyword
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+	stmt.Name = p.newBinding()
+	stmt.Names = []*ast.Identifier{stmt.Name}
+
+	// Tuple unpacking like let x, y = f();
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Names = append(stmt.Names, p.newBinding())
+	}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -152,6 +224,37 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
+	// Multiple return values are packed into a tuple
+	if p.peekTokenIs(token.COMMA) {
+		tuple := &ast.TupleExpression{Token: p.curTok, Elems: []ast.Expression{stmt.ReturnValue}}
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.nextToken()
+			tuple.Elems = append(tuple.Elems, p.parseExpression(LOWEST))
+		}
+		stmt.ReturnValue = tuple
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseYieldStatement() *ast.YieldStatement {
+	stmt := &ast.YieldStatement{Token: p.curTok}
+
+	if p.fn == nil {
+		p.errorAt(p.curTok.Pos(), p.curTok.End(), "yield outside of a function")
+	} else {
+		p.fn.Generator = true
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -159,11 +262,125 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+func (p *Parser) parseRaiseStatement() *ast.RaiseStatement {
+	stmt := &ast.RaiseStatement{Token: p.curTok}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseGlobalStatement() *ast.GlobalStatement {
+	stmt := &ast.GlobalStatement{Token: p.curTok}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = p.newIdentifier()
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseForStatement parses for (x in xs) { ... }, where the values may be
+// unpacked like in for (k, v in hash) { ... }
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curTok}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = p.newBinding()
+	stmt.Names = []*ast.Identifier{stmt.Name}
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Names = append(stmt.Names, p.newBinding())
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	return stmt
+}
+
+// parseDoWhileStatement parses do { ... } while (cond)
+func (p *Parser) parseDoWhileStatement() *ast.DoWhileStatement {
+	stmt := &ast.DoWhileStatement{Token: p.curTok}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	if !p.expectPeek(token.WHILE) {
+		return nil
+	}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExpressionStatement() ast.Statement {
 	stmt := &ast.ExpressionStatement{Token: p.curTok}
+	errs, leftover := len(p.errors), p.failed
 
 	stmt.Expression = p.parseExpression(LOWEST)
 
+	// Assignments only turn out to be ones after their target
+	if stmt.Expression != nil && (p.peekTokenIs(token.ASSIGN) || p.peekTokenIs(token.OR_ASSIGN) || p.peekTokenIs(token.NULLISH_ASSIGN)) {
+		return p.parseAssignStatement(stmt.Expression, !leftover && len(p.errors) == errs)
+	}
+
 	// Optional semicolon
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -172,7 +389,37 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
-func (p *Parser) parseExpression(pr precedence) ast.Expression {
+// parseAssignStatement parses target = value; with target already parsed and
+// the assignment operator, which may also be ||= or ??=, as peekTok. Only
+// elements of arrays and hashes can be assigned to, which is only reported
+// for targets that parsed cleanly, since others are likely leftovers of an
+// earlier error.
+func (p *Parser) parseAssignStatement(target ast.Expression, clean bool) ast.Statement {
+	p.nextToken()
+	stmt := &ast.AssignStatement{Token: p.curTok, Target: target, Operator: p.curTok.Literal}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if _, isIndex := target.(*ast.IndexExpression); !isIndex {
+		if clean {
+			p.errorAt(target.Pos(), ast.End(target), "cannot assign to %s", target.String())
+		}
+		return nil
+	}
+	if stmt.Value == nil {
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExpression(pr Precedence) ast.Expression {
 	prefixFn := p.prefixParseFns[p.curTok.Type]
 	if prefixFn == nil {
 		p.noPrefixParseFnErr(p.curTok.Type)
@@ -180,6 +427,10 @@ func (p *Parser) parseExpression(pr precedence) ast.Expression {
 	}
 
 	leftExpr := prefixFn()
+	// Carrying on would only build on the broken expression
+	if leftExpr == nil {
+		return nil
+	}
 
 	for !p.peekTokenIs(token.SEMICOLON) && pr < p.peekPrecedence() {
 		infixFn := p.infixParseFns[p.peekTok.Type]
@@ -196,7 +447,35 @@ func (p *Parser) parseExpression(pr precedence) ast.Expression {
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+	return p.newIdentifier()
+}
+
+// newIdentifier builds an identifier from curTok with its name interned, so
+// environment lookups compare names by pointer
+func (p *Parser) newIdentifier() *ast.Identifier {
+	return &ast.Identifier{Token: p.curTok, Value: intern.String(p.curTok.Literal)}
+}
+
+// newBinding builds an identifier being bound from curTok, along with the type
+// annotation that may follow it like in x: int
+func (p *Parser) newBinding() *ast.Identifier {
+	ident := p.newIdentifier()
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		ident.Type = p.parseTypeAnnotation()
+	}
+	return ident
+}
+
+// parseTypeAnnotation expects the next token to name a type
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	// fn is a keyword, but also the name of the function type
+	if !p.peekTokenIs(token.IDENT) && !p.peekTokenIs(token.FUNCTION) {
+		p.peekErr(token.IDENT)
+		return nil
+	}
+	p.nextToken()
+	return &ast.TypeAnnotation{Token: p.curTok, Name: p.curTok.Literal}
 }
 
 func (p *Parser) parseIntLiteral() ast.Expression {
@@ -204,8 +483,21 @@ func (p *Parser) parseIntLiteral() ast.Expression {
 
 	value, err := strconv.ParseInt(p.curTok.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curTok.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curTok.Pos(), p.curTok.End(), "could not parse %q as integer", p.curTok.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curTok}
+
+	value, err := strconv.ParseFloat(p.curTok.Literal, 64)
+	if err != nil {
+		p.errorAt(p.curTok.Pos(), p.curTok.End(), "could not parse %q as float", p.curTok.Literal)
 		return nil
 	}
 
@@ -270,6 +562,255 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expr
 }
 
+// parseMatchExpression parses match (x) { pattern if guard => body, ... }, where
+// guards are optional and bodies are blocks or single expressions. Arms with a
+// single expression body must be followed by a comma unless they come last.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expr := &ast.MatchExpression{Token: p.curTok}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expr.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if p.peekTokenIs(token.EOF) {
+			p.peekErr(token.RBRACE)
+			return nil
+		}
+		p.nextToken()
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expr.Arms = append(expr.Arms, arm)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if _, isBlock := arm.Body.(*ast.BlockStatement); !isBlock && !p.peekTokenIs(token.RBRACE) {
+			p.peekErr(token.COMMA)
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return expr
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.curTok}
+
+	arm.Pattern = p.parsePattern()
+	if arm.Pattern == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		// The arrow ends the guard rather than making it an arrow function
+		arm.Guard = p.parseExpression(ARROW)
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+	} else {
+		arm.Body = p.parseExpression(LOWEST)
+	}
+
+	return arm
+}
+
+// parsePattern parses the pattern starting at curTok
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curTok.Type {
+	case token.IDENT:
+		if p.curTok.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curTok}
+		}
+		// is only starts a type pattern when a type follows, so it can still
+		// be bound like any other name
+		if p.curTok.Literal == "is" && p.peekTokenIs(token.IDENT) {
+			return p.parseTypePattern()
+		}
+		return &ast.BindingPattern{Name: p.newIdentifier()}
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		return p.parseLiteralPattern()
+	case token.MINUS:
+		if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+			p.invalidPatternErr()
+			return nil
+		}
+		return p.parseLiteralPattern()
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	case token.LPAREN:
+		return p.parseTuplePattern()
+	default:
+		p.invalidPatternErr()
+		return nil
+	}
+}
+
+// parseTypePattern parses is Type, optionally followed by as name
+func (p *Parser) parseTypePattern() ast.Pattern {
+	pattern := &ast.TypePattern{Token: p.curTok}
+
+	p.nextToken()
+	pattern.Type = p.newIdentifier()
+
+	if p.peekTokenIs(token.IDENT) && p.peekTok.Literal == "as" {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		pattern.Binding = &ast.BindingPattern{Name: p.newIdentifier()}
+	}
+
+	return pattern
+}
+
+func (p *Parser) parseLiteralPattern() ast.Pattern {
+	value := p.prefixParseFns[p.curTok.Type]()
+	if value == nil {
+		return nil
+	}
+	return &ast.LiteralPattern{Value: value}
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curTok}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = p.parsePattern()
+			// Nothing may follow the rest, except a trailing comma
+			if p.peekTokenIs(token.COMMA) {
+				p.nextToken()
+			}
+			if !p.peekTokenIs(token.RBRACKET) {
+				p.errorAt(pattern.Rest.Pos(), ast.End(pattern.Rest), "rest pattern %s must come last", pattern.Rest.String())
+				return nil
+			}
+			break
+		}
+
+		elem := p.parsePattern()
+		if elem == nil {
+			return nil
+		}
+		pattern.Elems = append(pattern.Elems, elem)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curTok}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		// A name on its own binds the value of the key of the same name
+		if p.curTokenIs(token.IDENT) {
+			name := p.newIdentifier()
+			pattern.Keys = append(pattern.Keys, &ast.StringLiteral{Token: p.curTok, Value: name.Value})
+			pattern.Values = append(pattern.Values, &ast.BindingPattern{Name: name})
+		} else {
+			key := p.parsePattern()
+			literal, isLiteral := key.(*ast.LiteralPattern)
+			if !isLiteral {
+				if key != nil {
+					p.errorAt(key.Pos(), ast.End(key), "hash pattern keys must be literals or names")
+				}
+				return nil
+			}
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+
+			value := p.parsePattern()
+			if value == nil {
+				return nil
+			}
+			pattern.Keys = append(pattern.Keys, literal.Value)
+			pattern.Values = append(pattern.Values, value)
+		}
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return pattern
+}
+
+// parseTuplePattern parses (a, b), or a single pattern in parentheses
+func (p *Parser) parseTuplePattern() ast.Pattern {
+	pattern := &ast.TuplePattern{Token: p.curTok}
+	grouped := true
+
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+
+		elem := p.parsePattern()
+		if elem == nil {
+			return nil
+		}
+		pattern.Elems = append(pattern.Elems, elem)
+
+		if p.peekTokenIs(token.RPAREN) {
+			break
+		}
+		if !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		grouped = false
+	}
+
+	p.nextToken()
+
+	if grouped && len(pattern.Elems) == 1 {
+		return pattern.Elems[0]
+	}
+	return pattern
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	b := &ast.BlockStatement{Token: p.curTok, Statements: []ast.Statement{}}
 
@@ -293,20 +834,119 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	f.Params = p.parseFunctionParameters()
+	f.Params, f.Variadic = p.parseFunctionParameters(token.RPAREN)
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
+	if p.peekTokenIs(token.RARROW) {
+		p.nextToken()
+		f.ReturnType = p.parseTypeAnnotation()
+	}
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
-	f.Body = p.parseBlockStatement()
+	p.parseFunctionBody(f, p.parseBlockStatement)
+
+	// What a generator function returns is the generator, not its body's result
+	if f.Generator && f.ReturnType != nil {
+		p.errorAt(f.ReturnType.Pos(), f.ReturnType.Token.End(), "generator functions cannot declare a return type")
+	}
+
+	return f
+}
+
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	m := &ast.MacroLiteral{Token: p.curTok}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	var variadic bool
+	m.Params, variadic = p.parseFunctionParameters(token.RPAREN)
+	if variadic {
+		last := m.Params[len(m.Params)-1]
+		p.errorAt(last.Pos(), last.Token.End(), "macro parameter %s cannot be variadic", last.Value)
+	}
+
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// A macro body runs on its own, so it never makes the function around it a
+	// generator
+	outer := p.fn
+	p.fn = nil
+	m.Body = p.parseBlockStatement()
+	p.fn = outer
+
+	return m
+}
+
+// parseFunctionBody sets the body of f to what parse returns, attributing any
+// yields in it to f
+func (p *Parser) parseFunctionBody(f *ast.FunctionLiteral, parse func() *ast.BlockStatement) {
+	outer := p.fn
+	p.fn = f
+	f.Body = parse()
+	p.fn = outer
+}
+
+// parseArrowFunction desugars |x, y| x + y into fn(x, y) { x + y }
+func (p *Parser) parseArrowFunction() ast.Expression {
+	f := &ast.FunctionLiteral{Token: arrowFunctionToken(p.curTok)}
+
+	f.Params, f.Variadic = p.parseFunctionParameters(token.PIPE)
+
+	if !p.expectPeek(token.PIPE) {
+		return nil
+	}
+
+	p.parseFunctionBody(f, p.parseArrowFunctionBody)
 
 	return f
 }
 
+// parseSingleParamArrowFunction desugars x => x * 2 into fn(x) { x * 2 }
+func (p *Parser) parseSingleParamArrowFunction(left ast.Expression) ast.Expression {
+	param, isIdent := left.(*ast.Identifier)
+	if !isIdent {
+		p.errorAt(left.Pos(), ast.End(left), "invalid arrow function parameter %s", left.String())
+		return nil
+	}
+
+	f := &ast.FunctionLiteral{Token: arrowFunctionToken(param.Token), Params: []*ast.Identifier{param}}
+	p.parseFunctionBody(f, p.parseArrowFunctionBody)
+
+	return f
+}
+
+// parseArrowFunctionBody expects the next token to start the body, which is
+// either a block or a single expression
+func (p *Parser) parseArrowFunctionBody() *ast.BlockStatement {
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		return p.parseBlockStatement()
+	}
+
+	p.nextToken()
+
+	body := &ast.BlockStatement{Token: p.curTok}
+	stmt := &ast.ExpressionStatement{Token: p.curTok}
+	stmt.Expression = p.parseExpression(LOWEST)
+	body.Statements = []ast.Statement{stmt}
+
+	return body
+}
+
+// arrowFunctionToken makes the fn token of a desugared arrow function, placed at
+// the start of the arrow function
+func arrowFunctionToken(start token.Token) token.Token {
+	return token.Token{Type: token.FUNCTION, Literal: "fn", Line: start.Line, Column: start.Column}
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
 
@@ -317,7 +957,7 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 
 	p.nextToken()
 
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, p.parseListElement())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
@@ -327,7 +967,7 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 			return list
 		} else {
 			p.nextToken()
-			list = append(list, p.parseExpression(LOWEST))
+			list = append(list, p.parseListElement())
 		}
 	}
 
@@ -338,19 +978,113 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
+// parseListElement parses a single array element or call argument, which along
+// with hash literals are the only places a spread like ...arr is allowed
+func (p *Parser) parseListElement() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+
+	spread := &ast.SpreadExpression{Token: p.curTok}
+
+	p.nextToken()
+
+	spread.Value = p.parseExpression(LOWEST)
+
+	return spread
+}
+
+// parseInterfaceLiteral parses interface { name, ... } listing method names
+func (p *Parser) parseInterfaceLiteral() ast.Expression {
+	iface := &ast.InterfaceLiteral{Token: p.curTok, Methods: []string{}}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		name := p.curTok.Literal
+		if seen[name] {
+			p.errorAt(p.curTok.Pos(), p.curTok.End(), "duplicate method %s in interface", name)
+		}
+		seen[name] = true
+		iface.Methods = append(iface.Methods, intern.String(name))
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return iface
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	a := &ast.ArrayLiteral{Token: p.curTok}
 	a.Elems = p.parseExpressionList(token.RBRACKET)
 	return a
 }
 
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curTok, Keys: []ast.Expression{}, Values: []ast.Expression{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		// Spreading another hash merges in its pairs, like {...base, "x": 1}
+		if p.curTokenIs(token.ELLIPSIS) {
+			hash.Keys = append(hash.Keys, p.parseListElement())
+			hash.Values = append(hash.Values, nil)
+		} else {
+			key := p.parseExpression(LOWEST)
+
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+
+			p.nextToken()
+			value := p.parseExpression(LOWEST)
+
+			hash.Keys = append(hash.Keys, key)
+			hash.Values = append(hash.Values, value)
+		}
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	idx := &ast.IndexExpression{Token: p.curTok, Left: left}
 
 	p.nextToken()
 
+	// Open-ended slice like arr[:hi]
+	if p.curTokenIs(token.COLON) {
+		return p.parseSliceExpression(idx.Token, left, nil)
+	}
+
 	idx.Index = p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		return p.parseSliceExpression(idx.Token, left, idx.Index)
+	}
+
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
@@ -358,29 +1092,66 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return idx
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	params := []*ast.Identifier{}
+// parseSliceExpression expects curTok to be the colon separating both bounds
+func (p *Parser) parseSliceExpression(tok token.Token, left, low ast.Expression) ast.Expression {
+	slice := &ast.SliceExpression{Token: tok, Left: left, Low: low}
 
-	if p.peekTokenIs(token.RPAREN) {
-		return params
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		slice.High = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return slice
+}
+
+func (p *Parser) parseFunctionParameters(end token.TokenType) (params []*ast.Identifier, variadic bool) {
+	params = []*ast.Identifier{}
+
+	if p.peekTokenIs(end) {
+		return params, false
 	}
 
 	p.nextToken()
 
-	params = append(params, &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal})
+	params = append(params, p.newBinding())
+	variadic = p.parseVariadicMarker()
 
 	for p.peekTokenIs(token.COMMA) {
+		if variadic {
+			p.variadicNotLastErr(params[len(params)-1])
+		}
+
 		p.nextToken()
 		p.nextToken()
-		params = append(params, &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal})
+		params = append(params, p.newBinding())
+		variadic = p.parseVariadicMarker()
 	}
 
-	return params
+	return params, variadic
+}
+
+func (p *Parser) parseVariadicMarker() bool {
+	if !p.peekTokenIs(token.ELLIPSIS) {
+		return false
+	}
+	p.nextToken()
+	return true
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	call := &ast.CallExpression{Token: p.curTok, Function: function}
 	call.Args = p.parseExpressionList(token.RPAREN)
+
+	// dbg prints its argument the way it was written
+	if id, isIdent := function.(*ast.Identifier); isIdent && id.Value == "dbg" && call.Args != nil {
+		if text, ok := p.l.Text(call.Token.End(), p.curTok.Pos()); ok {
+			call.Source = strings.TrimSpace(text)
+		}
+	}
 	return call
 }
 
@@ -405,20 +1176,50 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expr
 }
 
+// parsePipelineExpression desugars x |> f into f(x) and x |> f(y) into f(x, y)
+func (p *Parser) parsePipelineExpression(left ast.Expression) ast.Expression {
+	tok := p.curTok
+
+	p.nextToken()
+
+	right := p.parseExpression(PIPELINE)
+	if right == nil {
+		return nil
+	}
+
+	if call, isCall := right.(*ast.CallExpression); isCall {
+		call.Args = append([]ast.Expression{left}, call.Args...)
+		return call
+	}
+
+	return &ast.CallExpression{Token: tok, Function: right, Args: []ast.Expression{left}}
+}
+
 func (p *Parser) noPrefixParseFnErr(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function found for %s", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.curTok.Pos(), p.curTok.End(), "no prefix parse function found for %s", t)
 }
 
-func getPrecedence(t token.TokenType) precedence {
+func (p *Parser) invalidPatternErr() {
+	p.errorAt(p.curTok.Pos(), p.curTok.End(), "invalid pattern starting with %s", p.curTok.Type)
+}
+
+func (p *Parser) variadicNotLastErr(param *ast.Identifier) {
+	p.errorAt(param.Pos(), param.Token.End(), "variadic parameter %s must be the last parameter", param.Value)
+}
+
+func getPrecedence(t token.TokenType) Precedence {
 	switch t {
+	case token.PIPELINE:
+		return PIPELINE
+	case token.ARROW:
+		return ARROW
 	case token.EQ, token.NEQ:
 		return EQUALS
 	case token.LT, token.GT, token.LTE, token.GTE:
 		return LESSGREATER
 	case token.PLUS, token.MINUS:
 		return SUM
-	case token.ASTERISK, token.SLASH:
+	case token.ASTERISK, token.SLASH, token.FLOOR_DIV:
 		return PRODUCT
 	case token.LPAREN:
 		return CALL
@@ -429,12 +1230,19 @@ func getPrecedence(t token.TokenType) precedence {
 	}
 }
 
-func (p *Parser) curPrecedence() precedence {
-	return getPrecedence(p.curTok.Type)
+func (p *Parser) curPrecedence() Precedence {
+	return p.precedenceOf(p.curTok.Type)
 }
 
-func (p *Parser) peekPrecedence() precedence {
-	return getPrecedence(p.peekTok.Type)
+func (p *Parser) peekPrecedence() Precedence {
+	return p.precedenceOf(p.peekTok.Type)
+}
+
+func (p *Parser) precedenceOf(t token.TokenType) Precedence {
+	if pr, exists := p.precedences[t]; exists {
+		return pr
+	}
+	return getPrecedence(t)
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -446,8 +1254,14 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 }
 
 func (p *Parser) peekErr(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekTok.Type)
-	p.errors = append(p.errors, msg)
+	p.errors = append(p.errors, Diagnostic{
+		Severity: SeverityError,
+		Pos:      p.peekTok.Pos(),
+		End:      p.peekTok.End(),
+		Message:  fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekTok.Type),
+		Expected: t,
+		Got:      p.peekTok.Type,
+	})
 }
 
 func (p *Parser) expectPeek(t token.TokenType) bool {
@@ -2,10 +2,13 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/nayyara-airlangga/basedlang/ast"
 	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/token"
 )
 
 func TestIdentifierExpression(t *testing.T) {
@@ -61,6 +64,25 @@ func TestIntLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	p := New(lexer.New("3.25; 10.0;"))
+	program := p.Parse()
+
+	checkParserErrors(t, p)
+
+	expected := []float64{3.25, 10}
+	for i, value := range expected {
+		stmt := program.Statements[i].(*ast.ExpressionStatement)
+		lit, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if lit.Value != value {
+			t.Errorf("lit.Value wrong. expected=%g, got=%g", value, lit.Value)
+		}
+	}
+}
+
 func TestBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -127,6 +149,158 @@ func TestArrayExpression(t *testing.T) {
 	testInfixExpression(t, array.Elems[2], 3, "+", 3)
 }
 
+func TestHashLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"one": 1, "two": 1 + 1, 3: true}`, `{one: 1, two: (1 + 1), 3: true}`},
+		{"{}", "{}"},
+		{`{"a": {"b": fn(x) { x }}}`, `{a: {b: fn(x) x}}`},
+		{`{...base, "x": 1, ...f(y)}`, `{...base, x: 1, ...f(y)}`},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.HashLiteral); !ok {
+			t.Fatalf("stmt.Expression is not *ast.HashLiteral. got=%T", stmt.Expression)
+		}
+		if stmt.Expression.String() != tc.expected {
+			t.Errorf("hash.String() wrong. expected=%q, got=%q", tc.expected, stmt.Expression.String())
+		}
+	}
+}
+
+func TestSpreadExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, ...a, 2]", "[1, ...a, 2]"},
+		{"[...a[1:], ...f(x)]", "[...(a[1:]), ...f(x)]"},
+		{"add(...args)", "add(...args)"},
+		{"add(1, ...a + b)", "add(1, ...(a + b))"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, actual)
+		}
+	}
+}
+
+func TestSpreadOutsideListIsInvalid(t *testing.T) {
+	p := New(lexer.New("let a = ...b;"))
+	p.Parse()
+
+	errors := p.Errs()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors for spread outside of a list")
+	}
+
+	expected := "no prefix parse function found for ..."
+	if errors[0].Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errors[0].Message)
+	}
+}
+
+func TestCustomOperators(t *testing.T) {
+	const (
+		MOD token.TokenType = "MOD"
+		POW token.TokenType = "POW"
+		NOT token.TokenType = "NOT"
+		AT  token.TokenType = "AT"
+	)
+
+	newParser := func(input string) *Parser {
+		p := New(lexer.New(input))
+		p.RegisterToken("%", MOD)
+		p.RegisterToken("mod", MOD)
+		p.RegisterInfix(MOD, PRODUCT, p.ParseInfixOperator)
+
+		// Right associative, binding tighter than * but looser than prefixes
+		p.RegisterToken("^", POW)
+		p.RegisterInfix(POW, PRODUCT+1, func(left ast.Expression) ast.Expression {
+			expr := &ast.InfixExpression{Token: p.CurToken(), Left: left, Operator: p.CurToken().Literal}
+			p.NextToken()
+			expr.Right = p.ParseExpression(PRODUCT)
+			return expr
+		})
+
+		p.RegisterToken("~", NOT)
+		p.RegisterPrefix(NOT, p.ParsePrefixOperator)
+
+		// @name reads a name and nothing else
+		p.RegisterToken("@", AT)
+		p.RegisterPrefix(AT, func() ast.Expression {
+			if !p.ExpectPeek(token.IDENT) {
+				return nil
+			}
+			if p.CurToken().Literal == "reserved" {
+				p.Errorf("@%s is reserved", p.CurToken().Literal)
+				return nil
+			}
+			return &ast.Identifier{Token: p.CurToken(), Value: "@" + p.CurToken().Literal}
+		})
+
+		return p
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2 % 3", "(1 + (2 % 3))"},
+		{"x mod 2 - 1", "((x mod 2) - 1)"},
+		{"2 ^ 3 ^ 2 * 4", "((2 ^ (3 ^ 2)) * 4)"},
+		{"~x == y", "((~x) == y)"},
+		{"@user", "@user"},
+	}
+
+	for _, tc := range tests {
+		p := newParser(tc.input)
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, actual)
+		}
+	}
+
+	invalid := []struct {
+		input    string
+		expected string
+	}{
+		{"@1", "expected next token to be IDENT, got INT instead"},
+		{"@reserved", "@reserved is reserved"},
+	}
+
+	for _, tc := range invalid {
+		p := newParser(tc.input)
+		p.Parse()
+
+		errors := p.Errs()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tc.input)
+			continue
+		}
+		if errors[0].Message != tc.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tc.expected, errors[0].Message)
+		}
+	}
+}
+
 func TestIndexExpression(t *testing.T) {
 	input := "arr[1 + 1]"
 
@@ -146,6 +320,51 @@ func TestIndexExpression(t *testing.T) {
 	testInfixExpression(t, idxExpr.Index, 1, "+", 1)
 }
 
+func TestSliceExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		low   any
+		high  any
+	}{
+		{"arr[1:2]", 1, 2},
+		{"arr[:2]", nil, 2},
+		{"arr[1:]", 1, nil},
+		{"arr[:]", nil, nil},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		slice, isSlice := stmt.Expression.(*ast.SliceExpression)
+		if !isSlice {
+			t.Fatalf("stmt.Expression is not *ast.SliceExpression. got=%T", stmt.Expression)
+		}
+		if !testIdentifier(t, slice.Left, "arr") {
+			return
+		}
+
+		if tc.low == nil {
+			if slice.Low != nil {
+				t.Errorf("slice.Low was not nil. got=%+v", slice.Low)
+			}
+		} else {
+			testLiteralExpression(t, slice.Low, tc.low)
+		}
+
+		if tc.high == nil {
+			if slice.High != nil {
+				t.Errorf("slice.High was not nil. got=%+v", slice.High)
+			}
+		} else {
+			testLiteralExpression(t, slice.High, tc.high)
+		}
+	}
+}
+
 func TestIfExpression(t *testing.T) {
 	input := `
 	if (x < y) {
@@ -414,6 +633,125 @@ func TestFunctionParams(t *testing.T) {
 	}
 }
 
+func TestVariadicFunctionParams(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		variadic bool
+		str      string
+	}{
+		{"fn(rest...) {};", []string{"rest"}, true, "fn(rest...) "},
+		{"fn(x, y, rest...) {};", []string{"x", "y", "rest"}, true, "fn(x, y, rest...) "},
+		{"fn(x, y) {};", []string{"x", "y"}, false, "fn(x, y) "},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		exprStmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := exprStmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Params) != len(tc.expected) {
+			t.Fatalf("Unexpected number of parameters. expected=%d, got=%d", len(tc.expected), len(function.Params))
+		}
+		for i, param := range tc.expected {
+			testLiteralExpression(t, function.Params[i], param)
+		}
+		if function.Variadic != tc.variadic {
+			t.Errorf("function.Variadic wrong. expected=%t, got=%t", tc.variadic, function.Variadic)
+		}
+		if function.String() != tc.str {
+			t.Errorf("function.String() wrong. expected=%q, got=%q", tc.str, function.String())
+		}
+	}
+}
+
+func TestVariadicParamNotLast(t *testing.T) {
+	p := New(lexer.New("fn(rest..., x) {};"))
+	p.Parse()
+
+	errors := p.Errs()
+	if len(errors) != 1 {
+		t.Fatalf("Unexpected number of parser errors. expected=%d, got=%d (%v)", 1, len(errors), errors)
+	}
+
+	expected := "variadic parameter rest must be the last parameter"
+	if errors[0].Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errors[0].Message)
+	}
+}
+
+func TestArrowFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"|x| x * 2", "fn(x) (x * 2)"},
+		{"|x, y| x + y", "fn(x, y) (x + y)"},
+		{"|| 1", "fn() 1"},
+		{"|x, rest...| rest", "fn(x, rest...) rest"},
+		{"|x| { let y = x; y }", "fn(x) let y = x;y"},
+		{"x => x * 2", "fn(x) (x * 2)"},
+		{"map(arr, x => x + 1)", "map(arr, fn(x) (x + 1))"},
+		{"map(arr, |x| x + 1)", "map(arr, fn(x) (x + 1))"},
+		{"x => y => x + y", "fn(x) fn(y) (x + y)"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, actual)
+		}
+	}
+}
+
+func TestArrowFunctionInvalidParam(t *testing.T) {
+	p := New(lexer.New("(a + b) => a"))
+	p.Parse()
+
+	errors := p.Errs()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors for invalid arrow function parameter")
+	}
+
+	expected := "invalid arrow function parameter (a + b)"
+	if errors[0].Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errors[0].Message)
+	}
+}
+
+func TestPipelineExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x |> f", "f(x)"},
+		{"x |> f(y)", "f(x, y)"},
+		{"x |> f |> g(1) |> h", "h(g(f(x), 1))"},
+		{"a + 1 |> f", "f((a + 1))"},
+		{"x |> |y| y * 2", "fn(y) (y * 2)(x)"},
+		{"x |> fns[0]", "(fns[0])(x)"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, actual)
+		}
+	}
+}
+
 func TestCallExpression(t *testing.T) {
 	input := `add(1, 2 * 3, 4 + 5);`
 
@@ -633,6 +971,7 @@ func TestInfixExpressions(t *testing.T) {
 		{"5 - 5;", 5, "-", 5},
 		{"5 * 5;", 5, "*", 5},
 		{"5 / 5;", 5, "/", 5},
+		{"5 // 5;", 5, "//", 5},
 		{"5 > 5;", 5, ">", 5},
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
@@ -708,6 +1047,10 @@ func TestOperatorPrecedences(t *testing.T) {
 			"a + b / c",
 			"(a + (b / c))",
 		},
+		{
+			"a - b // c * d",
+			"(a - ((b // c) * d))",
+		},
 		{
 			"a + b * c + d / e - f",
 			"(((a + (b * c)) + (d / e)) - f)",
@@ -796,6 +1139,10 @@ func TestOperatorPrecedences(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a * b[1 + 1:c * 2] * d",
+			"((a * (b[(1 + 1):(c * 2)])) * d)",
+		},
 	}
 
 	for _, tc := range tests {
@@ -876,36 +1223,681 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
-func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
-	if s.TokenLiteral() != "let" {
-		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
-		return false
+func TestRaiseStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`raise "oops";`, "raise oops;"},
+		{"raise x + 1", "raise (x + 1);"},
+		{`fn(x) { if (x < 0) { raise {"code": x} } x }`, "fn(x) if (x < 0) raise {code: x};x"},
 	}
-	letStmt, ok := s.(*ast.LetStatement)
-	if !ok {
-		t.Errorf("s not *ast.LetStatement. got=%T", s)
-		return false
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
 	}
-	if letStmt.Name.Value != name {
-		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, letStmt.Name.Value)
-		return false
+}
+
+func TestGlobalStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"global x = 1;", "global x = 1;"},
+		{"fn() { global count = count + 1 }", "fn() global count = (count + 1);"},
 	}
-	if letStmt.Name.TokenLiteral() != name {
-		t.Errorf("letStmt.Name.TokenLiteral() not '%s'. got=%s",
-			name, letStmt.Name.TokenLiteral())
-		return false
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
 	}
-	return true
-}
 
-func checkParserErrors(t *testing.T, p *Parser) {
-	errors := p.Errs()
-	if len(errors) == 0 {
-		return
+	invalid := []struct {
+		input    string
+		expected string
+	}{
+		{"global 1 = 2", "expected next token to be IDENT, got INT instead"},
+		{"global x", "expected next token to be =, got EOF instead"},
+		{"global xs[0] = 1", "expected next token to be =, got [ instead"},
 	}
-	t.Errorf("parser has %d errors", len(errors))
-	for _, msg := range errors {
-		t.Errorf("parser error: %q", msg)
+
+	for _, tc := range invalid {
+		p := New(lexer.New(tc.input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %q", tc.input)
+			continue
+		}
+		if p.Errs()[0].Message != tc.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tc.input, tc.expected, p.Errs()[0].Message)
+		}
+	}
+}
+
+func TestGeneratorFunctions(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		generator bool
+	}{
+		{"fn() { yield 1; }", "fn() yield 1;", true},
+		{"fn(x) { yield x; yield x + 1; }", "fn(x) yield x;yield (x + 1);", true},
+		{"|x| { yield x }", "fn(x) yield x;", true},
+		{"fn() { fn() { yield 1; } }", "fn() fn() yield 1;", false},
+		{"fn() { 1 }", "fn() 1", false},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, actual)
+		}
+		fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+		if fn.Generator != tc.generator {
+			t.Errorf("wrong generator flag for %q. expected=%t, got=%t", tc.input, tc.generator, fn.Generator)
+		}
+	}
+}
+
+func TestInvalidYield(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"yield 1;", "yield outside of a function"},
+		{"fn() -> int { yield 1; }", "generator functions cannot declare a return type"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		p.Parse()
+
+		errors := p.Errs()
+		if len(errors) != 1 {
+			t.Fatalf("Unexpected number of parser errors. expected=%d, got=%d (%v)", 1, len(errors), errors)
+		}
+		if errors[0].Message != tc.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tc.expected, errors[0].Message)
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		names    []string
+		expected string
+	}{
+		{"for (x in xs) { print(x); }", []string{"x"}, "for (x in xs) print(x)"},
+		{"for (k, v in h) { k }", []string{"k", "v"}, "for (k, v in h) k"},
+		{"for (x in range(0, 3)) {}", []string{"x"}, "for (x in range(0, 3)) "},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("Unexpected number of statements. expected=%d, got=%d", 1, len(program.Statements))
+		}
+		stmt, isFor := program.Statements[0].(*ast.ForStatement)
+		if !isFor {
+			t.Fatalf("program.Statements[0] is not *ast.ForStatement. got=%T", program.Statements[0])
+		}
+		if len(stmt.Names) != len(tc.names) {
+			t.Fatalf("Unexpected number of names. expected=%d, got=%d", len(tc.names), len(stmt.Names))
+		}
+		for i, name := range tc.names {
+			testIdentifier(t, stmt.Names[i], name)
+		}
+		if actual := stmt.String(); actual != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, actual)
+		}
+	}
+}
+
+func TestDoWhileStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"do { print(x); } while (x < 3);", "do print(x) while ((x < 3));"},
+		{`do { let line = input(); } while (line != "") 1`, "do let line = input(); while ((line != ));1"},
+		{"do {} while (true)", "do  while (true);"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, actual)
+		}
+	}
+
+	invalid := []struct {
+		input    string
+		expected string
+	}{
+		{"do { x } until (x)", "expected next token to be WHILE, got IDENT instead"},
+		{"do { x } while x", "expected next token to be (, got IDENT instead"},
+		{"do x while (x)", "expected next token to be {, got IDENT instead"},
+	}
+
+	for _, tc := range invalid {
+		p := New(lexer.New(tc.input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %q", tc.input)
+			continue
+		}
+		if p.Errs()[0].Message != tc.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tc.input, tc.expected, p.Errs()[0].Message)
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { 1 => a, _ => b }", "match x { 1 => a, _ => b }"},
+		{`match (x) { -1 => a, "s" => b, true => c, 2.5 => d }`, `match x { (-1) => a, s => b, true => c, 2.5 => d }`},
+		{"match (x) { [a, ...rest] => a, [] => 0, [_, b,] => b }", "match x { [a, ...rest] => a, [] => 0, [_, b] => b }"},
+		{`match (x) { {name, "age": a} => name }`, "match x { {name: name, age: a} => name }"},
+		{"match (x) { (a, b) => a, (c) => c, (d,) => d }", "match x { (a, b) => a, c => c, (d) => d }"},
+		{"match (x) { n if n > 1 => n, _ => 0 }", "match x { n if (n > 1) => n, _ => 0 }"},
+		{"match (x) { n => { let y = n; y } _ => 0 }", "match x { n => let y = n;y, _ => 0 }"},
+		{"match (x) { f => y => y }", "match x { f => fn(y) y }"},
+		{"match (x) {}", "match x {  }"},
+		{"match (x) { is Int => 1, is Str as s => s, [is Float as f] => f }", "match x { is Int => 1, is Str as s => s, [is Float as f] => f }"},
+		{"match (x) { is Int as n if n > 0 => n }", "match x { is Int as n if (n > 0) => n }"},
+		// is is only a keyword when a type follows it
+		{"match (x) { is => is, [is, as] => as }", "match x { is => is, [is, as] => as }"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, actual)
+		}
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { [...rest, a] => a }", "rest pattern rest must come last"},
+		{"match (x) { f(1) => a }", "expected next token to be =>, got ( instead"},
+		{"match (x) { 1 + 1 => a }", "expected next token to be =>, got + instead"},
+		{"match (x) { {[a]: 1} => a }", "hash pattern keys must be literals or names"},
+		{"match (x) { -a => a }", "invalid pattern starting with -"},
+		{"match (x) { 1 => a 2 => b }", "expected next token to be ,, got INT instead"},
+		{"match (x) { is Int as => a }", "expected next token to be IDENT, got => instead"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		p.Parse()
+
+		errors := p.Errs()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tc.input)
+			continue
+		}
+		if errors[0].Message != tc.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tc.expected, errors[0].Message)
+		}
+	}
+}
+
+func TestInterfaceLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let Shape = interface { area, perimeter };", "let Shape = interface { area, perimeter };"},
+		{"interface {\n  next,\n}", "interface { next }"},
+		{"interface {}", "interface {  }"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, actual)
+		}
+	}
+}
+
+func TestInvalidInterfaceLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"interface { area, area }", "duplicate method area in interface"},
+		{"interface { area perimeter }", "expected next token to be ,, got IDENT instead"},
+		{`interface { "area" }`, "expected next token to be IDENT, got STRING instead"},
+		{"interface area", "expected next token to be {, got IDENT instead"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		p.Parse()
+
+		errors := p.Errs()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tc.input)
+			continue
+		}
+		if errors[0].Message != tc.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tc.expected, errors[0].Message)
+		}
+	}
+}
+
+func TestMacroLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let unless = macro(cond, body) { quote(if (!unquote(cond)) { unquote(body) }) };",
+			"let unless = macro(cond, body) quote(if (!unquote(cond)) unquote(body));"},
+		{"macro() { quote(1) }", "macro() quote(1)"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, actual)
+		}
+	}
+
+	p := New(lexer.New("macro(args...) { args }"))
+	p.Parse()
+
+	expected := "macro parameter args cannot be variadic"
+	if errors := p.Errs(); len(errors) == 0 || errors[0].Message != expected {
+		t.Errorf("expected error %q, got=%v", expected, errors)
+	}
+}
+
+func TestLetTupleUnpacking(t *testing.T) {
+	input := "let x, y, z = f();"
+
+	p := New(lexer.New(input))
+	program := p.Parse()
+
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("Unexpected number of statements. expected=%d, got=%d", 1, len(program.Statements))
+	}
+
+	stmt := program.Statements[0]
+	if !testLetStatement(t, stmt, "x") {
+		return
+	}
+
+	letStmt := stmt.(*ast.LetStatement)
+	expected := []string{"x", "y", "z"}
+	if len(letStmt.Names) != len(expected) {
+		t.Fatalf("Unexpected number of names. expected=%d, got=%d", len(expected), len(letStmt.Names))
+	}
+	for i, name := range expected {
+		testIdentifier(t, letStmt.Names[i], name)
+	}
+	if letStmt.String() != "let x, y, z = f();" {
+		t.Errorf("letStmt.String() wrong. got=%q", letStmt.String())
+	}
+}
+
+func TestReturnMultipleValues(t *testing.T) {
+	input := "return a, 1 + 2, true;"
+
+	p := New(lexer.New(input))
+	program := p.Parse()
+
+	checkParserErrors(t, p)
+
+	returnStmt, isReturn := program.Statements[0].(*ast.ReturnStatement)
+	if !isReturn {
+		t.Fatalf("program.Statements[0] is not *ast.ReturnStatement. got=%T", program.Statements[0])
+	}
+
+	tuple, isTuple := returnStmt.ReturnValue.(*ast.TupleExpression)
+	if !isTuple {
+		t.Fatalf("returnStmt.ReturnValue is not *ast.TupleExpression. got=%T", returnStmt.ReturnValue)
+	}
+	if len(tuple.Elems) != 3 {
+		t.Fatalf("Unexpected number of elements. expected=%d, got=%d", 3, len(tuple.Elems))
+	}
+
+	testIdentifier(t, tuple.Elems[0], "a")
+	testInfixExpression(t, tuple.Elems[1], 1, "+", 2)
+	testBooleanLiteral(t, tuple.Elems[2], true)
+}
+
+func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
+	if s.TokenLiteral() != "let" {
+		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
+		return false
+	}
+	letStmt, ok := s.(*ast.LetStatement)
+	if !ok {
+		t.Errorf("s not *ast.LetStatement. got=%T", s)
+		return false
+	}
+	if letStmt.Name.Value != name {
+		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, letStmt.Name.Value)
+		return false
+	}
+	if letStmt.Name.TokenLiteral() != name {
+		t.Errorf("letStmt.Name.TokenLiteral() not '%s'. got=%s",
+			name, letStmt.Name.TokenLiteral())
+		return false
+	}
+	return true
+}
+
+func checkParserErrors(t *testing.T, p *Parser) {
+	errors := p.Errs()
+	if len(errors) == 0 {
+		return
+	}
+	t.Errorf("parser has %d errors", len(errors))
+	for _, d := range errors {
+		t.Errorf("parser error: %q", d.String())
+	}
+	t.FailNow()
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let x: int, y = f();", "let x: int, y = f();"},
+		{"let f = fn(a: int, b: str) -> bool { a };", "let f = fn(a: int, b: str) -> bool a;"},
+		{"let f = fn(g: fn, rest: int...) -> fn { g };", "let f = fn(g: fn, rest: int...) -> fn g;"},
+		{"let f = |a: int| a;", "let f = fn(a: int) a;"},
+		{"fn() -> array {}", "fn() -> array "},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+}
+
+func TestTypeAnnotationPositions(t *testing.T) {
+	p := New(lexer.New("fn(a: int) -> bool { a }"))
+	program := p.Parse()
+
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	f, isFn := stmt.Expression.(*ast.FunctionLiteral)
+	if !isFn {
+		t.Fatalf("stmt.Expression is not *ast.FunctionLiteral. got=%T", stmt.Expression)
+	}
+
+	param := f.Params[0]
+	if param.Value != "a" || param.Type == nil || param.Type.Name != "int" {
+		t.Fatalf("param wrong. got=%q", param.String())
+	}
+	if pos := param.Type.Pos(); pos.Line != 1 || pos.Column != 7 {
+		t.Errorf("param type position wrong. got=%d:%d", pos.Line, pos.Column)
+	}
+	if f.ReturnType == nil || f.ReturnType.Name != "bool" {
+		t.Fatalf("f.ReturnType wrong. got=%v", f.ReturnType)
+	}
+}
+
+func TestInvalidTypeAnnotation(t *testing.T) {
+	p := New(lexer.New("let x: 5 = 5;"))
+	p.Parse()
+
+	errors := p.Errs()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors")
+	}
+	expected := Diagnostic{
+		Severity: SeverityError,
+		Pos:      token.Position{Line: 1, Column: 8},
+		End:      token.Position{Line: 1, Column: 9},
+		Message:  "expected next token to be IDENT, got INT instead",
+		Expected: token.IDENT,
+		Got:      token.INT,
+	}
+	if errors[0] != expected {
+		t.Errorf("wrong error. expected=%#v, got=%#v", expected, errors[0])
+	}
+	if errors[0].String() != "1:8: expected next token to be IDENT, got INT instead" {
+		t.Errorf("wrong error string. got=%q", errors[0].String())
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	input := `/// Adds two numbers.
+///
+/// Both must be integers.
+let add = fn(a, b) { a + /// ignored
+  b };
+let x = 1;
+/// trailing`
+
+	p := New(lexer.New(input))
+	program := p.Parse()
+
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("Unexpected number of statements. expected=%d, got=%d", 2, len(program.Statements))
+	}
+
+	expected := []string{"Adds two numbers.\n\nBoth must be integers.", ""}
+	for i, doc := range expected {
+		stmt := program.Statements[i].(*ast.LetStatement)
+		if stmt.Doc != doc {
+			t.Errorf("program.Statements[%d].Doc wrong. expected=%q, got=%q", i, doc, stmt.Doc)
+		}
+	}
+}
+
+func TestReparse(t *testing.T) {
+	src := strings.Join([]string{
+		"let a = 1;",
+		"/// Adds one",
+		"let inc = fn(x) {",
+		"  x + 1",
+		"};",
+		"let b = inc(a); let c = b * 2;",
+		"",
+		"if (c > 2) { print(c) }",
+		"let d = [a, b, c];",
+		"d",
+	}, "\n")
+
+	pos := func(line, column int) token.Position { return token.Position{Line: line, Column: column} }
+
+	tests := []struct {
+		name string
+		edit Edit
+	}{
+		{"change a number", Edit{Start: pos(1, 9), End: pos(1, 10), Text: "42"}},
+		{"insert lines", Edit{Start: pos(4, 9), End: pos(4, 9), Text: " * 2\n  + 3\n"}},
+		{"delete lines", Edit{Start: pos(2, 1), End: pos(6, 1), Text: ""}},
+		{"edit a doc comment", Edit{Start: pos(2, 5), End: pos(2, 8), Text: "Increments"}},
+		{"change a statement sharing a line", Edit{Start: pos(6, 9), End: pos(6, 15), Text: "a"}},
+		{"open a brace", Edit{Start: pos(8, 13), End: pos(8, 13), Text: "{ "}},
+		{"append a statement", Edit{Start: pos(10, 2), End: pos(10, 2), Text: "\nlet e = d[0];"}},
+		{"prepend a statement", Edit{Start: pos(1, 1), End: pos(1, 1), Text: "let z = 0;\n"}},
+		{"join lines", Edit{Start: pos(9, 18), End: pos(10, 1), Text: " "}},
+	}
+
+	for _, tc := range tests {
+		prev := New(lexer.New(src))
+		program := prev.Parse()
+		checkParserErrors(t, prev)
+
+		edited := applyEdit(src, tc.edit)
+		full := New(lexer.New(edited))
+		expected := full.Parse()
+
+		reparsed, errs := Reparse(program, edited, tc.edit)
+		if !reflect.DeepEqual(reparsed, expected) {
+			t.Errorf("%s: reparsed program differs from parsing it again.\nexpected=%s\ngot=%s", tc.name, expected, reparsed)
+		}
+		if len(full.Errs()) == 0 && len(errs) != 0 {
+			t.Errorf("%s: unexpected errors %v", tc.name, errs)
+		}
+	}
+}
+
+func TestReparseReusesStatements(t *testing.T) {
+	src := "let a = 1;\nlet b = 2;\nlet c = 3;\nlet d = 4;\nlet e = 5;"
+	program := New(lexer.New(src)).Parse()
+	last := program.Statements[4]
+
+	edit := Edit{Start: token.Position{Line: 1, Column: 9}, End: token.Position{Line: 1, Column: 10}, Text: "\n10"}
+	reparsed, _ := Reparse(program, applyEdit(src, edit), edit)
+
+	if reparsed.Statements[4] != last {
+		t.Errorf("statement after the edit was reparsed")
+	}
+	if pos := last.Pos(); pos.Line != 6 {
+		t.Errorf("reused statement wasn't moved. expected line 6, got=%d", pos.Line)
+	}
+}
+
+// applyEdit replaces the text edit covers in src
+func applyEdit(src string, edit Edit) string {
+	offset := func(pos token.Position) int {
+		lines := strings.SplitAfter(src, "\n")
+		n := 0
+		for _, line := range lines[:pos.Line-1] {
+			n += len(line)
+		}
+		return n + pos.Column - 1
+	}
+	return src[:offset(edit.Start)] + edit.Text + src[offset(edit.End):]
+}
+
+func TestAssignStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"arr[0] = 1;", "(arr[0]) = 1;"},
+		{`h["a"] = x + 1`, "(h[a]) = (x + 1);"},
+		{"grid[i][j] = grid[j][i]; grid", "((grid[i])[j]) = ((grid[j])[i]);grid"},
+		{"fn() { xs[-1] = 0 }", "fn() (xs[(-1)]) = 0;"},
+		{"xs[0] ||= 1;", "(xs[0]) ||= 1;"},
+		{`h["a"] ??= f(x)`, "(h[a]) ??= f(x);"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, actual)
+		}
+	}
+
+	invalid := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 1", "cannot assign to x"},
+		{"f(x) = 1", "cannot assign to f(x)"},
+		{"xs[1:] = [1]", "cannot assign to (xs[1:])"},
+		{"xs[0] = ;", "no prefix parse function found for ;"},
+		{"x ||= 1", "cannot assign to x"},
+		{"x ??= 1", "cannot assign to x"},
+	}
+
+	for _, tc := range invalid {
+		p := New(lexer.New(tc.input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %q", tc.input)
+			continue
+		}
+		if p.Errs()[0].Message != tc.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tc.input, tc.expected, p.Errs()[0].Message)
+		}
+	}
+
+	// Targets left over from an earlier error aren't reported as well
+	leftovers := []struct {
+		input    string
+		reported bool
+	}{
+		{"let 1 = 2;", false},
+		{"let (a, b = 1;", false},
+		{"fn() { let 1 = 2 }", false},
+		{"let x 5; y = 1", true},
+	}
+
+	for _, tc := range leftovers {
+		p := New(lexer.New(tc.input))
+		p.Parse()
+
+		reported := false
+		for _, err := range p.Errs() {
+			reported = reported || strings.HasPrefix(err.Message, "cannot assign to")
+		}
+		if reported != tc.reported {
+			t.Errorf("wrong errors for %q. got=%v", tc.input, p.Errs())
+		}
 	}
-	t.FailNow()
 }
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/token"
+)
+
+// Edit replaces the source from Start up to End, both positions in the source
+// before the edit, with Text
+type Edit struct {
+	Start token.Position
+	End   token.Position
+	Text  string
+}
+
+// Reparse parses src, the source prev was parsed from with edit applied,
+// reparsing only the top-level statements around the edit. Statements after
+// them are moved to where they are in src and reused, so prev must not be used
+// afterwards. They are also reused as they are, so prev should come from a
+// parse without errors. The diagnostics returned are only those of the
+// reparsed statements.
+func Reparse(prev *ast.Program, src string, edit Edit) (*ast.Program, []Diagnostic) {
+	stmts := prev.Statements
+	n := len(stmts)
+	if n == 0 {
+		p := New(lexer.New(src))
+		return p.Parse(), p.Errs()
+	}
+
+	// Statements run up to the start of the next one, so the edit touches the
+	// last statement starting before it up to the last one starting in it
+	first := 0
+	for first+1 < n && !positionLess(edit.Start, stmts[first+1].Pos()) {
+		first++
+	}
+	last := first
+	for last+1 < n && !positionLess(edit.End, stmts[last+1].Pos()) {
+		last++
+	}
+
+	// Statements right next to the edit may also change, like one losing its
+	// closing brace or a doc comment. Those sharing a line with the end of the
+	// edit are reparsed too, since their columns may change.
+	delta := strings.Count(edit.Text, "\n") - (edit.End.Line - edit.Start.Line)
+	reusable := make(map[token.Position]int)
+	for j := last + 2; j < n; j++ {
+		if start := stmts[j].Pos(); start.Line > edit.End.Line {
+			reusable[token.Position{Line: start.Line + delta, Column: start.Column}] = j
+		}
+	}
+
+	program := &ast.Program{Statements: append([]ast.Statement{}, stmts[:max(first-1, 0)]...)}
+
+	var p *Parser
+	if first <= 1 {
+		p = New(lexer.New(src))
+	} else {
+		start := stmts[first-1].Pos()
+		p = New(lexer.NewAt(src, offsetOf(src, start), start))
+		// The doc comment before the statement was left behind, unchanged
+		if let, isLet := stmts[first-1].(*ast.LetStatement); isLet {
+			p.curDoc = let.Doc
+		}
+	}
+
+	for !p.curTokenIs(token.EOF) {
+		if j, isReusable := reusable[p.curTok.Pos()]; isReusable {
+			for _, stmt := range stmts[j:] {
+				shiftLines(stmt, delta)
+			}
+			program.Statements = append(program.Statements, stmts[j:]...)
+			break
+		}
+
+		stmt := p.parseStatement()
+		if stmt != nil {
+			program.Statements = append(program.Statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	return program, p.Errs()
+}
+
+func positionLess(a, b token.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// offsetOf returns the byte offset of pos in src
+func offsetOf(src string, pos token.Position) int {
+	offset := 0
+	for line := 1; line < pos.Line; line++ {
+		offset += strings.IndexByte(src[offset:], '\n') + 1
+	}
+	return offset + pos.Column - 1
+}
+
+// shiftLines moves every token of the tree rooted at node down by delta lines
+func shiftLines(node ast.Node, delta int) {
+	if delta == 0 {
+		return
+	}
+
+	shifted := make(map[ast.Node]bool)
+	ast.Walk(node, func(n ast.Node) bool {
+		if shifted[n] {
+			return false
+		}
+		shifted[n] = true
+
+		if tok := reflect.ValueOf(n).Elem().FieldByName("Token"); tok.IsValid() {
+			line := tok.FieldByName("Line")
+			line.SetInt(line.Int() + int64(delta))
+		}
+		return true
+	})
+}
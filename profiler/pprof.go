@@ -0,0 +1,170 @@
+package profiler
+
+// The pprof format is the Profile message of
+// https://github.com/google/pprof/blob/main/proto/profile.proto, which is
+// simple enough to encode by hand instead of depending on a protobuf library
+
+// Field numbers of the messages used
+const (
+	profileSampleType  = 1
+	profileSample      = 2
+	profileLocation    = 4
+	profileFunction    = 5
+	profileStringTable = 6
+	profilePeriodType  = 11
+	profilePeriod      = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID       = 1
+	functionName     = 2
+	functionFilename = 4
+)
+
+// encodeProfile builds a profile where every sample counts nodes evaluated.
+// Each distinct function gets a Function, and each line of a function a
+// Location.
+func encodeProfile(samples []*sample, filename string) []byte {
+	strs := newStringTable()
+	functions := make(map[string]uint64)
+	locations := make(map[Frame]uint64)
+
+	var locs, funcs protobuf
+	locationOf := func(f Frame) uint64 {
+		if id, exists := locations[f]; exists {
+			return id
+		}
+
+		fnID, exists := functions[f.Function]
+		if !exists {
+			fnID = uint64(len(functions) + 1)
+			functions[f.Function] = fnID
+			funcs.message(profileFunction, func(m *protobuf) {
+				m.uint64(functionID, fnID)
+				m.int64(functionName, strs.index(f.Function))
+				m.int64(functionFilename, strs.index(filename))
+			})
+		}
+
+		id := uint64(len(locations) + 1)
+		locations[f] = id
+		locs.message(profileLocation, func(m *protobuf) {
+			m.uint64(locationID, id)
+			m.message(locationLine, func(m *protobuf) {
+				m.uint64(lineFunctionID, fnID)
+				m.int64(lineLine, int64(f.Line))
+			})
+		})
+		return id
+	}
+
+	var b protobuf
+	valueType := func(m *protobuf) {
+		m.int64(valueTypeType, strs.index("evaluations"))
+		m.int64(valueTypeUnit, strs.index("count"))
+	}
+	b.message(profileSampleType, valueType)
+
+	for _, s := range samples {
+		ids := make([]uint64, len(s.stack))
+		for i, f := range s.stack {
+			ids[i] = locationOf(f)
+		}
+		b.message(profileSample, func(m *protobuf) {
+			m.packed(sampleLocationID, ids)
+			m.packed(sampleValue, []uint64{uint64(s.count)})
+		})
+	}
+
+	b.buf = append(b.buf, locs.buf...)
+	b.buf = append(b.buf, funcs.buf...)
+	for _, s := range strs.strs {
+		b.string(profileStringTable, s)
+	}
+	b.message(profilePeriodType, valueType)
+	b.int64(profilePeriod, 1)
+
+	return b.buf
+}
+
+// stringTable numbers the strings of a profile, where the first one must be
+// empty
+type stringTable struct {
+	strs    []string
+	indices map[string]int64
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{strs: []string{""}, indices: map[string]int64{"": 0}}
+}
+
+func (t *stringTable) index(s string) int64 {
+	if i, exists := t.indices[s]; exists {
+		return i
+	}
+	i := int64(len(t.strs))
+	t.strs = append(t.strs, s)
+	t.indices[s] = i
+	return i
+}
+
+// protobuf encodes messages in the protocol buffer wire format
+type protobuf struct {
+	buf []byte
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.buf = append(b.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	b.buf = append(b.buf, byte(x))
+}
+
+func (b *protobuf) key(field int, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protobuf) uint64(field int, x uint64) {
+	b.key(field, wireVarint)
+	b.varint(x)
+}
+
+func (b *protobuf) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *protobuf) string(field int, s string) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(s)))
+	b.buf = append(b.buf, s...)
+}
+
+func (b *protobuf) packed(field int, xs []uint64) {
+	var m protobuf
+	for _, x := range xs {
+		m.varint(x)
+	}
+	b.string(field, string(m.buf))
+}
+
+func (b *protobuf) message(field int, encode func(m *protobuf)) {
+	var m protobuf
+	encode(&m)
+	b.string(field, string(m.buf))
+}
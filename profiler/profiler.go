@@ -0,0 +1,114 @@
+// Package profiler collects where scripts spend their evaluation, attributing
+// every node the interpreter evaluates to the stack of function calls it was
+// evaluated in, and writes the result as a pprof profile. Standard Go tooling
+// can then show script hotspots by function and line, including as a flame
+// graph with go tool pprof -http.
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Frame is a function call on the stack, at the line being evaluated in it
+type Frame struct {
+	Function string
+	Line     int
+}
+
+// Profile counts the nodes evaluated under each distinct call stack
+type Profile struct {
+	mu      sync.Mutex
+	samples map[string]*sample
+}
+
+type sample struct {
+	stack []Frame
+	count int64
+}
+
+func NewProfile() *Profile {
+	return &Profile{samples: make(map[string]*sample)}
+}
+
+// Record counts a node evaluated with stack, which starts with the innermost
+// call
+func (p *Profile) Record(stack []Frame) {
+	var key strings.Builder
+	for _, f := range stack {
+		key.WriteString(f.Function)
+		key.WriteByte(':')
+		key.WriteString(strconv.Itoa(f.Line))
+		key.WriteByte(';')
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, exists := p.samples[key.String()]
+	if !exists {
+		s = &sample{stack: append([]Frame(nil), stack...)}
+		p.samples[key.String()] = s
+	}
+	s.count++
+}
+
+// WriteFolded writes the profile to w in the folded stacks format read by
+// flamegraph.pl and similar tools, one stack per line starting from the
+// outermost call, like main:3;fib:5;fib:2 12
+func (p *Profile) WriteFolded(w io.Writer) error {
+	lines := []string{}
+	for _, s := range p.sorted() {
+		frames := make([]string, len(s.stack))
+		for i, f := range s.stack {
+			frames[len(s.stack)-1-i] = f.Function + ":" + strconv.Itoa(f.Line)
+		}
+		lines = append(lines, strings.Join(frames, ";")+" "+strconv.FormatInt(s.count, 10)+"\n")
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, ""))
+	return err
+}
+
+// WritePprof writes the profile to w in the gzipped protocol buffer format of
+// pprof, with every function placed in filename
+func (p *Profile) WritePprof(w io.Writer, filename string) error {
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(encodeProfile(p.sorted(), filename)); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// sorted returns the samples ordered by stack, since map iteration is random
+// and the same profile should always be written the same way
+func (p *Profile) sorted() []*sample {
+	p.mu.Lock()
+	samples := make([]*sample, 0, len(p.samples))
+	for _, s := range p.samples {
+		samples = append(samples, s)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool { return lessStack(samples[i].stack, samples[j].stack) })
+	return samples
+}
+
+// lessStack orders stacks starting from their outermost call, so calls made
+// from the same place end up next to each other
+func lessStack(a, b []Frame) bool {
+	for i := 1; i <= len(a) && i <= len(b); i++ {
+		fa, fb := a[len(a)-i], b[len(b)-i]
+		if fa != fb {
+			if fa.Function != fb.Function {
+				return fa.Function < fb.Function
+			}
+			return fa.Line < fb.Line
+		}
+	}
+	return len(a) < len(b)
+}
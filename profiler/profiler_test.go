@@ -0,0 +1,127 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestWriteFolded(t *testing.T) {
+	p := NewProfile()
+	p.Record([]Frame{{"main", 3}})
+	p.Record([]Frame{{"fib", 2}, {"main", 3}})
+	p.Record([]Frame{{"fib", 2}, {"main", 3}})
+	p.Record([]Frame{{"fib", 2}, {"fib", 5}, {"main", 3}})
+
+	expected := "main:3 1\nmain:3;fib:2 2\nmain:3;fib:5;fib:2 1\n"
+
+	var out bytes.Buffer
+	if err := p.WriteFolded(&out); err != nil {
+		t.Fatalf("WriteFolded returned error: %s", err)
+	}
+	if out.String() != expected {
+		t.Errorf("WriteFolded wrong.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestWritePprof(t *testing.T) {
+	p := NewProfile()
+	p.Record([]Frame{{"fib", 2}, {"main", 3}})
+	p.Record([]Frame{{"fib", 2}, {"main", 3}})
+	p.Record([]Frame{{"main", 3}})
+
+	var out bytes.Buffer
+	if err := p.WritePprof(&out, "fib.based"); err != nil {
+		t.Fatalf("WritePprof returned error: %s", err)
+	}
+	gz, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatalf("profile isn't gzipped: %s", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("profile isn't gzipped: %s", err)
+	}
+
+	fields := decodeFields(t, data)
+
+	strs := []string{}
+	for _, s := range fields[profileStringTable] {
+		strs = append(strs, string(s))
+	}
+	expectedStrs := []string{"", "evaluations", "count", "main", "fib.based", "fib"}
+	if !reflect.DeepEqual(strs, expectedStrs) {
+		t.Errorf("wrong string table. expected=%q, got=%q", expectedStrs, strs)
+	}
+
+	// Samples hold the locations of their stack, innermost first, and a count
+	expectedSamples := [][2][]uint64{
+		{{1}, {1}},
+		{{2, 1}, {2}},
+	}
+	if len(fields[profileSample]) != len(expectedSamples) {
+		t.Fatalf("wrong number of samples. expected=%d, got=%d", len(expectedSamples), len(fields[profileSample]))
+	}
+	for i, s := range fields[profileSample] {
+		sample := decodeFields(t, s)
+		locs := decodeVarints(t, sample[sampleLocationID][0])
+		values := decodeVarints(t, sample[sampleValue][0])
+		if !reflect.DeepEqual(locs, expectedSamples[i][0]) || !reflect.DeepEqual(values, expectedSamples[i][1]) {
+			t.Errorf("samples[%d] wrong. expected=%v, got=%v %v", i, expectedSamples[i], locs, values)
+		}
+	}
+
+	if len(fields[profileLocation]) != 2 || len(fields[profileFunction]) != 2 {
+		t.Errorf("wrong number of locations and functions. expected=2 and 2, got=%d and %d",
+			len(fields[profileLocation]), len(fields[profileFunction]))
+	}
+}
+
+// decodeFields splits a message into the values of its fields, holding varints
+// as their encoding
+func decodeFields(t *testing.T, data []byte) map[int][][]byte {
+	fields := make(map[int][][]byte)
+	for len(data) > 0 {
+		key, n := readVarint(t, data)
+		data = data[n:]
+
+		switch key & 7 {
+		case wireVarint:
+			_, n := readVarint(t, data)
+			fields[int(key>>3)] = append(fields[int(key>>3)], data[:n])
+			data = data[n:]
+		case wireBytes:
+			size, n := readVarint(t, data)
+			data = data[n:]
+			fields[int(key>>3)] = append(fields[int(key>>3)], data[:size])
+			data = data[size:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return fields
+}
+
+func decodeVarints(t *testing.T, data []byte) []uint64 {
+	xs := []uint64{}
+	for len(data) > 0 {
+		x, n := readVarint(t, data)
+		xs = append(xs, x)
+		data = data[n:]
+	}
+	return xs
+}
+
+func readVarint(t *testing.T, data []byte) (uint64, int) {
+	var x uint64
+	for i, b := range data {
+		x |= uint64(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			return x, i + 1
+		}
+	}
+	t.Fatalf("truncated varint")
+	return 0, 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+
+	"github.com/nayyara-airlangga/basedlang/evaluator"
+	"github.com/nayyara-airlangga/basedlang/repl"
+)
+
+func replCmd(args []string) int {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	noColor := fs.Bool("no-color", false, "disable colored output")
+	prompt := fs.String("prompt", ">> ", "prompt shown before every input")
+	warnings := fs.Bool("warnings", false, "warn about unused bindings and unreachable code in inputs")
+	strict := fs.Bool("strict", false, "make ! on non-booleans and integer overflow errors")
+	listen := fs.String("listen", "", "serve the REPL over TCP on `addr` instead, with a session per connection. Clients aren't authenticated, so sessions can't use builtins reaching outside the interpreter unless -trusted is set")
+	trusted := fs.Bool("trusted", false, "with -listen, let sessions use every builtin and :save and :load files")
+	fuel := fs.Int64("fuel", repl.ServeFuel, "with -listen, how many `steps` each session may evaluate, 0 for unlimited")
+	memory := fs.Int64("memory", repl.ServeMemory, "with -listen, how many `bytes` of values each session may create, 0 for unlimited")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *listen != "" {
+		l, err := net.Listen("tcp", *listen)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Basedlang %s REPL listening on %s\n", version, l.Addr())
+
+		// Clients aren't authenticated, so unless they are trusted they can't
+		// reach anything outside the interpreter
+		caps := evaluator.CapNone
+		if *trusted {
+			caps = evaluator.CapAll
+		}
+
+		// Connections may not be terminals, so their output is never colored
+		if err := repl.Serve(l,
+			repl.WithPrompt(*prompt),
+			repl.WithWarnings(*warnings),
+			repl.WithStrict(*strict),
+			repl.WithCapabilities(caps),
+			repl.WithFileCommands(*trusted),
+			repl.WithFuel(*fuel),
+			repl.WithMemoryLimit(*memory)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Printf("Basedlang %s on %s %s\n", version, runtime.GOOS, runtime.GOARCH)
+	fmt.Println("Type away!")
+
+	return repl.Start(os.Stdin, os.Stdout,
+		repl.WithPrompt(*prompt),
+		repl.WithColor(!*noColor && colorSupported(os.Stdout)),
+		repl.WithWarnings(*warnings),
+		repl.WithStrict(*strict))
+}
@@ -4,46 +4,416 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 
+	"github.com/nayyara-airlangga/basedlang/ast"
 	"github.com/nayyara-airlangga/basedlang/evaluator"
 	"github.com/nayyara-airlangga/basedlang/lexer"
 	"github.com/nayyara-airlangga/basedlang/object"
 	"github.com/nayyara-airlangga/basedlang/parser"
+	"github.com/nayyara-airlangga/basedlang/resolver"
+	"github.com/nayyara-airlangga/basedlang/token"
 )
 
 const prompt string = ">> "
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+// ANSI escape codes used when coloring output
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorDim    = "\033[2m"
+)
+
+// defaultFormat pretty-prints arrays, tuples and hashes, eliding what wouldn't
+// fit on a screen anyway
+var defaultFormat = object.FormatOptions{
+	MaxDepth:     6,
+	MaxElems:     50,
+	QuoteStrings: true,
+	Indent:       "  ",
+}
+
+type config struct {
+	prompt   string
+	color    bool
+	warnings bool
+	strict   bool
+	format   object.FormatOptions
+	globals  *object.Environment
+	caps     evaluator.Capability
+	fuel     int64
+	memory   int64
+	// Whether :save and :load may touch the file system
+	fileCommands bool
+}
+
+type Option func(c *config)
+
+// WithPrompt replaces the default >> prompt
+func WithPrompt(prompt string) Option {
+	return func(c *config) {
+		c.prompt = prompt
+	}
+}
+
+// WithColor toggles coloring errors red, results green and types dim. Output is
+// plain by default since it may not be going to a terminal.
+func WithColor(color bool) Option {
+	return func(c *config) {
+		c.color = color
+	}
+}
+
+// WithWarnings toggles reporting unused bindings and unreachable code in every
+// input before evaluating it
+func WithWarnings(warnings bool) Option {
+	return func(c *config) {
+		c.warnings = warnings
+	}
+}
+
+// WithStrict evaluates inputs in the interpreter's strict mode
+func WithStrict(strict bool) Option {
+	return func(c *config) {
+		c.strict = strict
+	}
+}
+
+// WithFormat replaces how array, tuple and hash results are printed, which is
+// indented and truncated by default
+func WithFormat(format object.FormatOptions) Option {
+	return func(c *config) {
+		c.format = format
+	}
+}
+
+// WithGlobals makes the bindings of env visible to every session, which is how
+// a host exposes its values to consoles served with Serve. Bindings made in a
+// session stay in the session's own environment.
+func WithGlobals(env *object.Environment) Option {
+	return func(c *config) {
+		c.globals = env
+	}
+}
+
+// WithCapabilities only allows builtins called in the session to use caps,
+// which is evaluator.CapAll by default
+func WithCapabilities(caps evaluator.Capability) Option {
+	return func(c *config) {
+		c.caps = caps
+	}
+}
+
+// WithFuel limits a session to evaluating steps nodes over its lifetime, like
+// evaluator.WithFuel. It's unlimited by default, except in sessions served with
+// Serve.
+func WithFuel(steps int64) Option {
+	return func(c *config) {
+		c.fuel = steps
+	}
+}
+
+// WithMemoryLimit limits the approximate bytes of values a session creates
+// over its lifetime, like evaluator.WithMemoryLimit. It's unlimited by default,
+// except in sessions served with Serve.
+func WithMemoryLimit(bytes int64) Option {
+	return func(c *config) {
+		c.memory = bytes
+	}
+}
+
+// WithFileCommands toggles the :save and :load commands, which write and read
+// any file the process can. They are enabled by default, except in sessions
+// served with Serve.
+func WithFileCommands(enabled bool) Option {
+	return func(c *config) {
+		c.fileCommands = enabled
+	}
+}
+
+// paint wraps s in the given color when coloring is enabled
+func (c *config) paint(color, s string) string {
+	if !c.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// Start runs the REPL until in runs out or the program calls exit, returning
+// the status passed to exit or else 0
+func Start(in io.Reader, out io.Writer, opts ...Option) int {
+	c := &config{prompt: prompt, format: defaultFormat, caps: evaluator.CapAll, fileCommands: true}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// Scripts calling input read from the same buffer as the REPL, so neither
+	// swallows lines meant for the other. What they write to stderr, like the
+	// output of dbg, goes to the session's user too.
+	reader := bufio.NewReader(in)
+	interpreterOpts := []evaluator.Option{
+		evaluator.WithStdin(reader),
+		evaluator.WithStdout(out),
+		evaluator.WithStderr(out),
+		evaluator.WithCapabilities(c.caps),
+		evaluator.WithFuel(c.fuel),
+		evaluator.WithMemoryLimit(c.memory),
+	}
+	if c.strict {
+		interpreterOpts = append(interpreterOpts, evaluator.WithStrict())
+	}
 	env := object.NewEnvironment()
+	if c.globals != nil {
+		env = object.NewLocalEnvironment(c.globals)
+	}
+	s := &session{
+		config:      c,
+		out:         out,
+		env:         env,
+		interpreter: evaluator.New(interpreterOpts...),
+		macros:      object.NewEnvironment(),
+	}
+	defer s.interpreter.Close()
 
 	for {
-		fmt.Fprint(out, prompt)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
+		fmt.Fprint(out, c.prompt)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return 0
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if strings.HasPrefix(line, ":") {
+			s.runCommand(line)
+			continue
+		}
+
+		s.last = line
+		if exit := s.eval(line); exit != nil {
+			return int(exit.Code)
 		}
+	}
+}
+
+// session is the state of a single REPL run
+type session struct {
+	*config
+	out io.Writer
+
+	env         *object.Environment
+	interpreter *evaluator.Interpreter
+	// Macros defined by earlier inputs, which later ones can call
+	macros *object.Environment
+
+	// Last evaluated input, which inspection commands fall back to
+	last string
+	// Inputs that evaluated without errors, in order, for :save
+	history []string
+	// States saved by :snapshot, the latest last, for :rollback
+	snapshots []snapshot
+}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
+// snapshot is the state of a session that :rollback returns to. Bindings are
+// restored into the session's environments, but the values they hold are
+// shared with the session.
+type snapshot struct {
+	env     map[string]object.Object
+	macros  map[string]object.Object
+	history int
+}
+
+// eval evaluates input in the session's environment and prints its result. It
+// returns the exit signal when input calls exit.
+func (s *session) eval(input string) *object.Exit {
+	p := parser.New(lexer.New(input))
+
+	program := p.Parse()
+	if len(p.Errs()) != 0 {
+		s.printParserErrors(s.out, input, p.Errs())
+		return nil
+	}
+	program, expandErr := evaluator.ExpandMacros(program, s.macros)
+	if expandErr != nil {
+		s.printResult(s.out, expandErr)
+		s.printSnippet(s.out, input, expandErr.Pos, expandErr.End)
+		return nil
+	}
+
+	if s.warnings {
+		s.printWarnings(s.out, program)
+	}
 
+	evaluated := s.interpreter.Eval(program, s.env)
+	if exit, isExit := evaluated.(*object.Exit); isExit {
+		return exit
+	}
+	if evaluated == nil || evaluated.Type() != object.ERROR {
+		s.history = append(s.history, input)
+	}
+
+	s.printResult(s.out, evaluated)
+	if err, isErr := evaluated.(*object.Error); isErr {
+		s.printSnippet(s.out, input, err.Pos, err.End)
+	}
+	return nil
+}
+
+// runCommand handles REPL commands, which start with a colon. Commands that
+// inspect code use the given input or else the last evaluated one.
+func (s *session) runCommand(line string) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(line, ":"), " ")
+	arg = strings.TrimSpace(arg)
+
+	input := arg
+	if input == "" {
+		input = s.last
+	}
+
+	if (name == "save" || name == "load") && !s.fileCommands {
+		s.printError(":" + name + " is disabled in this session")
+		return
+	}
+
+	switch name {
+	case "tokens":
+		printTokens(s.out, input)
+	case "ast":
+		p := parser.New(lexer.New(input))
 		program := p.Parse()
 		if len(p.Errs()) != 0 {
-			printParserErrors(out, p.Errs())
-			continue
+			s.printParserErrors(s.out, input, p.Errs())
+			return
 		}
+		ast.Fprint(s.out, program)
+	case "save":
+		s.save(arg)
+	case "load":
+		s.load(arg)
+	case "snapshot":
+		s.snapshots = append(s.snapshots, snapshot{env: s.env.Bindings(), macros: s.macros.Bindings(), history: len(s.history)})
+		fmt.Fprintf(s.out, "snapshot %d taken\n", len(s.snapshots))
+	case "rollback":
+		s.rollback()
+	default:
+		s.printError("unknown command :" + name)
+	}
+}
 
-		if evaluated := evaluator.Eval(program, env); evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
-		}
+// save writes every input that evaluated successfully to path, so the session
+// can be resumed with :load
+func (s *session) save(path string) {
+	if path == "" {
+		s.printError("usage: :save <file>")
+		return
+	}
+
+	var content strings.Builder
+	for _, input := range s.history {
+		content.WriteString(input + "\n")
+	}
+
+	if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+		s.printError(err.Error())
+		return
+	}
+
+	fmt.Fprintf(s.out, "saved %d inputs to %s\n", len(s.history), path)
+}
+
+// rollback returns the session to the latest snapshot, undoing the bindings
+// and macros made since, and drops the snapshot
+func (s *session) rollback() {
+	if len(s.snapshots) == 0 {
+		s.printError("no snapshot to roll back to, take one with :snapshot")
+		return
+	}
+
+	snap := s.snapshots[len(s.snapshots)-1]
+	s.snapshots = s.snapshots[:len(s.snapshots)-1]
+	s.env.Restore(snap.env)
+	s.macros.Restore(snap.macros)
+	s.history = s.history[:snap.history]
+
+	fmt.Fprintf(s.out, "rolled back to snapshot %d\n", len(s.snapshots)+1)
+}
+
+// load replays the script at path into the current environment
+func (s *session) load(path string) {
+	if path == "" {
+		s.printError("usage: :load <file>")
+		return
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		s.printError(err.Error())
+		return
+	}
+
+	// Exiting from a loaded script only stops the script
+	s.eval(strings.TrimRight(string(src), "\n"))
+}
+
+func printTokens(out io.Writer, input string) {
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(out, "%d:%d\t%s\t%q\n", tok.Line, tok.Column, tok.Type, tok.Literal)
+	}
+}
+
+// printResult shows a value along with its type like => 6 : INTEGER. Statements
+// without a result, like let bindings, and null results print nothing. Arrays,
+// tuples and hashes are printed with the configured format.
+func (c *config) printResult(out io.Writer, evaluated object.Object) {
+	if evaluated == nil || evaluated.Type() == object.NULL {
+		return
+	}
+
+	if evaluated.Type() == object.ERROR {
+		fmt.Fprintln(out, c.paint(colorRed, evaluated.Inspect()))
+		return
+	}
+
+	result := evaluated.Inspect()
+	switch evaluated.Type() {
+	case object.ARRAY, object.TUPLE, object.HASH:
+		result = object.Format(evaluated, c.format)
+	}
+
+	fmt.Fprintf(out, "%s %s\n",
+		c.paint(colorGreen, "=> "+result),
+		c.paint(colorDim, ": "+string(evaluated.Type())))
+}
+
+func (s *session) printError(msg string) {
+	fmt.Fprintln(s.out, s.paint(colorRed, msg))
+}
+
+func (c *config) printParserErrors(out io.Writer, input string, errors []parser.Diagnostic) {
+	io.WriteString(out, c.paint(colorRed, " parser errors:")+"\n")
+	for _, d := range errors {
+		io.WriteString(out, "\t"+c.paint(colorRed, d.String())+"\n")
+		c.printSnippet(out, input, d.Pos, d.End)
+	}
+}
+
+// printWarnings reports what the resolver warns about in program. Its errors
+// are left out, since names bound by earlier inputs are unknown to it.
+func (c *config) printWarnings(out io.Writer, program *ast.Program) {
+	r := resolver.New(evaluator.BuiltinNames())
+	r.Resolve(program)
+	for _, msg := range r.Warnings() {
+		io.WriteString(out, c.paint(colorYellow, msg)+"\n")
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
-	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+// printSnippet quotes the line of input from start up to end with a caret
+// under it, printing nothing for errors without a position
+func (c *config) printSnippet(out io.Writer, input string, start, end token.Position) {
+	if line, caret, ok := token.Snippet(input, start, end); ok {
+		fmt.Fprintf(out, "\t%s\n\t%s\n", line, c.paint(colorRed, caret))
 	}
 }
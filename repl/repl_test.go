@@ -0,0 +1,386 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nayyara-airlangga/basedlang/evaluator"
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+func TestStart(t *testing.T) {
+	input := strings.Join([]string{
+		"let a = 5;",
+		"a + 1",
+		`"hi"`,
+		"if (false) { 1 }",
+		"a + true",
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := prompt +
+		prompt + "=> 6 : INTEGER\n" +
+		prompt + "=> hi : STRING\n" +
+		prompt +
+		prompt + "ERROR: type mismatch: INTEGER + BOOLEAN\n" +
+		"\ta + true\n" +
+		"\t^~~~~~~~\n" +
+		prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestExit(t *testing.T) {
+	input := strings.Join([]string{
+		"1",
+		"exit(3)",
+		"2",
+	}, "\n")
+
+	var out bytes.Buffer
+	status := Start(strings.NewReader(input), &out)
+
+	if status != 3 {
+		t.Errorf("wrong status. expected=3, got=%d", status)
+	}
+	expected := prompt + "=> 1 : INTEGER\n" + prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestInput(t *testing.T) {
+	input := strings.Join([]string{
+		`let name = input("name? ")`,
+		"Ada",
+		"name",
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := prompt + "name? " + prompt + "=> Ada : STRING\n" + prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestDbg(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("let x = 2;\ndbg(x * 3) + 1"), &out)
+
+	expected := prompt +
+		prompt + "[1:1] x * 3 = 6\n" + "=> 7 : INTEGER\n" +
+		prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestMacros(t *testing.T) {
+	input := strings.Join([]string{
+		"let double = macro(x) { quote(unquote(x) * 2) };",
+		"double(1 + 2)",
+		"double()",
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := prompt + prompt + "=> 6 : INTEGER\n" + prompt +
+		"ERROR: wrong number of arguments. got=0, want=1\n" + "\tdouble()\n\t^~~~~~~~\n" + prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	input := "let f = fn() { return 1; 2 }; f()"
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out, WithWarnings(true))
+
+	expected := prompt + "1:26: warning: unreachable code\n" + "=> 1 : INTEGER\n" + prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestPrettyPrint(t *testing.T) {
+	input := strings.Join([]string{
+		`["a", 1]`,
+		`{"xs": [1, 2, 3], "ys": []}`,
+		`[[1, 2, 3]]`,
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out, WithFormat(object.FormatOptions{
+		MaxElems:     2,
+		QuoteStrings: true,
+		Indent:       "  ",
+	}))
+
+	expected := prompt + "=> [\"a\", 1] : ARRAY\n" +
+		prompt + "=> {\n  \"xs\": [1, 2, ... 1 more],\n  \"ys\": []\n} : HASH\n" +
+		prompt + "=> [\n  [1, 2, ... 1 more]\n] : ARRAY\n" +
+		prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestInspectionCommands(t *testing.T) {
+	input := strings.Join([]string{
+		"let x = -1 + 2;",
+		":tokens",
+		":ast",
+		`:tokens f("a")`,
+		":what",
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := prompt +
+		prompt +
+		"1:1\tLET\t\"let\"\n" +
+		"1:5\tIDENT\t\"x\"\n" +
+		"1:7\t=\t\"=\"\n" +
+		"1:9\t-\t\"-\"\n" +
+		"1:10\tINT\t\"1\"\n" +
+		"1:12\t+\t\"+\"\n" +
+		"1:14\tINT\t\"2\"\n" +
+		"1:15\t;\t\";\"\n" +
+		prompt +
+		"Program\n" +
+		"  LetStatement\n" +
+		"    Identifier x\n" +
+		"    InfixExpression +\n" +
+		"      PrefixExpression -\n" +
+		"        IntLiteral 1\n" +
+		"      IntLiteral 2\n" +
+		prompt +
+		"1:1\tIDENT\t\"f\"\n" +
+		"1:2\t(\t\"(\"\n" +
+		"1:3\tSTRING\t\"a\"\n" +
+		"1:6\t)\t\")\"\n" +
+		prompt +
+		"unknown command :what\n" +
+		prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestColorAndPrompt(t *testing.T) {
+	input := strings.Join([]string{
+		"1 + 1",
+		"foobar",
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out, WithPrompt("λ "), WithColor(true))
+
+	expected := "λ " +
+		colorGreen + "=> 2" + colorReset + " " + colorDim + ": INTEGER" + colorReset + "\n" +
+		"λ " +
+		colorRed + "ERROR: identifier not found: foobar" + colorReset + "\n" +
+		"\tfoobar\n" +
+		"\t" + colorRed + "^~~~~~" + colorReset + "\n" +
+		"λ "
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.based")
+
+	input := strings.Join([]string{
+		"let a = 5;",
+		"a + true",
+		"let double = |x| x * 2;",
+		"foobar",
+		":save " + path,
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("session was not saved: %s", err)
+	}
+	expectedSaved := "let a = 5;\nlet double = |x| x * 2;\n"
+	if string(saved) != expectedSaved {
+		t.Errorf("wrong saved session.\nexpected=%q\ngot=%q", expectedSaved, string(saved))
+	}
+
+	out.Reset()
+	input = strings.Join([]string{
+		":load " + path,
+		"double(a)",
+		":load",
+	}, "\n")
+	Start(strings.NewReader(input), &out)
+
+	expected := prompt +
+		prompt + "=> 10 : INTEGER\n" +
+		prompt + "usage: :load <file>\n" +
+		prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader(`env("HOME")`), &out, WithCapabilities(evaluator.CapTime))
+
+	expected := prompt +
+		"ERROR: permission denied: env needs the process capability\n" +
+		"\tenv(\"HOME\")\n" +
+		"\t^~~~~~~~~~~\n" +
+		prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestSnapshotAndRollback(t *testing.T) {
+	input := strings.Join([]string{
+		"let a = 1;",
+		":snapshot",
+		"let a = 2;",
+		"let b = 3;",
+		":snapshot",
+		"let b = 4;",
+		":rollback",
+		"a + b",
+		":rollback",
+		"a",
+		"b",
+		":rollback",
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := prompt +
+		prompt + "snapshot 1 taken\n" +
+		prompt +
+		prompt +
+		prompt + "snapshot 2 taken\n" +
+		prompt +
+		prompt + "rolled back to snapshot 2\n" +
+		prompt + "=> 5 : INTEGER\n" +
+		prompt + "rolled back to snapshot 1\n" +
+		prompt + "=> 1 : INTEGER\n" +
+		prompt + "ERROR: identifier not found: b\n\tb\n\t^\n" +
+		prompt + "no snapshot to roll back to, take one with :snapshot\n" +
+		prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+// Functions defined before a rollback see the restored bindings, along with
+// the ones made after it
+func TestRollbackClosures(t *testing.T) {
+	input := strings.Join([]string{
+		"let x = 1;",
+		"let get = fn() { x };",
+		"let later = fn() { y };",
+		":snapshot",
+		"let x = 2;",
+		"get()",
+		":rollback",
+		"get()",
+		"let y = 3;",
+		"later()",
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := prompt +
+		prompt +
+		prompt +
+		prompt + "snapshot 1 taken\n" +
+		prompt +
+		prompt + "=> 2 : INTEGER\n" +
+		prompt + "rolled back to snapshot 1\n" +
+		prompt + "=> 1 : INTEGER\n" +
+		prompt +
+		prompt + "=> 3 : INTEGER\n" +
+		prompt
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen returned error: %s", err)
+	}
+
+	globals := object.NewEnvironment()
+	globals.Set("answer", &object.Integer{Value: 42})
+
+	served := make(chan error)
+	go func() { served <- Serve(l, WithGlobals(globals), WithFuel(10000)) }()
+
+	// Sends input over a new connection, returning everything the session
+	// printed until it ended
+	session := func(input string) string {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("net.Dial returned error: %s", err)
+		}
+		defer conn.Close()
+
+		io.WriteString(conn, input)
+		conn.(*net.TCPConn).CloseWrite()
+
+		out, err := io.ReadAll(conn)
+		if err != nil {
+			t.Fatalf("reading the session failed: %s", err)
+		}
+		return string(out)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = answer + 1;\na\n", prompt + prompt + "=> 43 : INTEGER\n" + prompt},
+		{"a\n", prompt + "ERROR: identifier not found: a\n\ta\n\t^\n" + prompt},
+		{"exit(1)\nanswer\n", prompt},
+		// Clients can't touch files unless the host allows it
+		{":save /tmp/session.based\n:load /etc/passwd\n", prompt + ":save is disabled in this session\n" + prompt + ":load is disabled in this session\n" + prompt},
+		// Nor use builtins reaching outside the interpreter, or run forever
+		{"now()\n", prompt + "ERROR: permission denied: now needs the time capability\n\tnow()\n\t^~~~~\n" + prompt},
+		{"let loop = fn() { loop() }; loop()\n", prompt + "ERROR: fuel exhausted: evaluation exceeded 10000 steps\n" +
+			"\tlet loop = fn() { loop() }; loop()\n\t                  ^~~~~~\n" + prompt},
+	}
+
+	for _, tc := range tests {
+		if out := session(tc.input); out != tc.expected {
+			t.Errorf("wrong output for %q.\nexpected=%q\ngot=%q", tc.input, tc.expected, out)
+		}
+	}
+
+	l.Close()
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned error: %s", err)
+	}
+}
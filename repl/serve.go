@@ -0,0 +1,50 @@
+package repl
+
+import (
+	"errors"
+	"net"
+
+	"github.com/nayyara-airlangga/basedlang/evaluator"
+)
+
+// Limits of sessions served with Serve, unless raised with WithFuel and
+// WithMemoryLimit
+const (
+	ServeFuel   = 50_000_000
+	ServeMemory = 64 << 20
+)
+
+// Serve runs a REPL session for every connection accepted on l, each on its own
+// goroutine and with an environment of its own, until l is closed. Calling exit
+// in a session only closes its connection. It returns nil once l is closed, or
+// else the error accepting connections failed with.
+//
+// Anyone who can connect runs code in the process without authenticating, so
+// sessions are locked down unless opts say otherwise: builtins get no
+// capabilities, :save and :load are disabled, and every session may only
+// evaluate ServeFuel nodes and create ServeMemory bytes of values. Hosts should
+// only loosen that with WithCapabilities, WithFileCommands, WithFuel and
+// WithMemoryLimit when they trust every client that can connect.
+func Serve(l net.Listener, opts ...Option) error {
+	opts = append([]Option{
+		WithCapabilities(evaluator.CapNone),
+		WithFileCommands(false),
+		WithFuel(ServeFuel),
+		WithMemoryLimit(ServeMemory),
+	}, opts...)
+
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+			Start(conn, conn, opts...)
+		}()
+	}
+}
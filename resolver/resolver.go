@@ -0,0 +1,320 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/token"
+)
+
+const (
+	ErrIdentifierNotFound = "%d:%d: identifier not found: %s"
+	ErrGlobalNotFound     = "%d:%d: global not found: %s"
+
+	WarnUnusedBinding = "%d:%d: warning: declared and not used: %s"
+	WarnUnreachable   = "%d:%d: warning: unreachable code"
+)
+
+// scope mirrors an environment the evaluator creates, which is either the
+// global one or the local one of a function call. Blocks share the scope they
+// appear in, just like they share their environment at runtime.
+type scope struct {
+	outer *scope
+
+	// Names bound so far while walking the scope in evaluation order
+	bound map[string]bool
+	// Names bound anywhere in the scope, since function bodies only run after
+	// the bindings around them are made
+	all map[string]bool
+
+	// Local bindings in the order they are made, checked for uses once the
+	// scope ends, and the latest one of each name which uses refer to
+	locals []*binding
+	latest map[string]*binding
+	// Names used by functions within the scope, which may run after any of the
+	// bindings of that name
+	captured map[string]bool
+}
+
+type binding struct {
+	name *ast.Identifier
+	used bool
+}
+
+// Resolver statically checks that every identifier refers to a binding, without
+// evaluating anything
+type Resolver struct {
+	predeclared map[string]bool
+	scope       *scope
+
+	errors   []string
+	warnings []warning
+}
+
+type warning struct {
+	pos token.Position
+	msg string
+}
+
+// New creates a resolver where predeclared names, like builtins, are always
+// bound
+func New(predeclared []string) *Resolver {
+	r := &Resolver{predeclared: make(map[string]bool), errors: []string{}}
+	for _, name := range predeclared {
+		r.predeclared[name] = true
+	}
+	return r
+}
+
+func (r *Resolver) Errs() []string { return r.errors }
+
+// Warnings returns the problems that don't stop a program from running, like
+// local bindings that are never used and code after a return, ordered by
+// position
+func (r *Resolver) Warnings() []string {
+	warnings := make([]string, len(r.warnings))
+	for i, w := range r.warnings {
+		warnings[i] = w.msg
+	}
+	return warnings
+}
+
+func (r *Resolver) Resolve(program *ast.Program) {
+	r.pushScope(program.Statements)
+	r.checkReachable(program.Statements)
+	for _, s := range program.Statements {
+		r.resolve(s)
+	}
+	r.popScope()
+
+	sort.SliceStable(r.warnings, func(i, j int) bool {
+		a, b := r.warnings[i].pos, r.warnings[j].pos
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+}
+
+func (r *Resolver) resolve(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.LetStatement:
+		if n.Value != nil {
+			r.resolve(n.Value)
+		}
+		for _, name := range n.Names {
+			r.bind(name)
+		}
+	case *ast.GlobalStatement:
+		r.resolve(n.Value)
+		r.resolveGlobal(n.Name)
+	case *ast.BlockStatement:
+		r.checkReachable(n.Statements)
+		for _, s := range n.Statements {
+			r.resolve(s)
+		}
+	case *ast.Identifier:
+		r.resolveIdentifier(n)
+	case *ast.FunctionLiteral:
+		r.pushScope(n.Body.Statements)
+		r.bindParams(n.Params)
+		r.resolve(n.Body)
+		r.popScope()
+	case *ast.MacroLiteral:
+		r.pushScope(n.Body.Statements)
+		r.bindParams(n.Params)
+		r.resolve(n.Body)
+		r.popScope()
+	case *ast.CallExpression:
+		if id, isIdent := n.Function.(*ast.Identifier); isIdent && id.Value == "quote" {
+			r.resolveQuoted(n.Args)
+			return
+		}
+		// dbg is evaluated specially rather than looked up
+		if id, isIdent := n.Function.(*ast.Identifier); isIdent && id.Value == "dbg" {
+			for _, arg := range n.Args {
+				r.resolve(arg)
+			}
+			return
+		}
+		for _, child := range ast.Children(node) {
+			r.resolve(child)
+		}
+	case *ast.ForStatement:
+		r.resolve(n.Iterable)
+		// Every iteration gets an environment of its own, like a function call
+		r.pushScope(n.Body.Statements)
+		r.bindParams(n.Names)
+		r.resolve(n.Body)
+		r.popScope()
+	case *ast.DoWhileStatement:
+		// The condition is evaluated in the environment of the run it follows
+		r.pushScope(n.Body.Statements)
+		r.resolve(n.Body)
+		r.resolve(n.Condition)
+		r.popScope()
+	case *ast.MatchArm:
+		// So does every arm of a match
+		var stmts []ast.Statement
+		if block, isBlock := n.Body.(*ast.BlockStatement); isBlock {
+			stmts = block.Statements
+		}
+		r.pushScope(stmts)
+		r.bindParams(ast.PatternBindings(n.Pattern))
+		for _, child := range ast.Children(n) {
+			r.resolve(child)
+		}
+		r.popScope()
+	default:
+		for _, child := range ast.Children(node) {
+			r.resolve(child)
+		}
+	}
+}
+
+// resolveQuoted resolves only the unquoted parts of quoted code, since the rest
+// is code to be placed elsewhere rather than run where it is
+func (r *Resolver) resolveQuoted(exprs []ast.Expression) {
+	for _, e := range exprs {
+		ast.Walk(e, func(n ast.Node) bool {
+			call, isCall := n.(*ast.CallExpression)
+			if !isCall {
+				return true
+			}
+			if id, isIdent := call.Function.(*ast.Identifier); isIdent && id.Value == "unquote" {
+				for _, arg := range call.Args {
+					r.resolve(arg)
+				}
+				return false
+			}
+			return true
+		})
+	}
+}
+
+func (r *Resolver) resolveIdentifier(id *ast.Identifier) {
+	if r.scope.bound[id.Value] {
+		if b := r.scope.latest[id.Value]; b != nil {
+			b.used = true
+		}
+		return
+	}
+	if r.predeclared[id.Value] {
+		return
+	}
+
+	for s := r.scope.outer; s != nil; s = s.outer {
+		if s.all[id.Value] {
+			s.captured[id.Value] = true
+			return
+		}
+	}
+
+	pos := id.Pos()
+	r.errors = append(r.errors, fmt.Sprintf(ErrIdentifierNotFound, pos.Line, pos.Column, id.Value))
+}
+
+// resolveGlobal checks that a global statement rebinds a name bound at the top
+// level, wherever the statement is. Names bound in the scopes in between don't
+// matter, since they are never the ones rebound.
+func (r *Resolver) resolveGlobal(id *ast.Identifier) {
+	s := r.scope
+	for s.outer != nil {
+		s = s.outer
+	}
+	if !s.all[id.Value] {
+		pos := id.Pos()
+		r.errors = append(r.errors, fmt.Sprintf(ErrGlobalNotFound, pos.Line, pos.Column, id.Value))
+	}
+}
+
+// bind makes a let binding of name in the current scope. Only bindings local to
+// functions are tracked for uses, since global ones may be used by whatever
+// loads the script. Names starting with an underscore are never reported.
+func (r *Resolver) bind(name *ast.Identifier) {
+	s := r.scope
+	if prev := s.latest[name.Value]; prev != nil && !prev.used && !s.captured[name.Value] {
+		r.warnUnused(prev)
+	}
+	s.bound[name.Value] = true
+
+	if s.outer == nil || strings.HasPrefix(name.Value, "_") {
+		delete(s.latest, name.Value)
+		return
+	}
+	b := &binding{name: name}
+	s.locals = append(s.locals, b)
+	s.latest[name.Value] = b
+}
+
+// bindParams binds names that are given values on entering a scope, like
+// parameters, which are never reported as unused
+func (r *Resolver) bindParams(names []*ast.Identifier) {
+	for _, name := range names {
+		r.scope.bound[name.Value] = true
+		r.scope.all[name.Value] = true
+	}
+}
+
+// checkReachable warns about the first statement following a return or raise in
+// stmts, which can never run
+func (r *Resolver) checkReachable(stmts []ast.Statement) {
+	for i, stmt := range stmts[:max(len(stmts)-1, 0)] {
+		switch stmt.(type) {
+		case *ast.ReturnStatement, *ast.RaiseStatement:
+			r.warn(stmts[i+1].Pos(), WarnUnreachable)
+			return
+		}
+	}
+}
+
+func (r *Resolver) warnUnused(b *binding) {
+	r.warn(b.name.Pos(), WarnUnusedBinding, b.name.Value)
+}
+
+func (r *Resolver) warn(pos token.Position, format string, args ...any) {
+	args = append([]any{pos.Line, pos.Column}, args...)
+	r.warnings = append(r.warnings, warning{pos: pos, msg: fmt.Sprintf(format, args...)})
+}
+
+func (r *Resolver) pushScope(stmts []ast.Statement) {
+	s := &scope{
+		outer:    r.scope,
+		bound:    make(map[string]bool),
+		all:      make(map[string]bool),
+		latest:   make(map[string]*binding),
+		captured: make(map[string]bool),
+	}
+
+	for _, stmt := range stmts {
+		collectBindings(stmt, s.all)
+	}
+
+	r.scope = s
+}
+
+func (r *Resolver) popScope() {
+	s := r.scope
+	for _, b := range s.locals {
+		// A function within the scope may use any binding of a name
+		if !b.used && !s.captured[b.name.Value] && s.latest[b.name.Value] == b {
+			r.warnUnused(b)
+		}
+	}
+	r.scope = s.outer
+}
+
+// collectBindings gathers the names bound by let statements in node, without
+// descending into functions and loops since those get scopes of their own
+func collectBindings(node ast.Node, names map[string]bool) {
+	ast.Walk(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral, *ast.MacroLiteral, *ast.ForStatement, *ast.DoWhileStatement, *ast.MatchArm:
+			return false
+		case *ast.LetStatement:
+			for _, name := range n.Names {
+				names[name.Value] = true
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,119 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1; a + len([a]);", []string{}},
+		{"foobar;", []string{"1:1: identifier not found: foobar"}},
+		{"a; let a = 1;", []string{"1:1: identifier not found: a"}},
+		{"let a = a;", []string{"1:9: identifier not found: a"}},
+		{"let f = fn(x, rest...) { x + len(rest) }; f(1);", []string{}},
+		{"let fib = fn(n) { fib(n - 1) };", []string{}},
+		{"let f = fn() { g() }; let g = fn() { 1 };", []string{}},
+		{"let f = fn() { y }; let g = fn() { let y = 1; y };", []string{"1:16: identifier not found: y"}},
+		{"let f = fn() { x; let x = 1; };", []string{"1:16: identifier not found: x"}},
+		{"if (true) { let b = 1; } b;", []string{}},
+		{"let x, y = f();", []string{"1:12: identifier not found: f"}},
+		{"[1, 2] |> |x| x[0] + z", []string{"1:22: identifier not found: z"}},
+		{"let add = x => y => x + y;", []string{}},
+		{"for (k, v in {}) { k + v; }", []string{}},
+		{"for (x in [1]) { let y = x; } y;", []string{"1:31: identifier not found: y"}},
+		{"for (x in x) {}", []string{"1:11: identifier not found: x"}},
+		{"let g = fn() { yield z; };", []string{"1:22: identifier not found: z"}},
+		{"match (1) { [a, ...rest] if a > 0 => a + len(rest), {name} => name, _ => b };", []string{
+			"1:74: identifier not found: b",
+		}},
+		{"match (1) { is len as n => n, is Point as p => p }; n;", []string{
+			"1:34: identifier not found: Point",
+			"1:53: identifier not found: n",
+		}},
+		{"match (1) { x => { let y = x; y } }; x + y;", []string{
+			"1:38: identifier not found: x",
+			"1:42: identifier not found: y",
+		}},
+		{"let m = macro(a) { quote(unquote(a) + b) }; quote(c + unquote(d));", []string{
+			"1:63: identifier not found: d",
+		}},
+		{"let a = 1; dbg(a + b);", []string{"1:20: identifier not found: b"}},
+		{"let n = 0; let f = fn() { let m = 1; global n = m; global m = n; global k = z; };", []string{
+			"1:59: global not found: m",
+			"1:77: identifier not found: z",
+			"1:73: global not found: k",
+		}},
+	}
+
+	for _, tc := range tests {
+		p := parser.New(lexer.New(tc.input))
+		program := p.Parse()
+		if len(p.Errs()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tc.input, p.Errs())
+		}
+
+		r := New([]string{"len"})
+		r.Resolve(program)
+
+		errors := r.Errs()
+		if len(errors) != len(tc.expected) {
+			t.Errorf("wrong number of errors for %q. expected=%v, got=%v", tc.input, tc.expected, errors)
+			continue
+		}
+		for i, msg := range tc.expected {
+			if errors[i] != msg {
+				t.Errorf("wrong error for %q. expected=%q, got=%q", tc.input, msg, errors[i])
+			}
+		}
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1;", []string{}},
+		{"fn() { let a = 1; 2 }", []string{"1:12: warning: declared and not used: a"}},
+		{"fn() { let _a = 1; 2 }", []string{}},
+		{"fn(x) { let a = x; a }", []string{}},
+		{"fn() { let a, b = [1, 2]; a }", []string{"1:15: warning: declared and not used: b"}},
+		{"fn() { let a = 1; let a = 2; a }", []string{"1:12: warning: declared and not used: a"}},
+		{"fn() { let a = 1; let a = a + 1; a }", []string{}},
+		{"fn() { let f = fn() { g() }; let g = fn() { 1 }; f() }", []string{}},
+		{"fn() { if (true) { let b = 1; } 2 }", []string{"1:24: warning: declared and not used: b"}},
+		{"fn() { return 1; 2; 3 }", []string{"1:18: warning: unreachable code"}},
+		{"fn() { if (true) { return 1; let x = 2; x } }", []string{"1:30: warning: unreachable code"}},
+		{"return 1; len(2);", []string{"1:11: warning: unreachable code"}},
+		{`raise "oops"; len(2);`, []string{"1:15: warning: unreachable code"}},
+		{"fn() { let a = 1; return 2; a }", []string{"1:29: warning: unreachable code"}},
+	}
+
+	for _, tc := range tests {
+		p := parser.New(lexer.New(tc.input))
+		program := p.Parse()
+		if len(p.Errs()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tc.input, p.Errs())
+		}
+
+		r := New([]string{"len"})
+		r.Resolve(program)
+
+		warnings := r.Warnings()
+		if len(warnings) != len(tc.expected) {
+			t.Errorf("wrong number of warnings for %q. expected=%v, got=%v", tc.input, tc.expected, warnings)
+			continue
+		}
+		for i, msg := range tc.expected {
+			if warnings[i] != msg {
+				t.Errorf("wrong warning for %q. expected=%q, got=%q", tc.input, msg, warnings[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/coverage"
+	"github.com/nayyara-airlangga/basedlang/evaluator"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/parser"
+	"github.com/nayyara-airlangga/basedlang/profiler"
+	"github.com/nayyara-airlangga/basedlang/token"
+)
+
+func runCmd(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	cover := fs.Bool("cover", false, "print the script annotated with statement coverage after running it")
+	coverProfile := fs.String("coverprofile", "", "write statement coverage in lcov format to `file`")
+	profile := fs.String("profile", "", "write a pprof profile of where the script spends its evaluation to `file`")
+	flamegraph := fs.String("flamegraph", "", "write the same profile as folded stacks for flamegraph.pl to `file`")
+	strict := fs.Bool("strict", false, "make ! on non-booleans and integer overflow errors")
+	watch := fs.Bool("watch", false, "run the script again whenever it changes, until interrupted")
+	trace := fs.Bool("trace", false, "write every evaluated node and its result to stderr")
+	dump := make(map[string]bool)
+	fs.Func("dump", "write the script's `tokens` or ast to stderr before running it, may be repeated", func(s string) error {
+		if s != "tokens" && s != "ast" {
+			return errors.New("expected tokens or ast")
+		}
+		dump[s] = true
+		return nil
+	})
+	plugins := make(map[string]object.BuiltinFn)
+	pluginFlag(fs, plugins)
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "run expects a script to run")
+		return 2
+	}
+
+	filename := fs.Arg(0)
+	o := runOptions{
+		cover:        *cover,
+		coverProfile: *coverProfile,
+		profile:      *profile,
+		flamegraph:   *flamegraph,
+		strict:       *strict,
+		trace:        *trace,
+		dump:         dump,
+		plugins:      plugins,
+		// Anything after the script is passed on to it
+		args: fs.Args()[1:],
+	}
+
+	if *watch {
+		if filename == "-" {
+			fmt.Fprintln(os.Stderr, "-watch needs a script file, not stdin")
+			return 2
+		}
+		return watchScript(filename, func() int { return runScript(filename, o) })
+	}
+	return runScript(filename, o)
+}
+
+// runOptions are the flags of run that apply to every run of a script
+type runOptions struct {
+	cover        bool
+	coverProfile string
+	profile      string
+	flamegraph   string
+	strict       bool
+	trace        bool
+	dump         map[string]bool
+	plugins      map[string]object.BuiltinFn
+	args         []string
+}
+
+// runScript runs the script at filename once, returning the exit status
+func runScript(filename string, o runOptions) int {
+	src, err := readScript(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var program *ast.Program
+	compiled := ast.IsEncoded(src)
+
+	// Tokens are dumped before parsing, so they can show why parsing fails
+	if o.dump["tokens"] {
+		if compiled {
+			fmt.Fprintln(os.Stderr, "-dump tokens needs the script source, compiled programs have no tokens")
+			return 2
+		}
+		dumpTokens(os.Stderr, string(src))
+	}
+
+	if compiled {
+		if program, err = ast.Decode(bytes.NewReader(src)); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err)
+			return 1
+		}
+	} else {
+		p := parser.New(lexer.New(string(src)))
+		program = p.Parse()
+		if len(p.Errs()) != 0 {
+			printParserErrors(filename, string(src), p.Errs())
+			return 1
+		}
+		var expandErr *object.Error
+		if program, expandErr = evaluator.ExpandMacros(program, object.NewEnvironment()); expandErr != nil {
+			printRuntimeError(filename, string(src), expandErr)
+			return 1
+		}
+	}
+
+	if o.dump["ast"] {
+		ast.Fprint(os.Stderr, program)
+	}
+
+	// The annotated report is built from the source, which compiled programs
+	// no longer carry
+	if o.cover && compiled {
+		fmt.Fprintln(os.Stderr, "-cover needs the script source, use -coverprofile for compiled programs")
+		return 2
+	}
+
+	opts := []evaluator.Option{evaluator.WithArgs(o.args)}
+	if o.strict {
+		opts = append(opts, evaluator.WithStrict())
+	}
+	if len(o.plugins) != 0 {
+		opts = append(opts, evaluator.WithBuiltins(o.plugins))
+	}
+	if o.trace {
+		opts = append(opts, evaluator.WithTrace(os.Stderr))
+	}
+
+	var profile *coverage.Profile
+	if o.cover || o.coverProfile != "" {
+		profile = coverage.NewProfile()
+		opts = append(opts, evaluator.WithCoverage(profile))
+	}
+	var evalProfile *profiler.Profile
+	if o.profile != "" || o.flamegraph != "" {
+		evalProfile = profiler.NewProfile()
+		opts = append(opts, evaluator.WithProfile(evalProfile))
+	}
+
+	in := evaluator.New(opts...)
+	defer in.Close()
+
+	status := 0
+	switch evaluated := in.Eval(program, object.NewEnvironment()).(type) {
+	case *object.Error:
+		// Compiled programs don't carry their source to quote
+		source := string(src)
+		if compiled {
+			source = ""
+		}
+		printRuntimeError(filename, source, evaluated)
+		status = 1
+	case *object.Exit:
+		status = int(evaluated.Code)
+	}
+
+	if o.cover {
+		if err := profile.WriteReport(os.Stdout, string(src), program); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if o.coverProfile != "" {
+		if err := writeLCOV(o.coverProfile, filename, profile, program); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if o.profile != "" {
+		if err := writeProfile(o.profile, func(w io.Writer) error { return evalProfile.WritePprof(w, filename) }); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if o.flamegraph != "" {
+		if err := writeProfile(o.flamegraph, evalProfile.WriteFolded); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	return status
+}
+
+// dumpTokens writes every token of src to w, one per line, the same way the
+// REPL's :tokens command does
+func dumpTokens(w io.Writer, src string) {
+	l := lexer.New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(w, "%d:%d\t%s\t%q\n", tok.Line, tok.Column, tok.Type, tok.Literal)
+	}
+}
+
+// watchInterval is how often run -watch checks whether the script changed
+const watchInterval = 200 * time.Millisecond
+
+// watchScript calls run, and then again every time the file at filename is
+// modified. It only returns if the file can't be found to begin with, leaving
+// the watching to be ended by an interrupt.
+func watchScript(filename string, run func() int) int {
+	for {
+		// Stat before running so changes made during the run aren't missed
+		info, err := os.Stat(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		status := run()
+		fmt.Fprintf(os.Stderr, "[exited with status %d, watching %s for changes]\n", status, filename)
+
+		waitForChange(filename, info)
+	}
+}
+
+// waitForChange blocks until the file at filename differs in modification time
+// or size from info. Editors often replace files on save, so the file being
+// missing for a moment is waited out.
+func waitForChange(filename string, info os.FileInfo) {
+	for {
+		time.Sleep(watchInterval)
+		current, err := os.Stat(filename)
+		if err != nil {
+			continue
+		}
+		if !current.ModTime().Equal(info.ModTime()) || current.Size() != info.Size() {
+			return
+		}
+	}
+}
+
+func writeLCOV(path, filename string, profile *coverage.Profile, program *ast.Program) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return profile.WriteLCOV(f, filename, program)
+}
+
+// writeProfile creates the file at path and writes a profile to it with write
+func writeProfile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return write(f)
+}
+
+// readScript reads the script at filename, or stdin when filename is -
+func readScript(filename string) ([]byte, error) {
+	if filename == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(filename)
+}
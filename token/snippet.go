@@ -0,0 +1,41 @@
+package token
+
+import "strings"
+
+// Snippet quotes the line of src that start is on along with a caret under the
+// span from start up to end, like
+//
+//	let x = 5 + true;
+//	        ^~~~~~~~
+//
+// Spans running past the line are cut at its end. It returns false when src
+// has no such line.
+func Snippet(src string, start, end Position) (line, caret string, ok bool) {
+	lines := strings.Split(src, "\n")
+	if start.Line < 1 || start.Line > len(lines) || start.Column < 1 {
+		return "", "", false
+	}
+	line = strings.TrimRight(lines[start.Line-1], "\r")
+
+	from := min(start.Column-1, len(line))
+	to := len(line)
+	if end.Line == start.Line {
+		to = min(max(end.Column-1, from), len(line))
+	}
+
+	// Tabs are kept so the caret lines up however wide they're displayed
+	var b strings.Builder
+	for _, ch := range line[:from] {
+		if ch == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	b.WriteRune('^')
+	if width := len([]rune(line[from:to])); width > 1 {
+		b.WriteString(strings.Repeat("~", width-1))
+	}
+
+	return line, b.String(), true
+}
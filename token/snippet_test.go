@@ -0,0 +1,33 @@
+package token
+
+import "testing"
+
+func TestSnippet(t *testing.T) {
+	src := "let x = 5;\n\tlet y = x + true;\r\nfoo("
+
+	tests := []struct {
+		start, end Position
+		line       string
+		caret      string
+	}{
+		{Position{1, 9}, Position{1, 10}, "let x = 5;", "        ^"},
+		{Position{2, 10}, Position{2, 18}, "\tlet y = x + true;", "\t        ^~~~~~~~"},
+		{Position{3, 1}, Position{4, 2}, "foo(", "^~~~"},
+		{Position{3, 5}, Position{3, 6}, "foo(", "    ^"},
+	}
+
+	for _, tc := range tests {
+		line, caret, ok := Snippet(src, tc.start, tc.end)
+		if !ok {
+			t.Errorf("no snippet for %v", tc.start)
+			continue
+		}
+		if line != tc.line || caret != tc.caret {
+			t.Errorf("wrong snippet for %v-%v.\nexpected=%q\n         %q\ngot=%q\n    %q", tc.start, tc.end, tc.line, tc.caret, line, caret)
+		}
+	}
+
+	if _, _, ok := Snippet(src, Position{5, 1}, Position{5, 2}); ok {
+		t.Errorf("expected no snippet past the last line")
+	}
+}
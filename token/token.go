@@ -5,16 +5,77 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+
+	// Position of the token's first character, both starting at 1
+	Line   int
+	Column int
+}
+
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (t Token) Pos() Position {
+	return Position{Line: t.Line, Column: t.Column}
+}
+
+// End is the position just past the token's last character. Tokens without
+// characters, like EOF, are given a width of one so they can still be pointed
+// at.
+func (t Token) End() Position {
+	width := len(t.Literal)
+	if t.Type == STRING {
+		width += 2 // the quotes aren't part of the literal
+	}
+	return Position{Line: t.Line, Column: t.Column + max(width, 1)}
 }
 
 var keywords map[string]TokenType = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":        FUNCTION,
+	"let":       LET,
+	"true":      TRUE,
+	"false":     FALSE,
+	"if":        IF,
+	"else":      ELSE,
+	"return":    RETURN,
+	"yield":     YIELD,
+	"do":        DO,
+	"while":     WHILE,
+	"raise":     RAISE,
+	"global":    GLOBAL,
+	"for":       FOR,
+	"in":        IN,
+	"match":     MATCH,
+	"interface": INTERFACE,
+	"macro":     MACRO,
+}
+
+// literals holds the text of the keywords by their types, to be shared by every
+// token of the type
+var literals = func() map[TokenType]string {
+	lits := make(map[TokenType]string, len(keywords))
+	for ident, t := range keywords {
+		lits[t] = ident
+	}
+	return lits
+}()
+
+// Literal returns the text of tokens of type t when it's always the same, like
+// for keywords and operators, and false otherwise. The same string is returned
+// every time, so tokens made with it don't allocate.
+func Literal(t TokenType) (string, bool) {
+	if lit, isKeyword := literals[t]; isKeyword {
+		return lit, true
+	}
+
+	switch t {
+	case ILLEGAL, EOF, IDENT, INT, FLOAT, STRING, DOC_COMMENT:
+		return "", false
+	default:
+		// Other types are named after their text
+		return string(t), true
+	}
 }
 
 func LookupType(ident string) TokenType {
@@ -7,15 +7,20 @@ const (
 	// Identifiers and literals
 	IDENT  TokenType = "IDENT" // AKA variable names
 	INT    TokenType = "INT"   // integer numbers
+	FLOAT  TokenType = "FLOAT" // numbers with a fractional part like 1.5
 	STRING TokenType = "STRING"
 
+	// A /// comment documenting what follows it, without the slashes
+	DOC_COMMENT TokenType = "DOC_COMMENT"
+
 	// Operators
-	ASSIGN   TokenType = "="
-	PLUS     TokenType = "+"
-	MINUS    TokenType = "-"
-	BANG     TokenType = "!"
-	ASTERISK TokenType = "*"
-	SLASH    TokenType = "/"
+	ASSIGN    TokenType = "="
+	PLUS      TokenType = "+"
+	MINUS     TokenType = "-"
+	BANG      TokenType = "!"
+	ASTERISK  TokenType = "*"
+	SLASH     TokenType = "/"
+	FLOOR_DIV TokenType = "//" // division rounding down
 
 	LT  TokenType = "<"
 	GT  TokenType = ">"
@@ -24,9 +29,20 @@ const (
 	LTE TokenType = "<="
 	GTE TokenType = ">="
 
+	PIPE     TokenType = "|"
+	ARROW    TokenType = "=>"
+	PIPELINE TokenType = "|>"
+	RARROW   TokenType = "->" // return type annotation
+
+	// Assignments only made when the element is falsy or null respectively
+	OR_ASSIGN      TokenType = "||="
+	NULLISH_ASSIGN TokenType = "??="
+
 	// Delimiters
 	COMMA     TokenType = ","
 	SEMICOLON TokenType = ";"
+	COLON     TokenType = ":"
+	ELLIPSIS  TokenType = "..."
 
 	LPAREN   TokenType = "("
 	RPAREN   TokenType = ")"
@@ -36,11 +52,21 @@ const (
 	RBRACKET TokenType = "]"
 
 	// Keywords
-	FUNCTION TokenType = "FUNCTION"
-	LET      TokenType = "LET"
-	TRUE     TokenType = "TRUE"
-	FALSE    TokenType = "FALSE"
-	IF       TokenType = "IF"
-	ELSE     TokenType = "ELSE"
-	RETURN   TokenType = "RETURN"
+	FUNCTION  TokenType = "FUNCTION"
+	LET       TokenType = "LET"
+	TRUE      TokenType = "TRUE"
+	FALSE     TokenType = "FALSE"
+	IF        TokenType = "IF"
+	ELSE      TokenType = "ELSE"
+	RETURN    TokenType = "RETURN"
+	YIELD     TokenType = "YIELD"
+	RAISE     TokenType = "RAISE"
+	GLOBAL    TokenType = "GLOBAL"
+	FOR       TokenType = "FOR"
+	DO        TokenType = "DO"
+	WHILE     TokenType = "WHILE"
+	IN        TokenType = "IN"
+	MATCH     TokenType = "MATCH"
+	INTERFACE TokenType = "INTERFACE"
+	MACRO     TokenType = "MACRO"
 )
@@ -0,0 +1,632 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/token"
+)
+
+const (
+	ErrTypeMismatch              = "%d:%d: type mismatch: %s %s %s"
+	ErrUnsupportedOperatorInfix  = "%d:%d: unsupported operator: %s %s %s"
+	ErrUnsupportedOperatorPrefix = "%d:%d: unsupported operator: %s%s"
+	ErrUnsupportedOperatorIndex  = "%d:%d: unsupported operator: index not supported on %s"
+	ErrUnsupportedOperatorSlice  = "%d:%d: unsupported operator: slice not supported on %s"
+	ErrInvalidIndex              = "%d:%d: invalid argument: index of type %s is not an integer"
+	ErrNotAFunction              = "%d:%d: not a function: %s"
+	ErrUnknownType               = "%d:%d: unknown type: %s"
+	ErrCannotAssign              = "%d:%d: type mismatch: cannot use %s as %s"
+	ErrCannotReturn              = "%d:%d: type mismatch: cannot return %s as %s"
+	ErrWrongArgType              = "%d:%d: type mismatch: cannot use %s as %s in argument %d"
+	ErrWrongNumberOfArgs         = "%d:%d: wrong number of arguments. got=%d, want=%d"
+	ErrNotEnoughArgs             = "%d:%d: wrong number of arguments. got=%d, want>=%d"
+)
+
+// scope holds the types of the names bound in an environment the evaluator
+// would create. Blocks share the scope they appear in, like they share their
+// environment at runtime.
+type scope struct {
+	outer    *scope
+	bindings map[string]binding
+
+	// Names bound more than once, whose type depends on when they are read.
+	// Function bodies may run after any of the bindings, so they see these as
+	// Unknown.
+	rebound map[string]bool
+}
+
+type binding struct {
+	typ Type
+	sig *signature // known for functions bound directly from a literal
+}
+
+// signature describes the parameters and results of a function literal, with
+// the results inferred from its body unless annotated
+type signature struct {
+	params   []Type // for a variadic function, the last is the type of each extra argument
+	variadic bool
+	result   Type
+}
+
+// function holds what is gathered while checking the body of a function
+type function struct {
+	returned []returned
+}
+
+type returned struct {
+	typ Type
+	pos token.Position
+}
+
+// Checker infers the types of expressions without evaluating them and reports
+// operations that are bound to fail at runtime, like true + 5. Anything it
+// cannot type statically is Unknown and never reported.
+type Checker struct {
+	scope *scope
+	fn    *function
+
+	signatures map[*ast.FunctionLiteral]*signature
+
+	// Top-level names rebound by global statements, which may happen whenever
+	// a function is called so they are never typed
+	globals map[string]bool
+
+	errors []string
+}
+
+func New() *Checker {
+	return &Checker{signatures: make(map[*ast.FunctionLiteral]*signature), errors: []string{}}
+}
+
+func (c *Checker) Errs() []string { return c.errors }
+
+func (c *Checker) Check(program *ast.Program) {
+	c.globals = make(map[string]bool)
+	ast.Walk(program, func(n ast.Node) bool {
+		if gs, isGlobal := n.(*ast.GlobalStatement); isGlobal {
+			c.globals[gs.Name.Value] = true
+		}
+		return true
+	})
+
+	c.pushScope(program.Statements)
+	for _, s := range program.Statements {
+		c.typeOf(s)
+	}
+	c.popScope()
+}
+
+// typeOf reports errors found in node and returns the type of the value it
+// evaluates to
+func (c *Checker) typeOf(node ast.Node) Type {
+	switch n := node.(type) {
+	case *ast.LetStatement:
+		c.checkLet(n)
+		return Unknown
+	case *ast.ExpressionStatement:
+		return c.typeOf(n.Expression)
+	case *ast.YieldStatement:
+		c.typeOf(n.Value)
+		return Unknown
+	case *ast.RaiseStatement:
+		c.typeOf(n.Value)
+		return Unknown
+	case *ast.GlobalStatement:
+		c.typeOf(n.Value)
+		return Unknown
+	case *ast.ForStatement:
+		c.checkFor(n)
+		return Unknown
+	case *ast.DoWhileStatement:
+		c.pushScope(n.Body.Statements)
+		c.typeOf(n.Body)
+		c.typeOf(n.Condition)
+		c.popScope()
+		return Unknown
+	case *ast.AssignStatement:
+		c.typeOf(n.Target)
+		c.typeOf(n.Value)
+		return Unknown
+	case *ast.MatchExpression:
+		c.checkMatch(n)
+		return Unknown
+	case *ast.ReturnStatement:
+		t := c.typeOf(n.ReturnValue)
+		if c.fn != nil {
+			c.fn.returned = append(c.fn.returned, returned{typ: t, pos: n.ReturnValue.Pos()})
+		}
+		return Unknown
+	case *ast.BlockStatement:
+		t := Unknown
+		for _, s := range n.Statements {
+			t = c.typeOf(s)
+		}
+		return t
+	case *ast.Identifier:
+		return c.lookup(n.Value).typ
+	case *ast.IntLiteral:
+		return Integer
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.BooleanLiteral:
+		return Boolean
+	case *ast.StringLiteral:
+		return String
+	case *ast.ArrayLiteral:
+		c.typeOfAll(n.Elems)
+		return Array
+	case *ast.TupleExpression:
+		c.typeOfAll(n.Elems)
+		return Tuple
+	case *ast.HashLiteral:
+		c.typeOfAll(n.Keys)
+		c.typeOfAll(n.Values)
+		return Hash
+	case *ast.SpreadExpression:
+		c.typeOf(n.Value)
+		return Unknown
+	case *ast.PrefixExpression:
+		return c.typeOfPrefix(n)
+	case *ast.InfixExpression:
+		return c.typeOfInfix(n)
+	case *ast.IndexExpression:
+		return c.typeOfIndex(n)
+	case *ast.SliceExpression:
+		return c.typeOfSlice(n)
+	case *ast.IfExpression:
+		return c.typeOfIf(n)
+	case *ast.FunctionLiteral:
+		c.signatures[n] = c.checkFunction(n)
+		return Function
+	case *ast.CallExpression:
+		return c.typeOfCall(n)
+	default:
+		return Unknown
+	}
+}
+
+func (c *Checker) checkLet(ls *ast.LetStatement) {
+	value := Unknown
+	if ls.Value != nil {
+		value = c.typeOf(ls.Value)
+	}
+
+	// Unpacked values cannot be typed, so only annotations tell their types
+	if len(ls.Names) > 1 {
+		for _, name := range ls.Names {
+			c.scope.bindings[name.Value] = binding{typ: c.annotated(name.Type)}
+		}
+		return
+	}
+
+	if ls.Name.Type == nil {
+		c.scope.bindings[ls.Name.Value] = binding{typ: value, sig: c.signatureOf(ls.Value)}
+		return
+	}
+
+	declared := c.annotated(ls.Name.Type)
+	if declared != Unknown && value != Unknown && value != declared {
+		c.errorf(ErrCannotAssign, ls.Value.Pos(), value, declared)
+	}
+
+	b := binding{typ: declared}
+	if declared == Function {
+		b.sig = c.signatureOf(ls.Value)
+	}
+	c.scope.bindings[ls.Name.Value] = b
+}
+
+// checkFor checks a for loop, whose values can't be typed statically
+func (c *Checker) checkFor(fs *ast.ForStatement) {
+	c.typeOf(fs.Iterable)
+
+	c.pushScope(fs.Body.Statements)
+	for _, name := range fs.Names {
+		c.scope.bindings[name.Value] = binding{typ: c.annotated(name.Type)}
+	}
+	c.typeOf(fs.Body)
+	c.popScope()
+}
+
+// checkMatch checks every arm of a match, whose bindings can't be typed
+// statically
+func (c *Checker) checkMatch(me *ast.MatchExpression) {
+	c.typeOf(me.Subject)
+
+	for _, arm := range me.Arms {
+		var stmts []ast.Statement
+		if block, isBlock := arm.Body.(*ast.BlockStatement); isBlock {
+			stmts = block.Statements
+		}
+
+		c.pushScope(stmts)
+		for _, name := range ast.PatternBindings(arm.Pattern) {
+			c.scope.bindings[name.Value] = binding{typ: Unknown}
+		}
+		// Names bound by type patterns have the type they were matched against,
+		// unless the name of the type is bound to something else
+		ast.Walk(arm.Pattern, func(n ast.Node) bool {
+			tp, isType := n.(*ast.TypePattern)
+			if !isType || tp.Binding == nil || c.bound(tp.Type.Value) {
+				return true
+			}
+			if t, known := values[tp.Type.Value]; known {
+				c.scope.bindings[tp.Binding.Name.Value] = binding{typ: t}
+			}
+			return true
+		})
+		if arm.Guard != nil {
+			c.typeOf(arm.Guard)
+		}
+		c.typeOf(arm.Body)
+		c.popScope()
+	}
+}
+
+// checkFunction checks the body of fl and returns its signature
+func (c *Checker) checkFunction(fl *ast.FunctionLiteral) *signature {
+	sig := &signature{variadic: fl.Variadic}
+
+	c.pushScope(fl.Body.Statements)
+	for i, p := range fl.Params {
+		t := c.annotated(p.Type)
+		sig.params = append(sig.params, t)
+
+		// The annotation of a variadic parameter is the type of each argument it
+		// collects
+		if fl.Variadic && i == len(fl.Params)-1 {
+			t = Array
+		}
+		c.scope.bindings[p.Value] = binding{typ: t}
+	}
+	sig.result = c.annotated(fl.ReturnType)
+
+	outer := c.fn
+	c.fn = &function{}
+	last := c.typeOf(fl.Body)
+	results := c.fn.returned
+	c.fn = outer
+	c.popScope()
+
+	// Calling a generator function returns a generator, whatever its body does
+	if fl.Generator {
+		sig.result = Unknown
+		return sig
+	}
+
+	// A body that ends in a return or raise statement has no value of its own
+	stmts := fl.Body.Statements
+	if len(stmts) == 0 {
+		results = append(results, returned{typ: Unknown, pos: fl.Body.Pos()})
+	} else if !isExit(stmts[len(stmts)-1]) {
+		results = append(results, returned{typ: last, pos: stmts[len(stmts)-1].Pos()})
+	}
+
+	if fl.ReturnType != nil {
+		for _, r := range results {
+			if sig.result != Unknown && r.typ != Unknown && r.typ != sig.result {
+				c.errorf(ErrCannotReturn, r.pos, r.typ, sig.result)
+			}
+		}
+		return sig
+	}
+
+	// The result is only known when every way out of the body agrees on it
+	sig.result = results[0].typ
+	for _, r := range results[1:] {
+		if r.typ != sig.result {
+			sig.result = Unknown
+		}
+	}
+
+	return sig
+}
+
+// isExit reports whether stmt leaves the function it's in, so the function
+// doesn't result in its value
+func isExit(stmt ast.Statement) bool {
+	switch stmt.(type) {
+	case *ast.ReturnStatement, *ast.RaiseStatement:
+		return true
+	default:
+		return false
+	}
+}
+
+// signatureOf returns the signature of the function expr evaluates to, or nil
+// when it is not statically known
+func (c *Checker) signatureOf(expr ast.Expression) *signature {
+	switch e := expr.(type) {
+	case *ast.FunctionLiteral:
+		return c.signatures[e]
+	case *ast.Identifier:
+		return c.lookup(e.Value).sig
+	default:
+		return nil
+	}
+}
+
+func (c *Checker) typeOfCall(ce *ast.CallExpression) Type {
+	if t := c.typeOf(ce.Function); t != Unknown && t != Function {
+		c.errorf(ErrNotAFunction, ce.Pos(), t)
+	}
+
+	args := make([]Type, len(ce.Args))
+	spread := false
+	for i, arg := range ce.Args {
+		args[i] = c.typeOf(arg)
+		if _, isSpread := arg.(*ast.SpreadExpression); isSpread {
+			spread = true
+		}
+	}
+
+	sig := c.signatureOf(ce.Function)
+	if sig == nil {
+		return Unknown
+	}
+	// Spread arguments could line up with the parameters in any way
+	if spread {
+		return sig.result
+	}
+
+	fixed := len(sig.params)
+	if sig.variadic {
+		fixed--
+		if len(args) < fixed {
+			c.errorf(ErrNotEnoughArgs, ce.Pos(), len(args), fixed)
+			return sig.result
+		}
+	} else if len(args) != fixed {
+		c.errorf(ErrWrongNumberOfArgs, ce.Pos(), len(args), fixed)
+		return sig.result
+	}
+
+	for i, arg := range args {
+		param := sig.params[min(i, len(sig.params)-1)]
+		if param != Unknown && arg != Unknown && arg != param {
+			c.errorf(ErrWrongArgType, ce.Args[i].Pos(), arg, param, i+1)
+		}
+	}
+
+	return sig.result
+}
+
+// annotated returns the type an optional annotation names, which is Unknown
+// when there is no annotation
+func (c *Checker) annotated(ta *ast.TypeAnnotation) Type {
+	if ta == nil {
+		return Unknown
+	}
+
+	t, ok := Lookup(ta.Name)
+	if !ok {
+		c.errorf(ErrUnknownType, ta.Pos(), ta.Name)
+		return Unknown
+	}
+	return t
+}
+
+func (c *Checker) typeOfAll(exprs []ast.Expression) {
+	for _, e := range exprs {
+		c.typeOf(e)
+	}
+}
+
+func (c *Checker) typeOfPrefix(pe *ast.PrefixExpression) Type {
+	right := c.typeOf(pe.Right)
+
+	switch pe.Operator {
+	case "!":
+		return Boolean
+	case "-":
+		if right != Unknown && !isNumber(right) {
+			c.errorf(ErrUnsupportedOperatorPrefix, pe.Pos(), pe.Operator, right)
+			return Unknown
+		}
+		return right
+	default:
+		return Unknown
+	}
+}
+
+func (c *Checker) typeOfInfix(ie *ast.InfixExpression) Type {
+	left := c.typeOf(ie.Left)
+	right := c.typeOf(ie.Right)
+	op := ie.Operator
+
+	if left == Unknown || right == Unknown {
+		return infixResult(op, left, right)
+	}
+
+	switch {
+	case isNumber(left) && isNumber(right):
+		return infixResult(op, left, right)
+	case left == String && right == String:
+		if op != "+" && infixResult(op, left, right) != Boolean {
+			c.errorf(ErrUnsupportedOperatorInfix, ie.Pos(), left, op, right)
+			return Unknown
+		}
+		return infixResult(op, left, right)
+	case op == "==" || op == "!=":
+		return Boolean
+	case left != right:
+		c.errorf(ErrTypeMismatch, ie.Pos(), left, op, right)
+	default:
+		c.errorf(ErrUnsupportedOperatorInfix, ie.Pos(), left, op, right)
+	}
+
+	return Unknown
+}
+
+// infixResult returns the type op evaluates to when applied to operands of the
+// given types, assuming it doesn't fail. That is enough to type some results
+// even with an unknown operand, since only numbers mix with floats and only
+// strings with strings.
+func infixResult(op string, left, right Type) Type {
+	switch {
+	case op == "<" || op == "<=" || op == ">" || op == ">=" || op == "==" || op == "!=":
+		return Boolean
+	case op == "/" && left == Integer && right == Integer:
+		// Integers that don't divide evenly result in a float
+		return Unknown
+	case left == Integer && right == Integer:
+		return Integer
+	case left == Float || right == Float:
+		return Float
+	case op == "+" && (left == String || right == String):
+		return String
+	default:
+		return Unknown
+	}
+}
+
+func isNumber(t Type) bool {
+	return t == Integer || t == Float
+}
+
+func (c *Checker) typeOfIndex(ie *ast.IndexExpression) Type {
+	left := c.typeOf(ie.Left)
+	idx := c.typeOf(ie.Index)
+
+	switch {
+	case left == Unknown || left == Hash:
+	case left != Array:
+		c.errorf(ErrUnsupportedOperatorIndex, ie.Pos(), left)
+	case idx != Unknown && idx != Integer:
+		c.errorf(ErrInvalidIndex, ie.Index.Pos(), idx)
+	}
+
+	return Unknown
+}
+
+func (c *Checker) typeOfSlice(se *ast.SliceExpression) Type {
+	left := c.typeOf(se.Left)
+	for _, bound := range []ast.Expression{se.Low, se.High} {
+		if bound == nil {
+			continue
+		}
+		if t := c.typeOf(bound); t != Unknown && t != Integer {
+			c.errorf(ErrInvalidIndex, bound.Pos(), t)
+		}
+	}
+
+	if left != Unknown && left != Array {
+		c.errorf(ErrUnsupportedOperatorSlice, se.Pos(), left)
+	}
+
+	return Array
+}
+
+func (c *Checker) typeOfIf(ie *ast.IfExpression) Type {
+	c.typeOf(ie.Condition)
+
+	// Bindings made in a branch may or may not have happened afterwards
+	before := c.snapshot()
+	consequence := c.typeOf(ie.Body)
+	c.merge(before)
+
+	if ie.Else == nil {
+		return Unknown
+	}
+
+	before = c.snapshot()
+	alternative := c.typeOf(ie.Else)
+	c.merge(before)
+
+	if consequence != alternative {
+		return Unknown
+	}
+	return consequence
+}
+
+// snapshot copies the bindings of the current scope
+func (c *Checker) snapshot() map[string]binding {
+	bindings := make(map[string]binding, len(c.scope.bindings))
+	for name, b := range c.scope.bindings {
+		bindings[name] = b
+	}
+	return bindings
+}
+
+// merge makes every name whose binding changed since before Unknown
+func (c *Checker) merge(before map[string]binding) {
+	for name, b := range c.scope.bindings {
+		if prev, ok := before[name]; !ok || prev != b {
+			c.scope.bindings[name] = binding{typ: Unknown}
+		}
+	}
+}
+
+// bound reports whether name is bound in any scope, rather than being a builtin
+func (c *Checker) bound(name string) bool {
+	for s := c.scope; s != nil; s = s.outer {
+		if _, ok := s.bindings[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Checker) lookup(name string) binding {
+	if b, ok := c.scope.bindings[name]; ok {
+		if c.scope.outer == nil && c.globals[name] {
+			return binding{typ: Unknown}
+		}
+		return b
+	}
+
+	for s := c.scope.outer; s != nil; s = s.outer {
+		if s.rebound[name] || s.outer == nil && c.globals[name] {
+			return binding{typ: Unknown}
+		}
+		if b, ok := s.bindings[name]; ok {
+			return b
+		}
+	}
+
+	// Builtins, or names the resolver reports
+	return binding{typ: Unknown}
+}
+
+func (c *Checker) pushScope(stmts []ast.Statement) {
+	s := &scope{outer: c.scope, bindings: make(map[string]binding), rebound: make(map[string]bool)}
+
+	bindings := make(map[string]int)
+	for _, stmt := range stmts {
+		countBindings(stmt, bindings)
+	}
+	for name, count := range bindings {
+		if count > 1 {
+			s.rebound[name] = true
+		}
+	}
+
+	c.scope = s
+}
+
+func (c *Checker) popScope() {
+	c.scope = c.scope.outer
+}
+
+func (c *Checker) errorf(format string, pos token.Position, args ...any) {
+	args = append([]any{pos.Line, pos.Column}, args...)
+	c.errors = append(c.errors, fmt.Sprintf(format, args...))
+}
+
+// countBindings counts how many times let statements in node bind each name,
+// without descending into functions and loops since those get scopes of their
+// own
+func countBindings(node ast.Node, counts map[string]int) {
+	ast.Walk(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral, *ast.MacroLiteral, *ast.ForStatement, *ast.DoWhileStatement, *ast.MatchArm:
+			return false
+		case *ast.LetStatement:
+			for _, name := range n.Names {
+				counts[name.Value]++
+			}
+		}
+		return true
+	})
+}
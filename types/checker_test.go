@@ -0,0 +1,111 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"1 + 2 * 3 < 10 == true;", []string{}},
+		{"true + 5;", []string{"1:1: type mismatch: BOOLEAN + INTEGER"}},
+		{"5 + true;", []string{"1:1: type mismatch: INTEGER + BOOLEAN"}},
+		{"true + false;", []string{"1:1: unsupported operator: BOOLEAN + BOOLEAN"}},
+		{`"a" + "b"; "a" - "b";`, []string{`1:12: unsupported operator: STRING - STRING`}},
+		{`let b = "a" < "b"; b + 1;`, []string{`1:20: type mismatch: BOOLEAN + INTEGER`}},
+		{`"a" == 1; [1] != fn() {};`, []string{}},
+		{"-true;", []string{"1:1: unsupported operator: -BOOLEAN"}},
+		{"let x = 1 + 2.5; let y = -x; (y < 1) + 1; 1.5 - true;", []string{
+			"1:31: type mismatch: BOOLEAN + INTEGER",
+			"1:43: type mismatch: FLOAT - BOOLEAN",
+		}},
+		{"let x = 1; let y = x + 1; y + true;", []string{"1:27: type mismatch: INTEGER + BOOLEAN"}},
+		{`let x = 1; let f = fn() { global x = "a"; x + "b" }; x + "b"; let y = 1; y + "b";`, []string{"1:74: type mismatch: INTEGER + STRING"}},
+		{`match (x) { is Str as s => s - 1, is Int as n => n + 1, is Chan as c => c + 1 };`, []string{"1:28: type mismatch: STRING - INTEGER"}},
+		{`let Str = 1; match (x) { is Str as s => s - 1 };`, []string{}},
+		{"let x = 7 / 2; let y = 7 // 2; let z = 7.0 // 2; x + true; y + true; z + true;", []string{
+			"1:60: type mismatch: INTEGER + BOOLEAN",
+			"1:70: type mismatch: FLOAT + BOOLEAN",
+		}},
+		{"let x = (1 < 2) + 1;", []string{"1:10: type mismatch: BOOLEAN + INTEGER"}},
+		{`let s = "a" + "b"; -s;`, []string{"1:20: unsupported operator: -STRING"}},
+		{"let f = fn(a, b) { a + b }; f(1, true); f + 1;", []string{"1:41: type mismatch: FUNCTION + INTEGER"}},
+		{"let f = fn() { 1 + true };", []string{"1:16: type mismatch: INTEGER + BOOLEAN"}},
+		{"1(2); let x = true; x();", []string{"1:1: not a function: INTEGER", "1:21: not a function: BOOLEAN"}},
+		{"[1, 2][true]; 1[0]; true[1:];", []string{
+			"1:8: invalid argument: index of type BOOLEAN is not an integer",
+			"1:15: unsupported operator: index not supported on INTEGER",
+			"1:21: unsupported operator: slice not supported on BOOLEAN",
+		}},
+		{"let x = if (true) { 1 } else { 2 }; x + true;", []string{"1:37: type mismatch: INTEGER + BOOLEAN"}},
+		{"let x = if (true) { 1 } else { \"a\" }; x + true;", []string{}},
+		{"let x = 1; if (true) { let x = \"a\"; } x + 1;", []string{}},
+		{"let x = 1; let f = fn() { x + 1 }; let x = true;", []string{}},
+		{"let g = fn() { yield 1; 1 }; g() + true;", []string{}},
+		{"let g = fn() { yield 1 + true; };", []string{"1:22: type mismatch: INTEGER + BOOLEAN"}},
+		{"for (x in [1]) { let y = 1; y + true; } let y = true;", []string{"1:29: type mismatch: INTEGER + BOOLEAN"}},
+		{"let x = 1; let f = fn() { x + true };", []string{"1:27: type mismatch: INTEGER + BOOLEAN"}},
+		{"let a, b = fn() { return 1, 2 }(); a + b;", []string{}},
+		{`let h = {"a": 1}; h["a"]; let f = fn(x) { x["a"] }; h + 1;`, []string{"1:53: type mismatch: HASH + INTEGER"}},
+		{"let x: int = 5; x + true;", []string{"1:17: type mismatch: INTEGER + BOOLEAN"}},
+		{`let x: int = "a";`, []string{"1:14: type mismatch: cannot use STRING as INTEGER"}},
+		{"let x: any = 1; let y: str = x; y - 1;", []string{"1:33: type mismatch: STRING - INTEGER"}},
+		{"let f = fn(a: int, b: bool) { a + b };", []string{"1:31: type mismatch: INTEGER + BOOLEAN"}},
+		{"let f = fn(rest: int...) { rest[0]; rest + 1 };", []string{"1:37: type mismatch: ARRAY + INTEGER"}},
+		{"let x: num = 1; let f = fn(a: double) -> real { a };", []string{
+			"1:8: unknown type: num",
+			"1:31: unknown type: double",
+			"1:42: unknown type: real",
+		}},
+		{"let a: int, b: str = f(); a + b;", []string{"1:27: type mismatch: INTEGER + STRING"}},
+		{"let half = fn(x) { x * 0.5 }; half(1) + true;", []string{"1:31: type mismatch: FLOAT + BOOLEAN"}},
+		{"let inc = fn(x) { x + 1 }; inc(1) + true;", []string{}},
+		{`let greet = fn(name) { "hi " + name }; -greet("a");`, []string{"1:40: unsupported operator: -STRING"}},
+		{"let f = fn(x) { if (x) { return 1; } 2 }; f(true) + true;", []string{"1:43: type mismatch: INTEGER + BOOLEAN"}},
+		{`let f = fn(x) { if (x) { return "a"; } 2 }; f(true) + true;`, []string{}},
+		{"let f = fn() { return true; }; let x = f(); x - 1;", []string{"1:45: type mismatch: BOOLEAN - INTEGER"}},
+		{"let g = fn() {}; g() + 1; fn(x) { x }(1) + true;", []string{}},
+		{"let f = fn(a: int) -> bool { a }; f(1) + 1;", []string{
+			"1:30: type mismatch: cannot return INTEGER as BOOLEAN",
+			"1:35: type mismatch: BOOLEAN + INTEGER",
+		}},
+		{`let f = fn(a: int, b: str) { a }; f("a", "b"); f(1);`, []string{
+			"1:37: type mismatch: cannot use STRING as INTEGER in argument 1",
+			"1:48: wrong number of arguments. got=1, want=2",
+		}},
+		{`let f = fn(a, rest: int...) { a }; f(1, 2, "c"); f();`, []string{
+			"1:44: type mismatch: cannot use STRING as INTEGER in argument 3",
+			"1:50: wrong number of arguments. got=0, want>=1",
+		}},
+		{"let f = fn(a: int) { a }; f(...[1]);", []string{}},
+		{"let f = fn(a: int) { a }; let g = f; g(true);", []string{"1:40: type mismatch: cannot use BOOLEAN as INTEGER in argument 1"}},
+		{"let f = fn() { 1 }; if (true) { let f = fn() { true } } f() + 1;", []string{}},
+	}
+
+	for _, tc := range tests {
+		p := parser.New(lexer.New(tc.input))
+		program := p.Parse()
+		if len(p.Errs()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tc.input, p.Errs())
+		}
+
+		c := New()
+		c.Check(program)
+
+		errors := c.Errs()
+		if len(errors) != len(tc.expected) {
+			t.Errorf("wrong number of errors for %q. expected=%v, got=%v", tc.input, tc.expected, errors)
+			continue
+		}
+		for i, msg := range tc.expected {
+			if errors[i] != msg {
+				t.Errorf("wrong error for %q. expected=%q, got=%q", tc.input, msg, errors[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package types
+
+import "github.com/nayyara-airlangga/basedlang/object"
+
+// Type is the static type of an expression. Types share their names with the
+// object types they describe, so static errors read like the runtime ones.
+type Type string
+
+const (
+	// Unknown is the type of expressions that can only be typed at runtime, like
+	// function parameters and call results. It is compatible with every type.
+	Unknown  Type = "UNKNOWN"
+	Integer  Type = Type(object.INTEGER)
+	Float    Type = Type(object.FLOAT)
+	Boolean  Type = Type(object.BOOLEAN)
+	String   Type = Type(object.STRING)
+	Function Type = Type(object.FUNCTION)
+	Array    Type = Type(object.ARRAY)
+	Tuple    Type = Type(object.TUPLE)
+	Hash     Type = Type(object.HASH)
+)
+
+func (t Type) String() string { return string(t) }
+
+// names maps the type names used in annotations to the types they stand for
+var names = map[string]Type{
+	"int":   Integer,
+	"float": Float,
+	"bool":  Boolean,
+	"str":   String,
+	"fn":    Function,
+	"array": Array,
+	"tuple": Tuple,
+	"hash":  Hash,
+	"any":   Unknown,
+}
+
+// values maps the names of the type values predeclared at runtime, like the Int
+// that typeof results are compared to, to the types they stand for
+var values = map[string]Type{
+	object.IntType.Name:   Integer,
+	object.FloatType.Name: Float,
+	object.BoolType.Name:  Boolean,
+	object.StrType.Name:   String,
+	object.FnType.Name:    Function,
+	object.ArrayType.Name: Array,
+	object.TupleType.Name: Tuple,
+	object.HashType.Name:  Hash,
+}
+
+// Lookup returns the type an annotation names, and false if there is no such
+// type
+func Lookup(name string) (Type, bool) {
+	t, ok := names[name]
+	return t, ok
+}
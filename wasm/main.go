@@ -0,0 +1,96 @@
+//go:build js && wasm
+
+// Command wasm exposes basedlang to JavaScript, so a browser playground can run
+// it without a server. Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o basedlang.wasm ./wasm
+//
+// and load it with the wasm_exec.js shipped with Go. Once started it defines
+// two global functions:
+//
+//	basedlang.parse(src) -> { ast: string, errors: string[] }
+//	basedlang.eval(src)  -> { result: string, type: string, output: string, errors: string[] }
+package main
+
+import (
+	"bytes"
+	"syscall/js"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/evaluator"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+func main() {
+	js.Global().Set("basedlang", js.ValueOf(map[string]any{
+		"parse": js.FuncOf(parse),
+		"eval":  js.FuncOf(eval),
+	}))
+
+	// Keep the functions above alive for as long as the page is open
+	select {}
+}
+
+func parse(this js.Value, args []js.Value) any {
+	program, errors := parseArg(args)
+	if len(errors) != 0 {
+		return js.ValueOf(map[string]any{"ast": "", "errors": errors})
+	}
+
+	var tree bytes.Buffer
+	ast.Fprint(&tree, program)
+
+	return js.ValueOf(map[string]any{"ast": tree.String(), "errors": []any{}})
+}
+
+func eval(this js.Value, args []js.Value) any {
+	program, errors := parseArg(args)
+	if len(errors) != 0 {
+		return js.ValueOf(map[string]any{"result": "", "type": "", "output": "", "errors": errors})
+	}
+	program, expandErr := evaluator.ExpandMacros(program, object.NewEnvironment())
+	if expandErr != nil {
+		return js.ValueOf(map[string]any{"result": "", "type": "", "output": "", "errors": []any{expandErr.Message}})
+	}
+
+	var output bytes.Buffer
+	in := evaluator.New(evaluator.WithStdout(&output))
+	defer in.Close()
+
+	evaluated := in.Eval(program, object.NewEnvironment())
+
+	res := map[string]any{"result": "", "type": "", "output": output.String(), "errors": []any{}}
+	if evaluated == nil {
+		return js.ValueOf(res)
+	}
+
+	if evaluated.Type() == object.ERROR {
+		res["errors"] = []any{evaluated.(*object.Error).Message}
+		return js.ValueOf(res)
+	}
+
+	res["result"] = evaluated.Inspect()
+	res["type"] = string(evaluated.Type())
+
+	return js.ValueOf(res)
+}
+
+// parseArg parses the source passed as the first argument, returning its errors
+// in a form js.ValueOf accepts
+func parseArg(args []js.Value) (*ast.Program, []any) {
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return nil, []any{"expected the source code as a single string argument"}
+	}
+
+	p := parser.New(lexer.New(args[0].String()))
+	program := p.Parse()
+
+	errors := make([]any, len(p.Errs()))
+	for i, d := range p.Errs() {
+		errors[i] = d.String()
+	}
+
+	return program, errors
+}